@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/chatmail/rpc-client-go/v2/deltachat/backup"
+)
+
+func printCommands(out io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintln(out, "  "+commands[name].usage)
+	}
+}
+
+// accId returns the account to operate on.
+func (c *cli) accId() (uint32, error) {
+	if c.account != 0 {
+		return c.account, nil
+	}
+	selected, err := c.rpc.GetSelectedAccountId()
+	if err != nil {
+		return 0, err
+	}
+	if selected != nil {
+		return *selected, nil
+	}
+	ids, err := c.rpc.GetAllAccountIds()
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, errors.New("no accounts, add one with: dcctl accounts add")
+	}
+	return ids[0], nil
+}
+
+// subcommand returns the first argument and the remaining ones, failing if the
+// first argument is not one of the given subcommands.
+func subcommand(args []string, usage string, names ...string) (string, []string, error) {
+	if len(args) == 0 || !slices.Contains(names, args[0]) {
+		return "", nil, fmt.Errorf("usage: dcctl %v", usage)
+	}
+	return args[0], args[1:], nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+func parseId(arg string, what string) (uint32, error) {
+	id, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v id %q", what, arg)
+	}
+	return uint32(id), nil
+}
+
+// optionalArg returns a pointer to the joined arguments, nil if there are none.
+func optionalArg(args []string) *string {
+	if len(args) == 0 {
+		return nil
+	}
+	arg := strings.Join(args, " ")
+	return &arg
+}
+
+func (c *cli) accounts(args []string) error {
+	sub, args, err := subcommand(args, commands["accounts"].usage, "list", "add", "remove")
+	if err != nil {
+		return err
+	}
+	switch sub {
+	case "add":
+		accId, err := c.rpc.AddAccount()
+		if err != nil {
+			return err
+		}
+		return c.printValue(accId)
+	case "remove":
+		if len(args) != 1 {
+			return errors.New("usage: dcctl accounts remove <account>")
+		}
+		accId, err := parseId(args[0], "account")
+		if err != nil {
+			return err
+		}
+		return c.rpc.RemoveAccount(accId)
+	}
+
+	accounts, err := c.rpc.GetAllAccounts()
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(accounts))
+	for _, account := range accounts {
+		switch account := account.(type) {
+		case *deltachat.AccountConfigured:
+			rows = append(rows, []string{formatId(account.Id), "configured", deref(account.Addr), deref(account.DisplayName)})
+		case *deltachat.AccountUnconfigured:
+			rows = append(rows, []string{formatId(account.Id), "unconfigured", "", ""})
+		}
+	}
+	return c.printTable(accounts, []string{"ID", "STATUS", "ADDRESS", "NAME"}, rows)
+}
+
+func (c *cli) chats(args []string) error {
+	_, args, err := subcommand(args, commands["chats"].usage, "list")
+	if err != nil {
+		return err
+	}
+	accId, err := c.accId()
+	if err != nil {
+		return err
+	}
+	items := []deltachat.ChatListItemFetchResult{}
+	var rows [][]string
+	for item, err := range c.rpc.ChatListItems(accId, nil, optionalArg(args), nil) {
+		var loadingErr *deltachat.ItemLoadingErr
+		if errors.As(err, &loadingErr) {
+			rows = append(rows, []string{formatId(loadingErr.Id), "", "", "", loadingErr.Reason})
+			continue
+		} else if err != nil {
+			return err
+		}
+		items = append(items, item)
+		switch item := item.(type) {
+		case *deltachat.ChatListItemFetchResultChatListItem:
+			summary := strings.TrimPrefix(item.SummaryText1+": "+item.SummaryText2, ": ")
+			rows = append(rows, []string{formatId(item.Id), item.Name, string(item.ChatType), strconv.FormatUint(uint64(item.FreshMessageCounter), 10), summary})
+		case *deltachat.ChatListItemFetchResultArchiveLink:
+			rows = append(rows, []string{formatId(deltachat.ChatIdArchivedLink), "Archived chats", "", strconv.FormatUint(uint64(item.FreshMessageCounter), 10), ""})
+		}
+	}
+	return c.printTable(items, []string{"ID", "NAME", "TYPE", "FRESH", "SUMMARY"}, rows)
+}
+
+func (c *cli) send(args []string) error {
+	flags := newFlagSet("send")
+	file := flags.String("file", "", "file to attach")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 || flags.NArg() == 1 && *file == "" {
+		return fmt.Errorf("usage: dcctl %v", commands["send"].usage)
+	}
+	chatId, err := parseId(flags.Arg(0), "chat")
+	if err != nil {
+		return err
+	}
+	accId, err := c.accId()
+	if err != nil {
+		return err
+	}
+	data := deltachat.MessageData{Text: optionalArg(flags.Args()[1:])}
+	if *file != "" {
+		data.File = file
+	}
+	msgId, err := c.rpc.SendMsg(accId, chatId, data)
+	if err != nil {
+		return err
+	}
+	return c.printValue(msgId)
+}
+
+func (c *cli) contacts(args []string) error {
+	accId, err := c.accId()
+	if err != nil {
+		return err
+	}
+	contacts := []deltachat.Contact{}
+	var rows [][]string
+	for contact, err := range c.rpc.Contacts(accId, 0, optionalArg(args)) {
+		if err != nil {
+			return err
+		}
+		contacts = append(contacts, contact)
+		rows = append(rows, []string{formatId(contact.Id), contact.Address, contact.DisplayName, strconv.FormatBool(contact.IsVerified)})
+	}
+	return c.printTable(contacts, []string{"ID", "ADDRESS", "NAME", "VERIFIED"}, rows)
+}
+
+func (c *cli) config(args []string) error {
+	sub, args, err := subcommand(args, commands["config"].usage, "get", "set")
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || sub == "get" && len(args) != 1 {
+		return fmt.Errorf("usage: dcctl config %v <key>", sub)
+	}
+	accId, err := c.accId()
+	if err != nil {
+		return err
+	}
+	config := deltachat.NewAccountConfig(c.rpc, accId)
+	key := deltachat.ConfigKey(args[0])
+	if sub == "set" {
+		return config.Set(key, optionalArg(args[1:]))
+	}
+	value, err := config.Get(key)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(value)
+	}
+	if value != nil {
+		fmt.Fprintln(c.out, *value)
+	}
+	return nil
+}
+
+func (c *cli) connectivity(args []string) error {
+	accId, err := c.accId()
+	if err != nil {
+		return err
+	}
+	conn, err := c.rpc.GetConnectivity(accId)
+	if err != nil {
+		return err
+	}
+	return c.printValue(conn)
+}
+
+func (c *cli) qr(args []string) error {
+	_, args, err := subcommand(args, commands["qr"].usage, "show")
+	if err != nil {
+		return err
+	}
+	flags := newFlagSet("qr show")
+	svg := flags.Bool("svg", false, "print the QR code as SVG image")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var chatId *uint32
+	if flags.NArg() > 0 {
+		id, err := parseId(flags.Arg(0), "chat")
+		if err != nil {
+			return err
+		}
+		chatId = &id
+	}
+	accId, err := c.accId()
+	if err != nil {
+		return err
+	}
+	if *svg {
+		qr, err := c.rpc.GetChatSecurejoinQrCodeSvg(accId, chatId)
+		if err != nil {
+			return err
+		}
+		return c.printValue(qr.Second)
+	}
+	qr, err := c.rpc.GetChatSecurejoinQrCode(accId, chatId)
+	if err != nil {
+		return err
+	}
+	return c.printValue(qr)
+}
+
+func (c *cli) backup(args []string) error {
+	sub, args, err := subcommand(args, commands["backup"].usage, "export", "import")
+	if err != nil {
+		return err
+	}
+	flags := newFlagSet("backup " + sub)
+	passphrase := flags.String("passphrase", "", "passphrase of the backup")
+	var keep *int
+	var verify *bool
+	if sub == "export" {
+		keep = flags.Int("keep", 0, "number of backups to keep in the directory, older backups are removed (default: keep all)")
+		verify = flags.Bool("verify", false, "verify the backup by importing it in a temporary accounts directory")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: dcctl backup %v [-passphrase p] <path>", sub)
+	}
+	var pass *string
+	if *passphrase != "" {
+		pass = passphrase
+	}
+
+	if sub == "export" {
+		accId, err := c.accId()
+		if err != nil {
+			return err
+		}
+		backups := backup.New(c.rpc, accId, flags.Arg(0))
+		backups.Passphrase = *passphrase
+		backups.KeepCount = *keep
+		backups.ServerCmd = c.server
+		path, err := backups.Export(nil)
+		if err != nil {
+			return err
+		}
+		if *verify {
+			if err := backups.Verify(path); err != nil {
+				return err
+			}
+		}
+		return c.printValue(path)
+	}
+	accId, err := c.rpc.AddAccount()
+	if err != nil {
+		return err
+	}
+	if err := c.rpc.ImportBackup(accId, flags.Arg(0), pass); err != nil {
+		c.rpc.RemoveAccount(accId) //nolint:errcheck
+		return err
+	}
+	return c.printValue(accId)
+}
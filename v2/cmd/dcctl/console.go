@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// minPaneRows is the minimum height of the terminal to show the events pane.
+const minPaneRows = 12
+
+// console reads lines with tab-completion from a terminal and prints messages above
+// the line being edited. If the input is not a terminal, lines are read as they are.
+//
+// OpenPane() splits the terminal: the prompt and the messages scroll in the upper region,
+// set with the ANSI scroll margins, and the last events are shown in a pane below it.
+type console struct {
+	in          *bufio.Reader
+	out         io.Writer
+	interactive bool
+	prompt      string
+	// complete returns the completions of the line being edited, and a hint to print
+	// if there is a single completion.
+	complete func(line string) (candidates []string, hint string)
+
+	mu   sync.Mutex
+	line []rune
+	pane *eventPane
+}
+
+// eventPane is the region at the bottom of the terminal showing the last events.
+type eventPane struct {
+	// first row of the pane, below its separator, the rows start at 1
+	top    int
+	height int
+	width  int
+	lines  []string
+}
+
+// setRawMode disables line buffering, echo and signals of the terminal with stty,
+// the returned function restores the previous state.
+func setRawMode(in *os.File) (func(), error) {
+	state, err := stty(in, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(in, "-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(in, strings.TrimSpace(state)) }, nil //nolint:errcheck
+}
+
+func stty(in *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = in
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// terminalSize returns the number of rows and columns of the terminal.
+func terminalSize(in *os.File) (int, int, error) {
+	size, err := stty(in, "size")
+	if err != nil {
+		return 0, 0, err
+	}
+	var rows, cols int
+	if _, err := fmt.Sscan(size, &rows, &cols); err != nil {
+		return 0, 0, err
+	}
+	return rows, cols, nil
+}
+
+// OpenPane reserves the bottom third of a terminal of the given size for the events
+// printed with PrintEvent(). Small terminals are not split.
+func (con *console) OpenPane(rows, cols int) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	if !con.interactive || rows < minPaneRows || cols < 1 {
+		return
+	}
+	height := rows / 3
+	separator := rows - height
+	con.pane = &eventPane{top: separator + 1, height: height, width: cols}
+	label := "── events "
+	fmt.Fprintf(con.out, "\033[%d;1H\033[J%v%v", separator, label, strings.Repeat("─", max(0, cols-utf8.RuneCountInString(label))))
+	// scroll region of the prompt, the cursor is moved to its last row
+	fmt.Fprintf(con.out, "\033[1;%dr\033[%d;1H", separator-1, separator-1)
+}
+
+// ClosePane restores the scroll region of the terminal and clears the events pane.
+func (con *console) ClosePane() {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	if con.pane == nil {
+		return
+	}
+	fmt.Fprintf(con.out, "\033[r\033[%d;1H\033[J", con.pane.top-1)
+	con.pane = nil
+}
+
+// PrintEvent shows text in the events pane, or prints it like Println() without pane.
+func (con *console) PrintEvent(text string) {
+	con.mu.Lock()
+	pane := con.pane
+	if pane == nil {
+		con.mu.Unlock()
+		con.Println(text)
+		return
+	}
+	defer con.mu.Unlock()
+	if runes := []rune(text); len(runes) > pane.width {
+		text = string(runes[:pane.width-1]) + "…"
+	}
+	pane.lines = append(pane.lines, text)
+	if len(pane.lines) > pane.height {
+		pane.lines = slices.Delete(pane.lines, 0, len(pane.lines)-pane.height)
+	}
+	// the cursor is saved and restored around the redraw of the pane
+	var buf strings.Builder
+	buf.WriteString("\0337")
+	for i, line := range pane.lines {
+		fmt.Fprintf(&buf, "\033[%d;1H\033[2K%v", pane.top+i, line)
+	}
+	buf.WriteString("\0338")
+	io.WriteString(con.out, buf.String()) //nolint:errcheck
+}
+
+// Println prints text on its own line, above the line being edited.
+func (con *console) Println(text string) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	if !con.interactive {
+		io.WriteString(con.out, text+"\n") //nolint:errcheck
+		return
+	}
+	io.WriteString(con.out, "\r\033[K"+text+"\n"+con.prompt+string(con.line)) //nolint:errcheck
+}
+
+// ReadLine returns the next line, io.EOF on Ctrl-D or at the end of the input.
+func (con *console) ReadLine() (string, error) {
+	if !con.interactive {
+		line, err := con.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	con.write(con.prompt)
+	for {
+		r, _, err := con.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			line := con.reset("\n")
+			return line, nil
+		case 3: // Ctrl-C
+			con.reset("^C\n" + con.prompt)
+		case 4: // Ctrl-D
+			if con.lineLen() == 0 {
+				con.write("\n")
+				return "", io.EOF
+			}
+		case 127, '\b':
+			con.backspace()
+		case '\t':
+			con.completeLine()
+		case 27:
+			// arrows and other escape sequences are not supported
+			con.skipEscape()
+		default:
+			if r >= ' ' {
+				con.insert(r)
+			}
+		}
+	}
+}
+
+func (con *console) write(text string) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	io.WriteString(con.out, text) //nolint:errcheck
+}
+
+func (con *console) lineLen() int {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	return len(con.line)
+}
+
+// reset clears the line being edited and returns it.
+func (con *console) reset(text string) string {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	line := string(con.line)
+	con.line = nil
+	io.WriteString(con.out, text) //nolint:errcheck
+	return line
+}
+
+func (con *console) insert(r rune) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	con.line = append(con.line, r)
+	io.WriteString(con.out, string(r)) //nolint:errcheck
+}
+
+func (con *console) backspace() {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	if len(con.line) > 0 {
+		con.line = con.line[:len(con.line)-1]
+		io.WriteString(con.out, "\b \b") //nolint:errcheck
+	}
+}
+
+func (con *console) skipEscape() {
+	if next, err := con.in.ReadByte(); err != nil || next != '[' {
+		return
+	}
+	for {
+		b, err := con.in.ReadByte()
+		if err != nil || b >= 0x40 && b <= 0x7e {
+			return
+		}
+	}
+}
+
+// completeLine replaces the line with its single completion or the common prefix of
+// the completions, the completions are printed if there is no common prefix to add.
+func (con *console) completeLine() {
+	if con.complete == nil {
+		return
+	}
+	con.mu.Lock()
+	line := string(con.line)
+	con.mu.Unlock()
+	candidates, hint := con.complete(line)
+	if len(candidates) == 0 {
+		return
+	}
+	completed := candidates[0]
+	for _, candidate := range candidates[1:] {
+		completed = commonPrefix(completed, candidate)
+	}
+	if completed != line {
+		con.mu.Lock()
+		io.WriteString(con.out, "\r\033[K"+con.prompt+completed) //nolint:errcheck
+		con.line = []rune(completed)
+		con.mu.Unlock()
+	} else if len(candidates) > 1 {
+		con.Println(strings.Join(candidates, "  "))
+	}
+	if len(candidates) == 1 && hint != "" {
+		con.Println(hint)
+	}
+}
+
+func commonPrefix(a, b string) string {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			// do not split multi-byte runes
+			for i > 0 && !utf8.RuneStart(a[i]) {
+				i--
+			}
+			return a[:i]
+		}
+	}
+	if len(a) < len(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorGray   = "\033[90m"
+)
+
+// eventFilter selects the events printed by the events command, empty fields match all events.
+type eventFilter struct {
+	accounts []uint32
+	// lower case kinds
+	kinds  []string
+	chatId uint32
+}
+
+func (filter *eventFilter) match(event deltachat.Event, ids eventIds) bool {
+	if len(filter.accounts) > 0 && !slices.Contains(filter.accounts, event.ContextId) {
+		return false
+	}
+	if len(filter.kinds) > 0 && !slices.Contains(filter.kinds, strings.ToLower(event.Event.GetKind())) {
+		return false
+	}
+	return filter.chatId == 0 || ids.ChatId == filter.chatId
+}
+
+// eventIds are the chat and message ids of the events that have them.
+type eventIds struct {
+	ChatId uint32 `json:"chatId"`
+	MsgId  uint32 `json:"msgId"`
+}
+
+// eventLine is an event printed as a JSON line.
+type eventLine struct {
+	Time      time.Time           `json:"time"`
+	AccountId uint32              `json:"accountId"`
+	Event     deltachat.EventType `json:"event"`
+	Message   *messageSummary     `json:"message,omitempty"`
+}
+
+// messageSummary annotates the message events.
+type messageSummary struct {
+	Id     uint32             `json:"id"`
+	ChatId uint32             `json:"chatId"`
+	Sender string             `json:"sender"`
+	Text   string             `json:"text"`
+	State  deltachat.MsgState `json:"state"`
+	Error  *string            `json:"error,omitempty"`
+}
+
+func (c *cli) events(args []string) error {
+	flags := newFlagSet("events")
+	accounts := flags.String("accounts", "", "comma-separated account ids (default: all accounts)")
+	kinds := flags.String("kind", "", "comma-separated event kinds, e.g. MsgFailed,Warning (default: all kinds)")
+	chatId := flags.Uint("chat", 0, "only print events of this chat")
+	annotate := flags.Bool("annotate", false, "fetch and print the message of message events")
+	color := flags.Bool("color", isTerminal(c.out), "color the events by kind")
+	count := flags.Int("n", 0, "exit after printing n events")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter := &eventFilter{chatId: uint32(*chatId)}
+	for _, account := range splitList(*accounts) {
+		accId, err := parseId(account, "account")
+		if err != nil {
+			return err
+		}
+		filter.accounts = append(filter.accounts, accId)
+	}
+	for _, kind := range splitList(*kinds) {
+		filter.kinds = append(filter.kinds, strings.ToLower(kind))
+	}
+
+	printed := 0
+	for {
+		events, err := c.rpc.GetNextEventBatch()
+		if err != nil {
+			if errors.Is(err, c.rpc.Context.Err()) {
+				return nil
+			}
+			return err
+		}
+		for _, event := range events {
+			var ids eventIds
+			if data, err := json.Marshal(event.Event); err == nil {
+				json.Unmarshal(data, &ids) //nolint:errcheck
+			}
+			if !filter.match(event, ids) {
+				continue
+			}
+			var summary *messageSummary
+			if *annotate && ids.MsgId != 0 {
+				summary = c.messageSummary(event.ContextId, ids.MsgId)
+			}
+			if err := c.printEvent(event, summary, *color); err != nil {
+				return err
+			}
+			printed++
+			if printed == *count {
+				return nil
+			}
+		}
+	}
+}
+
+// messageSummary returns the summary of a message, nil if it could not be loaded.
+func (c *cli) messageSummary(accId uint32, msgId uint32) *messageSummary {
+	msg, err := c.rpc.GetMessage(accId, msgId)
+	if err != nil {
+		return nil
+	}
+	return &messageSummary{Id: msg.Id, ChatId: msg.ChatId, Sender: msg.Sender.DisplayName, Text: msg.Text, State: msg.State, Error: msg.Error}
+}
+
+func (c *cli) printEvent(event deltachat.Event, summary *messageSummary, color bool) error {
+	now := time.Now()
+	if c.json {
+		data, err := json.Marshal(eventLine{Time: now, AccountId: event.ContextId, Event: event.Event, Message: summary})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.out, "%s\n", data)
+		return err
+	}
+
+	kind := event.Event.GetKind()
+	var fields []string
+	if data, err := json.Marshal(event.Event); err == nil {
+		var values map[string]json.RawMessage
+		json.Unmarshal(data, &values) //nolint:errcheck
+		delete(values, "kind")
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			fields = append(fields, key+"="+string(values[key]))
+		}
+	}
+	line := fmt.Sprintf("%v account=%v %v %v", now.Format(time.TimeOnly), event.ContextId, kind, strings.Join(fields, " "))
+	if summary != nil {
+		line += fmt.Sprintf(" | %v %v: %q", summary.State, summary.Sender, summary.Text)
+		if summary.Error != nil {
+			line += " error=" + strconv.Quote(*summary.Error)
+		}
+	}
+	if code := eventColor(kind); color && code != "" {
+		line = code + line + colorReset
+	}
+	_, err := fmt.Fprintln(c.out, strings.TrimSpace(line))
+	return err
+}
+
+func eventColor(kind string) string {
+	switch {
+	case kind == "Error" || strings.HasSuffix(kind, "Failed"):
+		return colorRed
+	case kind == "Warning":
+		return colorYellow
+	case kind == "Info":
+		return colorGray
+	case strings.HasPrefix(kind, "Msg") || kind == "IncomingMsg" || kind == "SmtpMessageSent":
+		return colorGreen
+	}
+	return ""
+}
+
+func splitList(list string) []string {
+	var items []string
+	for item := range strings.SplitSeq(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isTerminal returns true if out is a terminal.
+func isTerminal(out any) bool {
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+func eventBatches(batches ...[]deltachat.Event) func([]any) (any, error) {
+	return func([]any) (any, error) {
+		if len(batches) == 0 {
+			return nil, errors.New("transport closed")
+		}
+		batch := batches[0]
+		batches = batches[1:]
+		return batch, nil
+	}
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+	c, _, out := newTestCli(map[string]func([]any) (any, error){
+		"get_next_event_batch": eventBatches(
+			[]deltachat.Event{
+				{ContextId: 1, Event: &deltachat.EventTypeInfo{Msg: "ignored"}},
+				{ContextId: 1, Event: &deltachat.EventTypeWarning{Msg: "disk full"}},
+				{ContextId: 2, Event: &deltachat.EventTypeWarning{Msg: "other account"}},
+			},
+			[]deltachat.Event{
+				{ContextId: 1, Event: &deltachat.EventTypeMsgFailed{ChatId: 10, MsgId: 20}},
+				{ContextId: 1, Event: &deltachat.EventTypeMsgFailed{ChatId: 11, MsgId: 21}},
+			},
+		),
+		"get_message": func(params []any) (any, error) {
+			require.Equal(t, uint32(20), params[1])
+			failure := "smtp error"
+			return deltachat.Message{Id: 20, ChatId: 10, Text: "hi", State: deltachat.MsgStateOutFailed, Error: &failure}, nil
+		},
+	})
+
+	err := c.run([]string{"events", "-accounts", "1", "-kind", "warning,msgfailed", "-chat", "0", "-annotate", "-n", "2"})
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], `account=1 Warning msg="disk full"`)
+	require.Contains(t, lines[1], `account=1 MsgFailed chatId=10 msgId=20 | OutFailed : "hi" error="smtp error"`)
+	require.NotContains(t, out.String(), "\033[")
+}
+
+func TestEvents_Json(t *testing.T) {
+	t.Parallel()
+	c, _, out := newTestCli(map[string]func([]any) (any, error){
+		"get_next_event_batch": eventBatches([]deltachat.Event{
+			{ContextId: 1, Event: &deltachat.EventTypeMsgFailed{ChatId: 10, MsgId: 20}},
+			{ContextId: 1, Event: &deltachat.EventTypeMsgFailed{ChatId: 11, MsgId: 21}},
+		}),
+	})
+	c.json = true
+	err := c.run([]string{"events", "-chat", "11", "-color"})
+	require.ErrorContains(t, err, "transport closed")
+
+	var line struct {
+		AccountId uint32          `json:"accountId"`
+		Event     json.RawMessage `json:"event"`
+	}
+	require.Nil(t, json.Unmarshal(out.Bytes(), &line))
+	require.Equal(t, uint32(1), line.AccountId)
+	require.JSONEq(t, `{"kind": "MsgFailed", "chatId": 11, "msgId": 21}`, string(line.Event))
+}
+
+func TestEventColor(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, colorRed, eventColor("MsgFailed"))
+	require.Equal(t, colorYellow, eventColor("Warning"))
+	require.Equal(t, colorGreen, eventColor("SmtpMessageSent"))
+	require.Equal(t, "", eventColor("ConnectivityChanged"))
+}
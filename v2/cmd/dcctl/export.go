@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat/exporter"
+)
+
+func (c *cli) export(args []string) error {
+	flags := newFlagSet("export")
+	formatName := flags.String("format", "json", "export format: json, html or mbox")
+	output := flags.String("o", "", "output file (default: standard output)")
+	attachments := flags.String("attachments", "", "directory to copy the attachments to, HTML transcripts link them with this path")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: dcctl %v", commands["export"].usage)
+	}
+	format, err := exporter.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	chatId, err := parseId(flags.Arg(0), "chat")
+	if err != nil {
+		return err
+	}
+	accId, err := c.accId()
+	if err != nil {
+		return err
+	}
+
+	exp := exporter.New(c.rpc, accId)
+	exp.AttachmentsDir = *attachments
+	transcript, err := exp.Load(chatId)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return transcript.Write(c.out, format)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := transcript.Write(file, format); err != nil {
+		file.Close() //nolint:errcheck
+		return err
+	}
+	return file.Close()
+}
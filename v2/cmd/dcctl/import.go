@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat/importer"
+)
+
+func (c *cli) importTranscript(args []string) error {
+	flags := newFlagSet("import")
+	group := flags.Bool("group", false, "import in a new group instead of the device chat")
+	members := flags.Bool("members", false, "add the senders to the imported group, the messages are then sent to them")
+	dryRun := flags.Bool("dry-run", false, "validate the transcript and print the report without importing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: dcctl %v", commands["import"].usage)
+	}
+	transcript, err := importer.OpenTranscript(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	accId, err := c.accId()
+	if err != nil {
+		return err
+	}
+
+	imp := importer.New(c.rpc, accId)
+	if *group {
+		imp.Target = importer.TargetGroup
+	}
+	imp.AddMembers = *members
+	imp.DryRun = *dryRun
+	report, err := imp.Import(transcript)
+	if err != nil {
+		return err
+	}
+	return c.printValue(report)
+}
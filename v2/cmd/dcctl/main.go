@@ -0,0 +1,145 @@
+// Command dcctl is a command-line client of deltachat-rpc-server to inspect and operate
+// accounts, e.g. bot accounts in production.
+//
+// Usage:
+//
+//	dcctl [-accounts dir] [-server path] [-a account] [-json] command [arguments]
+//
+// The commands are:
+//
+//	accounts list                   list the accounts
+//	accounts add                    add a new unconfigured account
+//	accounts remove <account>       remove an account
+//	chats list [query]              list the chats
+//	send [-file path] <chat> [text] send a message
+//	contacts [query]                list the contacts
+//	config get <key>                print a configuration value
+//	config set <key> [value]        set a configuration value, unset it if value is not given
+//	connectivity                    print the connectivity
+//	qr show [-svg] [chat]           print the invite QR code of the account or of a group
+//	backup export [-passphrase p] [-keep n] [-verify] <dir>
+//	                                export a backup of the account to the directory,
+//	                                keeping the n newest backups, see package backup
+//	backup import [-passphrase p] <file>
+//	                                import a backup into a new account
+//	repl                            call JSON-RPC methods interactively, see below
+//	events [-accounts ids] [-kind kinds] [-chat id] [-annotate] [-color] [-n count]
+//	                                print the events of the server as they arrive
+//	export [-format json|html|mbox] [-o file] [-attachments dir] <chat>
+//	                                export the messages of a chat, see package exporter
+//	import [-group] [-members] [-dry-run] <file>
+//	                                import a JSON transcript, see package importer
+//
+// The repl command reads JSON-RPC method calls like `get_config 1 "addr"` and prints their
+// results, method names are completed with Tab. The events received from the server are
+// shown in a pane at the bottom of the terminal, or printed above the prompt if the terminal
+// is too small.
+//
+// The events command prints the events of all accounts, or of the accounts and event kinds
+// given as comma-separated lists, e.g. -kind MsgFailed,Warning. With -annotate, the message
+// of message events is fetched and summarized. With -json, events are printed as JSON lines.
+//
+// Commands operating on an account use the account given with -a, or the selected
+// account of the accounts directory. With -json, results are printed as JSON instead of tables.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+func main() {
+	accountsDir := flag.String("accounts", "", "accounts directory (default: DC_ACCOUNTS_PATH or deltachat-rpc-server default)")
+	server := flag.String("server", "deltachat-rpc-server", "deltachat-rpc-server binary")
+	accId := flag.Uint("a", 0, "account id (default: selected account)")
+	jsonOutput := flag.Bool("json", false, "print results as JSON")
+	verbose := flag.Bool("v", false, "print the logs of deltachat-rpc-server")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: dcctl [flags] command [arguments]")
+		flag.PrintDefaults()
+		fmt.Fprintln(flag.CommandLine.Output(), "\ncommands:")
+		printCommands(flag.CommandLine.Output())
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	trans := deltachat.NewIOTransport()
+	trans.Cmd = *server
+	trans.AccountsDir = *accountsDir
+	if !*verbose {
+		trans.Stderr = nil
+	}
+	if err := trans.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, "dcctl:", err)
+		os.Exit(1)
+	}
+	// stop commands like events on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	c := &cli{
+		rpc:     &deltachat.Rpc{Context: ctx, Transport: trans},
+		out:     os.Stdout,
+		json:    *jsonOutput,
+		account: uint32(*accId),
+		server:  *server,
+	}
+	err := c.run(flag.Args())
+	stop()
+	trans.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dcctl:", err)
+		os.Exit(1)
+	}
+}
+
+// cli runs the dcctl commands.
+type cli struct {
+	rpc  *deltachat.Rpc
+	out  io.Writer
+	json bool
+	// account given with -a, 0 for the selected account
+	account uint32
+	// deltachat-rpc-server binary given with -server
+	server string
+}
+
+type command struct {
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+// commands are the dcctl commands by name, commands with subcommands dispatch on their first argument.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"accounts":     {"accounts list|add|remove", (*cli).accounts},
+		"chats":        {"chats list [query]", (*cli).chats},
+		"send":         {"send [-file path] <chat> [text]", (*cli).send},
+		"contacts":     {"contacts [query]", (*cli).contacts},
+		"config":       {"config get|set <key> [value]", (*cli).config},
+		"connectivity": {"connectivity", (*cli).connectivity},
+		"qr":           {"qr show [-svg] [chat]", (*cli).qr},
+		"backup":       {"backup export|import", (*cli).backup},
+		"repl":         {"repl", (*cli).repl},
+		"events":       {"events [-accounts ids] [-kind kinds] [-chat id] [-annotate] [-color] [-n count]", (*cli).events},
+		"export":       {"export [-format json|html|mbox] [-o file] [-attachments dir] <chat>", (*cli).export},
+		"import":       {"import [-group] [-members] [-dry-run] <file>", (*cli).importTranscript},
+	}
+}
+
+func (c *cli) run(args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, run dcctl -h for the list of commands", args[0])
+	}
+	return cmd.run(c, args[1:])
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransport answers the RPC calls with the handler registered for the method.
+type fakeTransport struct {
+	handlers map[string]func(params []any) (any, error)
+	calls    []string
+}
+
+func (trans *fakeTransport) Call(ctx context.Context, method string, params ...any) error {
+	return trans.CallResult(ctx, nil, method, params...)
+}
+
+func (trans *fakeTransport) CallResult(ctx context.Context, result any, method string, params ...any) error {
+	trans.calls = append(trans.calls, method)
+	handler, ok := trans.handlers[method]
+	if !ok {
+		return fmt.Errorf("unexpected call to %v", method)
+	}
+	value, err := handler(params)
+	if err != nil || result == nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+func newTestCli(handlers map[string]func(params []any) (any, error)) (*cli, *fakeTransport, *bytes.Buffer) {
+	trans := &fakeTransport{handlers: handlers}
+	out := &bytes.Buffer{}
+	c := &cli{rpc: &deltachat.Rpc{Context: context.Background(), Transport: trans}, out: out, account: 1}
+	return c, trans, out
+}
+
+func result(value any) func([]any) (any, error) {
+	return func([]any) (any, error) { return value, nil }
+}
+
+func TestAccountsList(t *testing.T) {
+	t.Parallel()
+	addr := "bot@example.org"
+	c, _, out := newTestCli(map[string]func([]any) (any, error){
+		"get_all_accounts": result([]deltachat.Account{
+			&deltachat.AccountConfigured{Id: 1, Addr: &addr},
+			&deltachat.AccountUnconfigured{Id: 2},
+		}),
+	})
+	require.Nil(t, c.run([]string{"accounts", "list"}))
+	require.Equal(t, "ID  STATUS        ADDRESS          NAME\n1   configured    bot@example.org  \n2   unconfigured                   \n", out.String())
+
+	out.Reset()
+	c.json = true
+	require.Nil(t, c.run([]string{"accounts", "list"}))
+	var accounts []map[string]any
+	require.Nil(t, json.Unmarshal(out.Bytes(), &accounts))
+	require.Equal(t, "Unconfigured", accounts[1]["kind"])
+
+	require.ErrorContains(t, c.run([]string{"accounts", "rename"}), "usage: dcctl accounts")
+	require.ErrorContains(t, c.run([]string{"unknown"}), "unknown command")
+}
+
+func TestSend(t *testing.T) {
+	t.Parallel()
+	var data deltachat.MessageData
+	c, _, out := newTestCli(map[string]func([]any) (any, error){
+		"send_msg": func(params []any) (any, error) {
+			require.Equal(t, uint32(10), params[1])
+			data = params[2].(deltachat.MessageData)
+			return 42, nil
+		},
+	})
+	require.Nil(t, c.run([]string{"send", "-file", "report.csv", "10", "hello", "world"}))
+	require.Equal(t, "hello world", *data.Text)
+	require.Equal(t, "report.csv", *data.File)
+	require.Equal(t, "42\n", out.String())
+
+	require.ErrorContains(t, c.run([]string{"send", "10"}), "usage: dcctl send")
+	require.ErrorContains(t, c.run([]string{"send", "chat", "hi"}), "invalid chat id")
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+	values := map[string]*string{}
+	c, _, out := newTestCli(map[string]func([]any) (any, error){
+		"set_config": func(params []any) (any, error) {
+			values[params[1].(string)] = params[2].(*string)
+			return nil, nil
+		},
+		"get_config": func(params []any) (any, error) {
+			return values[params[1].(string)], nil
+		},
+	})
+	require.Nil(t, c.run([]string{"config", "set", "displayname", "Echo", "Bot"}))
+	require.Nil(t, c.run([]string{"config", "get", "displayname"}))
+	require.Equal(t, "Echo Bot\n", out.String())
+
+	var valueErr *deltachat.ConfigValueErr
+	require.ErrorAs(t, c.run([]string{"config", "set", "bot", "yes"}), &valueErr)
+
+	require.Nil(t, c.run([]string{"config", "set", "displayname"}))
+	require.Nil(t, values["displayname"])
+}
+
+func TestAccId(t *testing.T) {
+	t.Parallel()
+	c, trans, out := newTestCli(map[string]func([]any) (any, error){
+		"get_selected_account_id": result(nil),
+		"get_all_account_ids":     result([]uint32{3, 4}),
+		"get_connectivity": func(params []any) (any, error) {
+			require.Equal(t, uint32(3), params[0])
+			return deltachat.ConnectivityConnected, nil
+		},
+	})
+	c.account = 0
+	require.Nil(t, c.run([]string{"connectivity"}))
+	require.Equal(t, "Connected\n", out.String())
+	require.Equal(t, []string{"get_selected_account_id", "get_all_account_ids", "get_connectivity"}, trans.calls)
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+	c, _, out := newTestCli(map[string]func([]any) (any, error){
+		"get_basic_chat_info": result(deltachat.BasicChat{Id: 10, Name: "support", ChatType: deltachat.ChatTypeGroup}),
+		"get_message_ids":     result([]uint32{20}),
+		"get_messages": result(map[string]any{
+			"20": map[string]any{"kind": "message", "id": 20, "chatId": 10, "text": "hello", "sender": map[string]any{"address": "alice@example.org"}},
+		}),
+	})
+	require.Nil(t, c.run([]string{"export", "-format", "mbox", "10"}))
+	require.Contains(t, out.String(), "From alice@example.org ")
+	require.Contains(t, out.String(), "\nhello\n")
+
+	require.ErrorContains(t, c.run([]string{"export", "-format", "pdf", "10"}), "unknown export format")
+}
+
+func TestImportDryRun(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	transcript := `{"chatName": "Support", "messages": [{"id": 1, "sender": {"address": "alice@example.org"}, "timestamp": "2024-05-01T10:30:00Z", "text": "Hello"}]}`
+	require.Nil(t, os.WriteFile(path, []byte(transcript), 0o644))
+	c, trans, out := newTestCli(nil)
+	require.Nil(t, c.run([]string{"import", "-group", "-dry-run", path}))
+	require.Empty(t, trans.calls)
+	require.Equal(t, "would import 1 messages with 0 attachments from \"Support\" into group chat, 1 contacts, 0 skipped messages\n", out.String())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// printTable prints the rows as a table, or value as JSON if -json was given.
+func (c *cli) printTable(value any, header []string, rows [][]string) error {
+	if c.json {
+		return c.printJSON(value)
+	}
+	writer := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		for i, cell := range row {
+			// keep multi-line summaries in their row
+			row[i] = strings.ReplaceAll(cell, "\n", " ")
+		}
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+// printValue prints a single value, as JSON if -json was given.
+func (c *cli) printValue(value any) error {
+	if c.json {
+		return c.printJSON(value)
+	}
+	_, err := fmt.Fprintln(c.out, value)
+	return err
+}
+
+func (c *cli) printJSON(value any) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func formatId(id uint32) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
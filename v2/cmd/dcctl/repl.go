@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+const replHelp = `Enter a JSON-RPC method followed by its parameters as JSON values, e.g.:
+
+  get_config 1 "displayname"
+  send_msg 1 10 {"text": "hello"}
+
+Press Tab to complete method names. Other commands:
+
+  method?            print the signature of a method
+  :methods [prefix]  list the methods of the bindings
+  :events on|off     show or hide the events received from the server
+  :help              print this help
+  :quit              exit (also Ctrl-D)`
+
+// repl evaluates raw JSON-RPC calls and prints the events of the server.
+type repl struct {
+	rpc *deltachat.Rpc
+	// signatures of the methods by name, see deltachat.BindingsMethods()
+	methods map[string]string
+	names   []string
+	events  atomic.Bool
+}
+
+func newRepl(rpc *deltachat.Rpc) *repl {
+	methods := deltachat.BindingsMethods()
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	r := &repl{rpc: rpc, methods: methods, names: names}
+	r.events.Store(true)
+	return r
+}
+
+func (c *cli) repl(args []string) error {
+	r := newRepl(c.rpc)
+	con := &console{in: bufio.NewReader(os.Stdin), out: c.out, prompt: "dc> ", complete: r.complete}
+	if restore, err := setRawMode(os.Stdin); err == nil {
+		defer restore()
+		con.interactive = true
+		if rows, cols, err := terminalSize(os.Stdin); err == nil {
+			con.OpenPane(rows, cols)
+			defer con.ClosePane()
+		}
+		con.Println("Type :help for help.")
+	}
+
+	ctx, cancel := context.WithCancel(c.rpc.Context)
+	defer cancel()
+	go r.printEvents(ctx, con)
+
+	for {
+		line, err := con.ReadLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == ":quit" || line == ":q" {
+			return nil
+		}
+		output, err := r.eval(line)
+		if err != nil {
+			con.Println("error: " + err.Error())
+		} else if output != "" {
+			con.Println(output)
+		}
+	}
+}
+
+// eval evaluates a line and returns the text to print.
+func (r *repl) eval(line string) (string, error) {
+	switch {
+	case line == "":
+		return "", nil
+	case line == ":help":
+		return replHelp, nil
+	case strings.HasPrefix(line, ":methods"):
+		prefix := strings.TrimSpace(strings.TrimPrefix(line, ":methods"))
+		var lines []string
+		for _, name := range r.names {
+			if strings.HasPrefix(name, prefix) {
+				lines = append(lines, name+r.methods[name])
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	case strings.HasPrefix(line, ":events"):
+		switch strings.TrimSpace(strings.TrimPrefix(line, ":events")) {
+		case "on":
+			r.events.Store(true)
+		case "off":
+			r.events.Store(false)
+		default:
+			return "", errors.New("usage: :events on|off")
+		}
+		return "", nil
+	case strings.HasPrefix(line, ":"):
+		return "", fmt.Errorf("unknown command %q, type :help for help", line)
+	case strings.HasSuffix(line, "?"):
+		name := strings.TrimSuffix(line, "?")
+		signature, ok := r.methods[name]
+		if !ok {
+			return "", fmt.Errorf("unknown method %q", name)
+		}
+		return name + signature, nil
+	}
+
+	method, rest, _ := strings.Cut(line, " ")
+	params, err := parseParams(rest)
+	if err != nil {
+		return "", err
+	}
+	var result json.RawMessage
+	if err := r.rpc.Transport.CallResult(r.rpc.Context, &result, method, params...); err != nil {
+		return "", err
+	}
+	return formatJSON(result), nil
+}
+
+// parseParams returns the JSON values of the given text.
+func parseParams(text string) ([]any, error) {
+	params := []any{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	for {
+		var param json.RawMessage
+		err := decoder.Decode(&param)
+		if errors.Is(err, io.EOF) {
+			return params, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid parameter %v: %w", len(params)+1, err)
+		}
+		params = append(params, param)
+	}
+}
+
+func formatJSON(data json.RawMessage) string {
+	if len(data) == 0 {
+		return "null"
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
+// complete returns the method names starting with the method name being typed,
+// and the signature of the method as hint.
+func (r *repl) complete(line string) ([]string, string) {
+	if strings.Contains(line, " ") {
+		method, _, _ := strings.Cut(line, " ")
+		if signature, ok := r.methods[method]; ok {
+			return []string{line}, method + signature
+		}
+		return nil, ""
+	}
+	var candidates []string
+	for _, name := range r.names {
+		if strings.HasPrefix(name, line) {
+			candidates = append(candidates, name+" ")
+		}
+	}
+	if len(candidates) == 1 {
+		method := strings.TrimSpace(candidates[0])
+		return candidates, method + r.methods[method]
+	}
+	return candidates, ""
+}
+
+// printEvents shows the events of the server in the events pane until ctx is canceled.
+func (r *repl) printEvents(ctx context.Context, con *console) {
+	rpc := &deltachat.Rpc{Context: ctx, Transport: r.rpc.Transport}
+	for {
+		events, err := rpc.GetNextEventBatch()
+		if err != nil {
+			return
+		}
+		if !r.events.Load() {
+			continue
+		}
+		for _, event := range events {
+			data, _ := json.Marshal(event.Event)
+			con.PrintEvent(fmt.Sprintf("[event] account %v: %s", event.ContextId, data))
+		}
+	}
+}
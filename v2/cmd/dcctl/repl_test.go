@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepl_Eval(t *testing.T) {
+	t.Parallel()
+	var params []any
+	c, _, _ := newTestCli(map[string]func([]any) (any, error){
+		"send_msg": func(p []any) (any, error) {
+			params = p
+			return 42, nil
+		},
+		"get_config": result(map[string]any{"value": nil}),
+	})
+	r := newRepl(c.rpc)
+
+	output, err := r.eval(`send_msg 1 10 {"text": "hello world"}`)
+	require.Nil(t, err)
+	require.Equal(t, "42", output)
+	require.Len(t, params, 3)
+	require.JSONEq(t, `{"text": "hello world"}`, string(params[2].(json.RawMessage)))
+
+	output, err = r.eval(`get_config 1 "addr"`)
+	require.Nil(t, err)
+	require.Equal(t, "{\n  \"value\": null\n}", output)
+
+	_, err = r.eval(`send_msg 1 {`)
+	require.ErrorContains(t, err, "invalid parameter 2")
+
+	output, err = r.eval("get_config?")
+	require.Nil(t, err)
+	require.Equal(t, "get_config(uint32, string) *string", output)
+	output, err = r.eval(":methods get_conf")
+	require.Nil(t, err)
+	require.Contains(t, output, "get_config(uint32, string) *string")
+
+	_, err = r.eval(":events off")
+	require.Nil(t, err)
+	require.False(t, r.events.Load())
+}
+
+func TestRepl_Complete(t *testing.T) {
+	t.Parallel()
+	r := newRepl(nil)
+	candidates, hint := r.complete("get_chat")
+	require.Contains(t, candidates, "get_chat_media ")
+	require.Contains(t, candidates, "get_chatlist_entries ")
+	require.Empty(t, hint)
+
+	candidates, hint = r.complete("batch_get_c")
+	require.Equal(t, []string{"batch_get_config "}, candidates)
+	require.Equal(t, "batch_get_config(uint32, []string) map[string]*string", hint)
+
+	candidates, hint = r.complete("get_config 1 ")
+	require.Equal(t, []string{"get_config 1 "}, candidates)
+	require.Equal(t, "get_config(uint32, string) *string", hint)
+}
+
+func TestConsole(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	con := &console{
+		in:          bufio.NewReader(strings.NewReader("get_c\t1\x7f2 \x1b[A\"x\"\rabc\x03\x04")),
+		out:         &out,
+		interactive: true,
+		prompt:      "> ",
+		complete: func(line string) ([]string, string) {
+			return []string{"get_config "}, "hint"
+		},
+	}
+	line, err := con.ReadLine()
+	require.Nil(t, err)
+	require.Equal(t, `get_config 2 "x"`, line)
+	require.Contains(t, out.String(), "hint\n")
+
+	_, err = con.ReadLine()
+	require.Equal(t, io.EOF, err)
+
+	con = &console{in: bufio.NewReader(strings.NewReader("first\nsecond")), out: &out}
+	line, _ = con.ReadLine()
+	require.Equal(t, "first", line)
+	line, _ = con.ReadLine()
+	require.Equal(t, "second", line)
+	_, err = con.ReadLine()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestConsole_EventPane(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	con := &console{out: &out, interactive: true, prompt: "> "}
+	con.OpenPane(24, 20)
+	// rows 17 to 24 show the events, the prompt scrolls above the separator
+	require.Contains(t, out.String(), "\033[1;15r")
+	require.Equal(t, 17, con.pane.top)
+
+	for i := range 10 {
+		con.PrintEvent(fmt.Sprintf("event %v", i))
+	}
+	con.PrintEvent(strings.Repeat("x", 30))
+	require.Len(t, con.pane.lines, 8)
+	require.Equal(t, "event 3", con.pane.lines[0])
+	require.Equal(t, strings.Repeat("x", 19)+"…", con.pane.lines[7])
+	require.Contains(t, out.String(), "\033[24;1H\033[2K"+strings.Repeat("x", 19)+"…\0338")
+
+	con.ClosePane()
+	require.Nil(t, con.pane)
+	out.Reset()
+	con.PrintEvent("event")
+	require.Equal(t, "\r\033[Kevent\n> ", out.String())
+
+	// small terminals are not split
+	con.OpenPane(10, 80)
+	require.Nil(t, con.pane)
+}
+
+func TestCommonPrefix(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "get_c", commonPrefix("get_config", "get_chat"))
+	require.Equal(t, "get", commonPrefix("get", "get_chat"))
+	require.Equal(t, "a", commonPrefix("aé", "aè"))
+}
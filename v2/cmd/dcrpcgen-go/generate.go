@@ -0,0 +1,577 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/chatmail/rpc-client-go/v2/internal/openrpc"
+)
+
+type generator struct {
+	doc   *openrpc.Document
+	types *openrpc.Types
+	pkg   string
+	// decoded is the set of tagged unions that need an unmarshal helper.
+	decoded map[string]bool
+}
+
+// typeOverrides maps integers of the schema to the named types declared in const.go.
+// Keys are "Struct.property" for fields, "method.param" for parameters and "method"
+// for results.
+var typeOverrides = map[string]string{
+	"Message.state":                  "MsgState",
+	"MessageLoadResultMessage.state": "MsgState",
+	"get_chatlist_entries.listFlags": "ChatListFlags",
+	"get_contact_ids.listFlags":      "ContactFlags",
+	"get_contacts.listFlags":         "ContactFlags",
+	"get_connectivity":               "Connectivity",
+}
+
+// overrideType returns typ with its base type replaced by the override for key, if any.
+func overrideType(key, typ string) string {
+	named, ok := typeOverrides[key]
+	if !ok {
+		return typ
+	}
+	if strings.HasPrefix(typ, "*") {
+		return "*" + named
+	}
+	return named
+}
+
+func newGenerator(doc *openrpc.Document, pkg string) (*generator, error) {
+	types, err := openrpc.NewTypes(doc)
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{doc: doc, types: types, pkg: pkg, decoded: make(map[string]bool)}
+
+	// unions that are only used as parameters don't need to be unmarshalled
+	for _, method := range doc.Methods {
+		if method.Result != nil {
+			if err := g.markDecoded(method.Result.Schema); err != nil {
+				return nil, fmt.Errorf("%v: %w", method.Name, err)
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(doc.Components.Schemas)) {
+		for _, object := range append([]*openrpc.Schema{doc.Components.Schemas[name]}, types.Unions[name]...) {
+			for _, prop := range object.Properties {
+				if err := g.markDecoded(prop); err != nil {
+					return nil, fmt.Errorf("%v: %w", name, err)
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+func (g *generator) markDecoded(schema *openrpc.Schema) error {
+	schema, _ = schema.NonNull()
+	if name := schema.RefName(); name != "" {
+		if g.types.IsUnion(name) {
+			g.decoded[name] = true
+		}
+		return nil
+	}
+	for _, item := range schema.Items {
+		if err := g.markDecoded(item); err != nil {
+			return err
+		}
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		return g.markDecoded(schema.AdditionalProperties.Schema)
+	}
+	return nil
+}
+
+type field struct {
+	name     string
+	jsonName string
+	typ      string
+	doc      string
+	// union is the name of the tagged union if the field holds one.
+	union    string
+	optional bool
+}
+
+func (g *generator) fields(structName string, schema *openrpc.Schema) ([]field, error) {
+	var fields []field
+	for _, name := range slices.Sorted(maps.Keys(schema.Properties)) {
+		if name == "kind" {
+			if _, ok := schema.VariantKind(); ok {
+				continue
+			}
+		}
+		prop := schema.Properties[name]
+		typ, nullable, err := g.types.GoType(prop)
+		if err != nil {
+			return nil, fmt.Errorf("property %v: %w", name, err)
+		}
+		f := field{name: exportedName(name), jsonName: name, typ: typ, doc: prop.Description}
+		f.optional = nullable || !schema.IsRequired(name)
+		if g.types.IsUnion(typ) {
+			f.union = typ
+		} else if g.containsUnion(typ) {
+			return nil, fmt.Errorf("property %v: collections of tagged unions are not supported in objects", name)
+		}
+		if f.optional {
+			if f.union != "" {
+				f.typ = "*" + typ
+			} else {
+				f.typ = openrpc.Optional(typ, true)
+			}
+		}
+		f.typ = overrideType(structName+"."+name, f.typ)
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// containsUnion returns true if typ is a collection of tagged unions.
+func (g *generator) containsUnion(typ string) bool {
+	for {
+		var ok bool
+		if typ, ok = strings.CutPrefix(typ, "[]"); ok {
+			continue
+		}
+		if typ, ok = strings.CutPrefix(typ, "map[string]"); ok {
+			continue
+		}
+		if typ, ok = strings.CutPrefix(typ, "*"); ok {
+			continue
+		}
+		break
+	}
+	return g.types.IsUnion(typ)
+}
+
+// Types returns the source code of types.go.
+func (g *generator) Types() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %v\n\nimport (\n\t\"encoding/json\"\n", g.pkg)
+	if len(g.decoded) > 0 {
+		buf.WriteString("\t\"fmt\"\n")
+	}
+	buf.WriteString(`)
+
+// Pair is a generic two-element tuple used for RPC methods that return two values.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+func (p *Pair[A, B]) UnmarshalJSON(data []byte) error {
+	var raw [2]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw[0], &p.First); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[1], &p.Second)
+}
+`)
+
+	for _, name := range slices.Sorted(maps.Keys(g.doc.Components.Schemas)) {
+		schema := g.doc.Components.Schemas[name]
+		var err error
+		if values, docs, ok := schema.StringEnum(); ok {
+			g.writeEnum(&buf, name, schema, values, docs)
+		} else if variants, ok := g.types.Unions[name]; ok {
+			err = g.writeUnion(&buf, name, schema, variants)
+		} else if len(schema.Properties) > 0 || schema.TypeName() == "object" && schema.AdditionalProperties == nil {
+			err = g.writeStruct(&buf, name, schema)
+		} else {
+			var typ string
+			typ, _, err = g.types.GoType(schema)
+			if err == nil {
+				buf.WriteString("\n")
+				writeDoc(&buf, "", schema.Description)
+				fmt.Fprintf(&buf, "type %v %v\n", name, typ)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+	}
+	return formatSource(buf.Bytes())
+}
+
+func (g *generator) writeEnum(buf *bytes.Buffer, name string, schema *openrpc.Schema, values, docs []string) {
+	buf.WriteString("\n")
+	writeDoc(buf, "", schema.Description)
+	fmt.Fprintf(buf, "type %v string\n\nconst (\n", name)
+	for i, value := range values {
+		writeDoc(buf, "\t", docs[i])
+		fmt.Fprintf(buf, "\t%v%v %v = %q\n", name, exportedName(value), name, value)
+	}
+	buf.WriteString(")\n")
+}
+
+func (g *generator) writeStruct(buf *bytes.Buffer, name string, schema *openrpc.Schema) error {
+	fields, err := g.fields(name, schema)
+	if err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	writeDoc(buf, "", schema.Description)
+	writeStructType(buf, name, fields)
+	writeMarshal(buf, name, fields)
+	writeUnmarshal(buf, name, fields)
+	return nil
+}
+
+func (g *generator) writeUnion(buf *bytes.Buffer, name string, schema *openrpc.Schema, variants []*openrpc.Schema) error {
+	buf.WriteString("\n")
+	writeDoc(buf, "", schema.Description)
+	fmt.Fprintf(buf, "type %v interface {\n\tis%vVariant()\n\tGetKind() string\n}\n", name, name)
+
+	for _, variant := range variants {
+		kind, _ := variant.VariantKind()
+		typeName := name + exportedName(kind)
+		fields, err := g.fields(typeName, variant)
+		if err != nil {
+			return fmt.Errorf("variant %v: %w", kind, err)
+		}
+		buf.WriteString("\n")
+		writeDoc(buf, "", variant.Description)
+		writeStructType(buf, typeName, fields)
+		fmt.Fprintf(buf, "\nfunc (*%v) is%vVariant() {}\n", typeName, name)
+		fmt.Fprintf(buf, "func (*%v) GetKind() string { return %q }\n", typeName, kind)
+		fmt.Fprintf(buf, "func (v *%v) MarshalJSON() ([]byte, error) {\n\ttype alias %v\n", typeName, typeName)
+		buf.WriteString("\treturn json.Marshal(struct {\n\t\tKind string `json:\"kind\"`\n\t\talias\n")
+		writeUnionFields(buf, fields)
+		fmt.Fprintf(buf, "\t}{Kind: %q, alias: alias(*v)%v})\n}\n", kind, unionValues("v", fields))
+		writeUnmarshal(buf, typeName, fields)
+	}
+
+	if !g.decoded[name] {
+		return nil
+	}
+	fmt.Fprintf(buf, `
+func unmarshal%v(data json.RawMessage, out *%v) error {
+	var header struct {
+		Kind string `+"`json:\"kind\"`"+`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+	switch header.Kind {
+`, name, name)
+	for _, variant := range variants {
+		kind, _ := variant.VariantKind()
+		fmt.Fprintf(buf, `	case %q:
+		var v %v
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*out = &v
+`, kind, name+exportedName(kind))
+	}
+	fmt.Fprintf(buf, `	default:
+		return fmt.Errorf("unknown %v variant: %%q", header.Kind)
+	}
+	return nil
+}
+`, name)
+	return nil
+}
+
+func writeStructType(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, "type %v struct {\n", name)
+	for _, f := range fields {
+		writeDoc(buf, "\t", f.doc)
+		fmt.Fprintf(buf, "\t%v %v `json:\"%v\"`\n", f.name, f.typ, f.tag())
+	}
+	buf.WriteString("}\n")
+}
+
+func (f field) tag() string {
+	if f.union != "" {
+		return "-"
+	}
+	if f.optional {
+		return f.jsonName + ",omitempty"
+	}
+	return f.jsonName
+}
+
+// writeMarshal writes a MarshalJSON method for structs with tagged union fields, which
+// are excluded from the default encoding.
+func writeMarshal(buf *bytes.Buffer, name string, fields []field) {
+	if !slices.ContainsFunc(fields, func(f field) bool { return f.union != "" }) {
+		return
+	}
+	fmt.Fprintf(buf, "\nfunc (s %v) MarshalJSON() ([]byte, error) {\n\ttype alias %v\n\treturn json.Marshal(struct {\n\t\talias\n", name, name)
+	writeUnionFields(buf, fields)
+	fmt.Fprintf(buf, "\t}{alias: alias(s)%v})\n}\n", unionValues("s", fields))
+}
+
+// writeUnionFields writes the tagged union fields of a struct with their JSON names.
+func writeUnionFields(buf *bytes.Buffer, fields []field) {
+	for _, f := range fields {
+		if f.union == "" {
+			continue
+		}
+		tag := f.jsonName
+		if f.optional {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(buf, "\t\t%v %v `json:\"%v\"`\n", f.name, f.typ, tag)
+	}
+}
+
+// unionValues returns the composite literal elements copying the tagged union fields of recv.
+func unionValues(recv string, fields []field) string {
+	var values strings.Builder
+	for _, f := range fields {
+		if f.union != "" {
+			fmt.Fprintf(&values, ", %v: %v.%v", f.name, recv, f.name)
+		}
+	}
+	return values.String()
+}
+
+// writeUnmarshal writes an UnmarshalJSON method for structs with tagged union fields.
+func writeUnmarshal(buf *bytes.Buffer, name string, fields []field) {
+	if !slices.ContainsFunc(fields, func(f field) bool { return f.union != "" }) {
+		return
+	}
+	fmt.Fprintf(buf, "\nfunc (s *%v) UnmarshalJSON(data []byte) error {\n\tvar raw struct {\n", name)
+	for _, f := range fields {
+		if f.union != "" {
+			fmt.Fprintf(buf, "\t\t%v json.RawMessage `json:\"%v\"`\n", f.name, f.jsonName)
+		} else {
+			fmt.Fprintf(buf, "\t\t%v %v `json:\"%v\"`\n", f.name, f.typ, f.tag())
+		}
+	}
+	buf.WriteString("\t}\n\tif err := json.Unmarshal(data, &raw); err != nil {\n\t\treturn err\n\t}\n")
+	for _, f := range fields {
+		if f.union == "" {
+			fmt.Fprintf(buf, "\ts.%v = raw.%v\n", f.name, f.name)
+		}
+	}
+	assign := ":="
+	for _, f := range fields {
+		switch {
+		case f.union == "":
+		case f.optional:
+			// absent and null values clear the field, data may be decoded into a used value
+			fmt.Fprintf(buf, `	s.%v = nil
+	if len(raw.%v) > 0 && string(raw.%v) != "null" {
+		var val %v
+		if err := unmarshal%v(raw.%v, &val); err != nil {
+			return err
+		}
+		s.%v = &val
+	}
+`, f.name, f.name, f.name, f.union, f.union, f.name, f.name)
+		default:
+			fmt.Fprintf(buf, `	err %v unmarshal%v(raw.%v, &s.%v)
+	if err != nil {
+		return err
+	}
+`, assign, f.union, f.name, f.name)
+			assign = "="
+		}
+	}
+	buf.WriteString("\treturn nil\n}\n")
+}
+
+// Rpc returns the source code of rpc.go.
+func (g *generator) Rpc() ([]byte, error) {
+	var body bytes.Buffer
+	usesJson := false
+	for _, method := range g.doc.Methods {
+		raw, err := g.writeMethod(&body, method)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", method.Name, err)
+		}
+		usesJson = usesJson || raw
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %v\n\nimport (\n\t\"context\"\n", g.pkg)
+	if usesJson {
+		buf.WriteString("\t\"encoding/json\"\n")
+	}
+	buf.WriteString(`)
+
+// Delta Chat RPC client. This is the root of the API.
+type Rpc struct {
+	// Context to be used on calls to Transport.CallResult() and Transport.Call()
+	Context   context.Context
+	Transport RpcTransport
+}
+`)
+	buf.Write(body.Bytes())
+	return formatSource(buf.Bytes())
+}
+
+// writeMethod writes the Rpc method for the given RPC method, it returns true if the
+// result is decoded from json.RawMessage.
+func (g *generator) writeMethod(buf *bytes.Buffer, method openrpc.Method) (bool, error) {
+	var params, args []string
+	for _, param := range method.Params {
+		typ, err := g.types.ParamType(param)
+		if err != nil {
+			return false, fmt.Errorf("param %v: %w", param.Name, err)
+		}
+		name := unexportedName(param.Name)
+		typ = overrideType(method.Name+"."+name, typ)
+		params = append(params, name+" "+typ)
+		args = append(args, ", "+name)
+	}
+	call := fmt.Sprintf("%q%v", method.Name, strings.Join(args, ""))
+
+	doc := method.Description
+	if doc == "" {
+		doc = method.Summary
+	}
+	buf.WriteString("\n")
+	writeDoc(buf, "", doc)
+	signature := fmt.Sprintf("func (rpc *Rpc) %v(%v)", exportedName(method.Name), strings.Join(params, ", "))
+
+	typ, nullable, err := g.types.ResultType(method)
+	if err != nil {
+		return false, fmt.Errorf("result: %w", err)
+	}
+	typ = overrideType(method.Name, typ)
+	if typ == "" {
+		fmt.Fprintf(buf, "%v error {\n\treturn rpc.Transport.Call(rpc.Context, %v)\n}\n", signature, call)
+		return false, nil
+	}
+
+	if union := g.unionOf(typ, ""); union != "" {
+		fmt.Fprintf(buf, `%v (%v, error) {
+	var raw json.RawMessage
+	if err := rpc.Transport.CallResult(rpc.Context, &raw, %v); err != nil {
+		return nil, err
+	}
+`, signature, typ, call)
+		if nullable {
+			buf.WriteString("\tif string(raw) == \"null\" {\n\t\treturn nil, nil\n\t}\n")
+		}
+		fmt.Fprintf(buf, "\tvar result %v\n\terr := unmarshal%v(raw, &result)\n\treturn result, err\n}\n", typ, union)
+		return true, nil
+	}
+	if union := g.unionOf(typ, "[]"); union != "" {
+		fmt.Fprintf(buf, `%v (%v, error) {
+	var rawList []json.RawMessage
+	if err := rpc.Transport.CallResult(rpc.Context, &rawList, %v); err != nil {
+		return nil, err
+	}
+	result := make(%v, len(rawList))
+	for i, raw := range rawList {
+		if err := unmarshal%v(raw, &result[i]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+`, signature, typ, call, typ, union)
+		return true, nil
+	}
+	if union := g.unionOf(typ, "map[string]"); union != "" {
+		fmt.Fprintf(buf, `%v (%v, error) {
+	var rawMap map[string]json.RawMessage
+	if err := rpc.Transport.CallResult(rpc.Context, &rawMap, %v); err != nil {
+		return nil, err
+	}
+	result := make(%v, len(rawMap))
+	for k, raw := range rawMap {
+		var val %v
+		if err := unmarshal%v(raw, &val); err != nil {
+			return nil, err
+		}
+		result[k] = val
+	}
+	return result, nil
+}
+`, signature, typ, call, typ, union, union)
+		return true, nil
+	}
+
+	fmt.Fprintf(buf, `%v (%v, error) {
+	var result %v
+	err := rpc.Transport.CallResult(rpc.Context, &result, %v)
+	return result, err
+}
+`, signature, typ, typ, call)
+	return false, nil
+}
+
+// unionOf returns the name of the tagged union if typ is prefix followed by a tagged union.
+func (g *generator) unionOf(typ, prefix string) string {
+	name, ok := strings.CutPrefix(typ, prefix)
+	if !ok {
+		return ""
+	}
+	if !g.types.IsUnion(name) {
+		return ""
+	}
+	return name
+}
+
+// writeDoc writes the given text as a comment.
+func writeDoc(buf *bytes.Buffer, indent, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			fmt.Fprintf(buf, "%v//\n", indent)
+		} else {
+			fmt.Fprintf(buf, "%v// %v\n", indent, line)
+		}
+	}
+}
+
+// exportedName converts snake_case and camelCase names to PascalCase.
+func exportedName(name string) string {
+	var result strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+// unexportedName converts snake_case names to camelCase, avoiding Go keywords.
+func unexportedName(name string) string {
+	exported := []rune(exportedName(name))
+	if len(exported) == 0 {
+		return "_"
+	}
+	exported[0] = unicode.ToLower(exported[0])
+	result := string(exported)
+	if token.IsKeyword(result) {
+		result += "_"
+	}
+	return result
+}
+
+func formatSource(src []byte) ([]byte, error) {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, src)
+	}
+	return formatted, nil
+}
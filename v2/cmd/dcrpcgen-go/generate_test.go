@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func generateFixture(t *testing.T) map[string][]byte {
+	schema, err := os.ReadFile(filepath.Join("testdata", "schema.json"))
+	require.Nil(t, err)
+	files, err := generate(schema, "deltachat")
+	require.Nil(t, err)
+	return files
+}
+
+func TestGenerate_Golden(t *testing.T) {
+	t.Parallel()
+	for name, src := range generateFixture(t) {
+		path := filepath.Join("testdata", name+".golden")
+		if *update {
+			require.Nil(t, os.WriteFile(path, src, 0o644))
+			continue
+		}
+		expected, err := os.ReadFile(path)
+		require.Nil(t, err)
+		require.Equal(t, string(expected), string(src), "run go test -update to update %v", path)
+	}
+}
+
+// The fixture schema is a subset of the schema of deltachat-rpc-server, so the generated
+// declarations are compared with the committed bindings line by line: each line of a generated
+// declaration must appear, in order, in the committed declaration of the same name.
+func TestGenerate_Committed(t *testing.T) {
+	t.Parallel()
+	for name, src := range generateFixture(t) {
+		if filepath.Ext(name) != ".go" {
+			continue
+		}
+		committedSrc, err := os.ReadFile(filepath.Join("..", "..", "deltachat", name))
+		require.Nil(t, err)
+		committed := declarations(t, name, committedSrc)
+		for key, decl := range declarations(t, name, src) {
+			if committedDecl, ok := committed[key]; ok {
+				require.Truef(t, isSubsequence(decl, committedDecl), "%v of %v differs from the committed bindings:\n%v\ncommitted:\n%v",
+					key, name, strings.Join(decl, "\n"), strings.Join(committedDecl, "\n"))
+			}
+		}
+	}
+}
+
+// declarations returns the lines of the functions, methods and types of a Go file by name,
+// with the whitespace of the lines normalized.
+func declarations(t *testing.T, name string, src []byte) map[string][]string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	require.Nil(t, err)
+	decls := make(map[string][]string)
+	for _, decl := range file.Decls {
+		var key string
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			key = decl.Name.Name
+			if decl.Recv != nil {
+				key = types.ExprString(decl.Recv.List[0].Type) + "." + key
+			}
+		case *ast.GenDecl:
+			if spec, ok := decl.Specs[0].(*ast.TypeSpec); ok && len(decl.Specs) == 1 {
+				key = "type " + spec.Name.Name
+			}
+		}
+		if key == "" {
+			continue
+		}
+		text := string(src[fset.Position(decl.Pos()).Offset:fset.Position(decl.End()).Offset])
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.Join(strings.Fields(line), " "))
+		}
+		decls[key] = lines
+	}
+	return decls
+}
+
+func isSubsequence(lines, of []string) bool {
+	i := 0
+	for _, line := range of {
+		if i < len(lines) && lines[i] == line {
+			i++
+		}
+	}
+	return i == len(lines)
+}
+
+func TestGenerate_TypeCheck(t *testing.T) {
+	t.Parallel()
+	fset := token.NewFileSet()
+	var files []*ast.File
+	sources := generateFixture(t)
+	// RpcTransport and the types of typeOverrides are not generated
+	sources["transport.go"] = []byte(`package deltachat
+
+import "context"
+
+type RpcTransport interface {
+	Call(ctx context.Context, method string, params ...any) error
+	CallResult(ctx context.Context, result any, method string, params ...any) error
+}
+
+type MsgState uint32
+type Connectivity uint32
+type ChatListFlags uint32
+type ContactFlags uint32
+`)
+	delete(sources, "schema_fingerprint.json")
+	for name, src := range sources {
+		file, err := parser.ParseFile(fset, name, src, 0)
+		require.Nil(t, err)
+		files = append(files, file)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err := conf.Check("deltachat", fset, files, nil)
+	require.Nil(t, err)
+}
+
+func TestGenerate_UnsupportedSchema(t *testing.T) {
+	t.Parallel()
+	_, err := generate([]byte(`{"components": {"schemas": {"Untagged": {"oneOf": [{"type": "object"}]}}}}`), "deltachat")
+	require.ErrorContains(t, err, "Untagged")
+
+	_, err = generate([]byte(`{"methods": [{"name": "foo", "params": [], "result": {"schema": {"type": "array", "items": []}}}]}`), "deltachat")
+	require.ErrorContains(t, err, "foo")
+
+	_, err = generate([]byte(`not json`), "deltachat")
+	require.NotNil(t, err)
+}
+
+func TestExportedName(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "CheckEmailValidity", exportedName("check_email_validity"))
+	require.Equal(t, "ContactId", exportedName("contact_id"))
+	require.Equal(t, "IsV3", exportedName("is_v3"))
+	require.Equal(t, "AcceptInvalidCertificates", exportedName("acceptInvalidCertificates"))
+	require.Equal(t, "TextPlain", exportedName("text/plain"))
+	require.Equal(t, "accountId", unexportedName("account_id"))
+	require.Equal(t, "qrContent", unexportedName("qrContent"))
+	require.Equal(t, "type_", unexportedName("type"))
+}
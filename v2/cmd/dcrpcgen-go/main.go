@@ -0,0 +1,100 @@
+// Command dcrpcgen-go generates the Go bindings of the Delta Chat JSON-RPC API
+// (rpc.go and types.go) from the OpenRPC schema of deltachat-rpc-server, together with
+// the schema_fingerprint.json file used to detect incompatible servers at runtime.
+//
+// Usage:
+//
+//	dcrpcgen-go [-schema schema.json] [-o dir] [-package name]
+//
+// If -schema is not given, the schema is read from the output of
+// "deltachat-rpc-server --openrpc", the path of the server binary can be set with -server.
+//
+// The bindings of the deltachat package are regenerated with:
+//
+//	cd v2/deltachat && go generate
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/chatmail/rpc-client-go/v2/internal/openrpc"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "", "path of the OpenRPC schema, \"-\" for stdin (default: output of deltachat-rpc-server --openrpc)")
+	server := flag.String("server", "deltachat-rpc-server", "deltachat-rpc-server binary used if -schema is not given")
+	outDir := flag.String("o", ".", "output directory")
+	pkg := flag.String("package", "deltachat", "name of the generated package")
+	flag.Parse()
+
+	if err := run(*schemaPath, *server, *outDir, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "dcrpcgen-go:", err)
+		os.Exit(1)
+	}
+}
+
+func run(schemaPath, server, outDir, pkg string) error {
+	data, err := readSchema(schemaPath, server)
+	if err != nil {
+		return err
+	}
+	files, err := generate(data, pkg)
+	if err != nil {
+		return err
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(outDir, name), src, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSchema(path, server string) ([]byte, error) {
+	switch path {
+	case "":
+		data, err := exec.Command(server, "--openrpc").Output()
+		if err != nil {
+			return nil, fmt.Errorf("running %v --openrpc: %w", server, err)
+		}
+		return data, nil
+	case "-":
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// generate returns the generated files by file name for the given OpenRPC schema.
+func generate(schema []byte, pkg string) (map[string][]byte, error) {
+	doc, err := openrpc.Parse(schema)
+	if err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	g, err := newGenerator(doc, pkg)
+	if err != nil {
+		return nil, err
+	}
+	types, err := g.Types()
+	if err != nil {
+		return nil, err
+	}
+	rpc, err := g.Rpc()
+	if err != nil {
+		return nil, err
+	}
+	fp, err := openrpc.NewFingerprint(doc)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := json.MarshalIndent(fp, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{"types.go": types, "rpc.go": rpc, "schema_fingerprint.json": append(fingerprint, '\n')}, nil
+}
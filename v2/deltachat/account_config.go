@@ -0,0 +1,398 @@
+package deltachat
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ConfigKey is the key of an account configuration value.
+type ConfigKey string
+
+const (
+	ConfigAddr                  ConfigKey = "addr"
+	ConfigDisplayname           ConfigKey = "displayname"
+	ConfigSelfstatus            ConfigKey = "selfstatus"
+	ConfigSelfavatar            ConfigKey = "selfavatar"
+	ConfigBot                   ConfigKey = "bot"
+	ConfigMdnsEnabled           ConfigKey = "mdns_enabled"
+	ConfigBccSelf               ConfigKey = "bcc_self"
+	ConfigMvboxMove             ConfigKey = "mvbox_move"
+	ConfigOnlyFetchMvbox        ConfigKey = "only_fetch_mvbox"
+	ConfigSyncMsgs              ConfigKey = "sync_msgs"
+	ConfigWebxdcRealtimeEnabled ConfigKey = "webxdc_realtime_enabled"
+	ConfigShowEmails            ConfigKey = "show_emails"
+	ConfigMediaQuality          ConfigKey = "media_quality"
+	ConfigDownloadLimit         ConfigKey = "download_limit"
+	ConfigDeleteDeviceAfter     ConfigKey = "delete_device_after"
+	ConfigDeleteServerAfter     ConfigKey = "delete_server_after"
+)
+
+type configKind int
+
+const (
+	configString configKind = iota
+	configBool
+	configInt
+	// durations are stored in seconds
+	configDuration
+)
+
+func (kind configKind) String() string {
+	return [...]string{"string", "bool", "int", "duration"}[kind]
+}
+
+// configKinds are the value types of the known keys, unknown keys like "ui.*" are strings.
+var configKinds = map[ConfigKey]configKind{
+	ConfigBot:                   configBool,
+	ConfigMdnsEnabled:           configBool,
+	ConfigBccSelf:               configBool,
+	ConfigMvboxMove:             configBool,
+	ConfigOnlyFetchMvbox:        configBool,
+	ConfigSyncMsgs:              configBool,
+	ConfigWebxdcRealtimeEnabled: configBool,
+	ConfigShowEmails:            configInt,
+	ConfigMediaQuality:          configInt,
+	ConfigDownloadLimit:         configInt,
+	ConfigDeleteDeviceAfter:     configDuration,
+	ConfigDeleteServerAfter:     configDuration,
+}
+
+// ConfigValueErr is returned by AccountConfig if a value does not match the type of its key.
+type ConfigValueErr struct {
+	Key   ConfigKey
+	Value string
+	// Type expected for the key: "string", "bool", "int" or "duration".
+	Type string
+}
+
+func (err *ConfigValueErr) Error() string {
+	return fmt.Sprintf("invalid %v value for config key %q: %q", err.Type, err.Key, err.Value)
+}
+
+// ValidateConfig checks that value is a valid value for the given key, nil values unset the key
+// and are always valid.
+func ValidateConfig(key ConfigKey, value *string) error {
+	if value == nil {
+		return nil
+	}
+	kind := configKinds[key]
+	var err error
+	switch kind {
+	case configBool:
+		if *value != "0" && *value != "1" {
+			err = strconv.ErrSyntax
+		}
+	case configInt:
+		_, err = strconv.ParseInt(*value, 10, 64)
+	case configDuration:
+		_, err = strconv.ParseUint(*value, 10, 63)
+	}
+	if err != nil {
+		return &ConfigValueErr{Key: key, Value: *value, Type: kind.String()}
+	}
+	return nil
+}
+
+// AccountConfig is a typed view of the configuration of an account,
+// built on top of Rpc.GetConfig() and Rpc.SetConfig().
+type AccountConfig struct {
+	Rpc       *Rpc
+	AccountId uint32
+}
+
+// Create a new AccountConfig for the given account.
+func NewAccountConfig(rpc *Rpc, accId uint32) *AccountConfig {
+	return &AccountConfig{Rpc: rpc, AccountId: accId}
+}
+
+// Get returns the raw value of the given key, nil if the key is not set.
+func (config *AccountConfig) Get(key ConfigKey) (*string, error) {
+	return config.Rpc.GetConfig(config.AccountId, string(key))
+}
+
+// Set sets the raw value of the given key after validating it, a nil value unsets the key.
+func (config *AccountConfig) Set(key ConfigKey, value *string) error {
+	if err := ValidateConfig(key, value); err != nil {
+		return err
+	}
+	return config.Rpc.SetConfig(config.AccountId, string(key), value)
+}
+
+// GetString returns the value of a string key, empty if the key is not set.
+func (config *AccountConfig) GetString(key ConfigKey) (string, error) {
+	if err := checkConfigKind(key, configString); err != nil {
+		return "", err
+	}
+	value, err := config.Get(key)
+	if err != nil || value == nil {
+		return "", err
+	}
+	return *value, nil
+}
+
+// SetString sets the value of a string key.
+func (config *AccountConfig) SetString(key ConfigKey, value string) error {
+	if err := checkConfigKind(key, configString); err != nil {
+		return err
+	}
+	return config.Set(key, &value)
+}
+
+// GetBool returns the value of a bool key, false if the key is not set.
+func (config *AccountConfig) GetBool(key ConfigKey) (bool, error) {
+	value, err := config.getKind(key, configBool)
+	return value != 0, err
+}
+
+// SetBool sets the value of a bool key.
+func (config *AccountConfig) SetBool(key ConfigKey, value bool) error {
+	if err := checkConfigKind(key, configBool); err != nil {
+		return err
+	}
+	return config.Set(key, formatConfigBool(value))
+}
+
+// GetInt returns the value of an int key, 0 if the key is not set.
+func (config *AccountConfig) GetInt(key ConfigKey) (int64, error) {
+	return config.getKind(key, configInt)
+}
+
+// SetInt sets the value of an int key.
+func (config *AccountConfig) SetInt(key ConfigKey, value int64) error {
+	if err := checkConfigKind(key, configInt); err != nil {
+		return err
+	}
+	raw := strconv.FormatInt(value, 10)
+	return config.Set(key, &raw)
+}
+
+// GetDuration returns the value of a key stored in seconds, 0 usually means "never".
+func (config *AccountConfig) GetDuration(key ConfigKey) (time.Duration, error) {
+	seconds, err := config.getKind(key, configDuration)
+	return time.Duration(seconds) * time.Second, err
+}
+
+// SetDuration sets the value of a key stored in seconds, the duration is truncated to seconds.
+func (config *AccountConfig) SetDuration(key ConfigKey, value time.Duration) error {
+	if err := checkConfigKind(key, configDuration); err != nil {
+		return err
+	}
+	raw := strconv.FormatInt(int64(value/time.Second), 10)
+	return config.Set(key, &raw)
+}
+
+// getKind returns the numeric value of a bool, int or duration key, 0 if the key is not set.
+func (config *AccountConfig) getKind(key ConfigKey, kind configKind) (int64, error) {
+	if err := checkConfigKind(key, kind); err != nil {
+		return 0, err
+	}
+	value, err := config.Get(key)
+	if err != nil || value == nil || *value == "" {
+		return 0, err
+	}
+	number, err := strconv.ParseInt(*value, 10, 64)
+	if err != nil {
+		return 0, &ConfigValueErr{Key: key, Value: *value, Type: kind.String()}
+	}
+	return number, nil
+}
+
+func checkConfigKind(key ConfigKey, kind configKind) error {
+	if actual := configKinds[key]; actual != kind {
+		return fmt.Errorf("config key %q is a %v, not a %v", key, actual, kind)
+	}
+	return nil
+}
+
+func formatConfigBool(value bool) *string {
+	raw := "0"
+	if value {
+		raw = "1"
+	}
+	return &raw
+}
+
+// Load fills the fields of the struct pointed to by settings that have a `config:"key"` tag
+// with a single Rpc.BatchGetConfig() call. Supported field types are string, bool, integers,
+// time.Duration and pointers to them, matching the type of the key: unknown keys are strings
+// and durations are time.Duration or integers in seconds. Pointers are set to nil if the key
+// is not set, values that overflow the field type are rejected with ConfigValueErr.
+func (config *AccountConfig) Load(settings any) error {
+	if reflect.ValueOf(settings).Kind() != reflect.Pointer {
+		return fmt.Errorf("config settings must be a pointer to a struct, got %T", settings)
+	}
+	fields, err := configFields(settings)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(fields))
+	for _, field := range fields {
+		keys = append(keys, string(field.key))
+	}
+	values, err := config.Rpc.BatchGetConfig(config.AccountId, keys)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if err := field.load(values[string(field.key)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes the fields of settings that have a `config:"key"` tag with a single
+// Rpc.BatchSetConfig() call, nil pointer fields are skipped. See Load() for the supported types.
+func (config *AccountConfig) Save(settings any) error {
+	fields, err := configFields(settings)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]*string, len(fields))
+	for _, field := range fields {
+		value, ok := field.save()
+		if !ok {
+			continue
+		}
+		if err := ValidateConfig(field.key, value); err != nil {
+			return err
+		}
+		values[string(field.key)] = value
+	}
+	return config.Rpc.BatchSetConfig(config.AccountId, values)
+}
+
+type configField struct {
+	key   ConfigKey
+	value reflect.Value
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+func configFields(settings any) ([]configField, error) {
+	value := reflect.ValueOf(settings)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config settings must be a struct or a pointer to a struct, got %T", settings)
+	}
+	var fields []configField
+	for i := range value.NumField() {
+		structField := value.Type().Field(i)
+		key, ok := structField.Tag.Lookup("config")
+		if !ok || key == "" || key == "-" {
+			continue
+		}
+		if !structField.IsExported() {
+			return nil, fmt.Errorf("config field %v is not exported", structField.Name)
+		}
+		field := configField{key: ConfigKey(key), value: value.Field(i)}
+		typ := field.value.Type()
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if kind := configKinds[field.key]; !configKindAccepts(kind, typ) {
+			return nil, fmt.Errorf("config field %v: type %v can not hold the %v value of %q", structField.Name, typ, kind, key)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// configKindAccepts returns true if a field of the given type can hold values of the given kind:
+// strings, bools, integers but time.Duration, and time.Duration or integers for durations in seconds.
+func configKindAccepts(kind configKind, typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.String:
+		return kind == configString
+	case reflect.Bool:
+		return kind == configBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if typ == durationType {
+			return kind == configDuration
+		}
+		return kind == configInt || kind == configDuration
+	}
+	return false
+}
+
+func (field configField) load(raw *string) error {
+	value := field.value
+	if value.Kind() == reflect.Pointer {
+		if raw == nil {
+			value.SetZero()
+			return nil
+		}
+		value.Set(reflect.New(value.Type().Elem()))
+		value = value.Elem()
+	}
+	if raw == nil || *raw == "" && value.Kind() != reflect.String {
+		value.SetZero()
+		return nil
+	}
+	invalid := &ConfigValueErr{Key: field.key, Value: *raw, Type: configKinds[field.key].String()}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(*raw)
+	case reflect.Bool:
+		number, err := strconv.ParseInt(*raw, 10, 64)
+		if err != nil {
+			return invalid
+		}
+		value.SetBool(number != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := strconv.ParseInt(*raw, 10, 64)
+		if err != nil {
+			return invalid
+		}
+		if value.Type() == durationType {
+			if number > math.MaxInt64/int64(time.Second) || number < math.MinInt64/int64(time.Second) {
+				return invalid
+			}
+			number *= int64(time.Second)
+		}
+		if value.OverflowInt(number) {
+			return invalid
+		}
+		value.SetInt(number)
+	default:
+		number, err := strconv.ParseUint(*raw, 10, 64)
+		if err != nil || value.OverflowUint(number) {
+			return invalid
+		}
+		value.SetUint(number)
+	}
+	return nil
+}
+
+// save returns the raw value of the field, false if the field is a nil pointer.
+func (field configField) save() (*string, bool) {
+	value := field.value
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+	}
+	var raw string
+	switch value.Kind() {
+	case reflect.String:
+		raw = value.String()
+	case reflect.Bool:
+		return formatConfigBool(value.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number := value.Int()
+		if value.Type() == durationType {
+			number /= int64(time.Second)
+		}
+		raw = strconv.FormatInt(number, 10)
+	default:
+		raw = strconv.FormatUint(value.Uint(), 10)
+	}
+	return &raw, true
+}
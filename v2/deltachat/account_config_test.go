@@ -0,0 +1,149 @@
+package deltachat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+	require.Nil(t, ValidateConfig(ConfigBot, strptr("1")))
+	require.Nil(t, ValidateConfig(ConfigBot, nil))
+	require.Nil(t, ValidateConfig(ConfigDisplayname, strptr("yes")))
+	require.Nil(t, ValidateConfig("ui.theme", strptr("dark")))
+	require.Nil(t, ValidateConfig(ConfigDeleteDeviceAfter, strptr("3600")))
+
+	err := ValidateConfig(ConfigBot, strptr("yes"))
+	require.NotNil(t, err)
+	valueErr, ok := err.(*ConfigValueErr)
+	require.True(t, ok)
+	require.Equal(t, ConfigBot, valueErr.Key)
+	require.Equal(t, "bool", valueErr.Type)
+	require.NotEmpty(t, err.Error())
+
+	require.NotNil(t, ValidateConfig(ConfigMediaQuality, strptr("high")))
+	require.NotNil(t, ValidateConfig(ConfigDeleteServerAfter, strptr("-1")))
+}
+
+type testSettings struct {
+	Displayname       string        `config:"displayname"`
+	Selfstatus        *string       `config:"selfstatus"`
+	Bot               bool          `config:"bot"`
+	MdnsEnabled       *bool         `config:"mdns_enabled"`
+	MediaQuality      int           `config:"media_quality"`
+	DeleteDeviceAfter time.Duration `config:"delete_device_after"`
+	Theme             string        `config:"ui.theme"`
+	Ignored           string
+}
+
+func TestConfigFields(t *testing.T) {
+	t.Parallel()
+	fields, err := configFields(&testSettings{})
+	require.Nil(t, err)
+	require.Len(t, fields, 7)
+
+	_, err = configFields(42)
+	require.NotNil(t, err)
+	_, err = configFields(struct {
+		Chats []uint32 `config:"chats"`
+	}{})
+	require.NotNil(t, err)
+	_, err = configFields(struct {
+		displayname string `config:"displayname"`
+	}{})
+	require.NotNil(t, err)
+	_, err = configFields(struct {
+		MediaQuality bool `config:"media_quality"`
+	}{})
+	require.ErrorContains(t, err, "MediaQuality")
+	_, err = configFields(struct {
+		ShowEmails time.Duration `config:"show_emails"`
+	}{})
+	require.NotNil(t, err)
+	_, err = configFields(struct {
+		Theme int `config:"ui.theme"`
+	}{})
+	require.NotNil(t, err)
+}
+
+func TestConfigField_LoadOverflow(t *testing.T) {
+	t.Parallel()
+	var settings struct {
+		MediaQuality      int8          `config:"media_quality"`
+		DownloadLimit     uint16        `config:"download_limit"`
+		DeleteServerAfter time.Duration `config:"delete_server_after"`
+	}
+	fields, err := configFields(&settings)
+	require.Nil(t, err)
+
+	require.Nil(t, fields[0].load(strptr("100")))
+	require.Equal(t, int8(100), settings.MediaQuality)
+	var valueErr *ConfigValueErr
+	require.ErrorAs(t, fields[0].load(strptr("300")), &valueErr)
+	require.Equal(t, int8(100), settings.MediaQuality)
+	require.ErrorAs(t, fields[1].load(strptr("70000")), &valueErr)
+	require.ErrorAs(t, fields[2].load(strptr("9223372036854775807")), &valueErr)
+}
+
+func TestAccountConfig(t *testing.T) {
+	t.Parallel()
+	acfactory.WithUnconfiguredAccount(func(rpc *Rpc, accId uint32) {
+		config := NewAccountConfig(rpc, accId)
+		require.Nil(t, config.SetString(ConfigDisplayname, "Alice"))
+		name, err := config.GetString(ConfigDisplayname)
+		require.Nil(t, err)
+		require.Equal(t, "Alice", name)
+
+		require.Nil(t, config.SetBool(ConfigBot, true))
+		isBot, err := config.GetBool(ConfigBot)
+		require.Nil(t, err)
+		require.True(t, isBot)
+
+		require.Nil(t, config.SetDuration(ConfigDeleteDeviceAfter, time.Hour))
+		after, err := config.GetDuration(ConfigDeleteDeviceAfter)
+		require.Nil(t, err)
+		require.Equal(t, time.Hour, after)
+
+		require.Nil(t, config.SetInt(ConfigMediaQuality, 1))
+		quality, err := config.GetInt(ConfigMediaQuality)
+		require.Nil(t, err)
+		require.Equal(t, int64(1), quality)
+
+		require.NotNil(t, config.SetBool(ConfigDisplayname, true))
+		require.NotNil(t, config.SetString(ConfigBot, "yes"))
+		require.NotNil(t, config.Set(ConfigBot, strptr("yes")))
+		_, err = config.GetBool(ConfigDisplayname)
+		require.NotNil(t, err)
+	})
+}
+
+func TestAccountConfig_LoadSave(t *testing.T) {
+	t.Parallel()
+	acfactory.WithUnconfiguredAccount(func(rpc *Rpc, accId uint32) {
+		config := NewAccountConfig(rpc, accId)
+		enabled := false
+		settings := testSettings{
+			Displayname:       "Bob",
+			Bot:               true,
+			MdnsEnabled:       &enabled,
+			MediaQuality:      1,
+			DeleteDeviceAfter: 24 * time.Hour,
+			Theme:             "dark",
+		}
+		require.Nil(t, config.Save(&settings))
+
+		var loaded testSettings
+		require.Nil(t, config.Load(&loaded))
+		require.Equal(t, "Bob", loaded.Displayname)
+		require.True(t, loaded.Bot)
+		require.NotNil(t, loaded.MdnsEnabled)
+		require.False(t, *loaded.MdnsEnabled)
+		require.Equal(t, 1, loaded.MediaQuality)
+		require.Equal(t, 24*time.Hour, loaded.DeleteDeviceAfter)
+		require.Equal(t, "dark", loaded.Theme)
+
+		require.NotNil(t, config.Load(loaded))
+	})
+}
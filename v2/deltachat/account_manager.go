@@ -0,0 +1,358 @@
+package deltachat
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultIoRestartDelay is the minimum delay between two I/O restarts of an account
+// of an AccountManager created with NewAccountManager().
+const DefaultIoRestartDelay = time.Minute
+
+// AccountState is the lifecycle state of an account managed by an AccountManager.
+type AccountState int
+
+const (
+	AccountStateUnconfigured AccountState = iota
+	AccountStateConfiguring
+	AccountStateConfigureFailed
+	// Configured account with I/O stopped.
+	AccountStateConfigured
+	// Configured account with I/O started.
+	AccountStateRunning
+)
+
+func (state AccountState) String() string {
+	switch state {
+	case AccountStateUnconfigured:
+		return "Unconfigured"
+	case AccountStateConfiguring:
+		return "Configuring"
+	case AccountStateConfigureFailed:
+		return "ConfigureFailed"
+	case AccountStateConfigured:
+		return "Configured"
+	case AccountStateRunning:
+		return "Running"
+	}
+	return "AccountState(" + strconv.Itoa(int(state)) + ")"
+}
+
+func (state AccountState) MarshalText() ([]byte, error) {
+	return []byte(state.String()), nil
+}
+
+// AccountStatus is the status of an account managed by an AccountManager.
+type AccountStatus struct {
+	AccountId uint32
+	State     AccountState
+	// Progress of the configuration in permille, from the ConfigureProgress events.
+	ConfigureProgress uint16
+	// Connectivity updated on ConnectivityChanged events.
+	Connectivity Connectivity
+	// Number of I/O restarts of the account after connectivity failures.
+	IoRestarts int
+	// Last configuration or I/O restart error, empty if the last attempt succeeded.
+	Error     string
+	UpdatedAt time.Time
+}
+
+// AccountSource is the configuration of an account provisioned by an AccountManager.
+type AccountSource struct {
+	// dcaccount: or dclogin: QR code, used if not empty.
+	Qr string
+	// Login parameters, used if Qr is empty.
+	Login *EnteredLoginParam
+}
+
+// AccountManager adds, configures and removes accounts at runtime, and tracks their
+// configuration and connectivity.
+//
+// The events of the accounts must be passed to HandleEvent(), see AccountManager.EventHandler().
+// When a running account loses its connectivity, its I/O is restarted, at most once per IoRestartDelay.
+// ConfigureProgress events are only taken into account while AddAccount() configures the account.
+type AccountManager struct {
+	Rpc *Rpc
+	// Minimum delay between two I/O restarts of an account.
+	IoRestartDelay time.Duration
+	mu             sync.Mutex
+	accounts       map[uint32]*managedAccount
+}
+
+type managedAccount struct {
+	status      AccountStatus
+	lastRestart time.Time
+	restarting  bool
+	// configuration started by AddAccount() running
+	configuring bool
+}
+
+// Create a new AccountManager, the existing accounts are tracked after calling Load() or LoadRunning().
+func NewAccountManager(rpc *Rpc) *AccountManager {
+	return &AccountManager{Rpc: rpc, IoRestartDelay: DefaultIoRestartDelay, accounts: make(map[uint32]*managedAccount)}
+}
+
+// Load tracks the existing accounts of the Rpc, their state is Unconfigured or Configured.
+func (manager *AccountManager) Load() error {
+	return manager.load(false)
+}
+
+// LoadRunning tracks the existing accounts of the Rpc like Load(), but the I/O of the
+// configured accounts is assumed to be started, e.g. by Bot.Run() or
+// Rpc.StartIoForAllAccounts(), so their state is Running and their I/O is restarted
+// when they lose their connectivity.
+func (manager *AccountManager) LoadRunning() error {
+	return manager.load(true)
+}
+
+func (manager *AccountManager) load(running bool) error {
+	ids, err := manager.Rpc.GetAllAccountIds()
+	if err != nil {
+		return err
+	}
+	for _, accId := range ids {
+		configured, err := manager.Rpc.IsConfigured(accId)
+		if err != nil {
+			return err
+		}
+		connectivity, err := manager.Rpc.GetConnectivity(accId)
+		if err != nil {
+			return err
+		}
+		manager.update(accId, func(status *AccountStatus) {
+			switch {
+			case configured && running:
+				status.State = AccountStateRunning
+			case configured && status.State != AccountStateRunning:
+				status.State = AccountStateConfigured
+			}
+			status.Connectivity = connectivity
+		})
+	}
+	return nil
+}
+
+// Provision adds and configures an account for each source concurrently, and starts the
+// I/O of the configured accounts. The ids of the accounts are returned in the order of the
+// sources, 0 if the account could not be added, with the errors of the failed accounts.
+// Accounts that failed to configure are kept with the AccountStateConfigureFailed state.
+func (manager *AccountManager) Provision(sources ...AccountSource) ([]uint32, error) {
+	ids := make([]uint32, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids[i], errs[i] = manager.AddAccount(source)
+		}()
+	}
+	wg.Wait()
+	return ids, errors.Join(errs...)
+}
+
+// AddAccount adds an account, configures it from the given source and starts its I/O.
+// The id of the added account is returned even if its configuration failed.
+func (manager *AccountManager) AddAccount(source AccountSource) (uint32, error) {
+	if source.Qr == "" && source.Login == nil {
+		return 0, errors.New("account source without QR code or login parameters")
+	}
+	accId, err := manager.Rpc.AddAccount()
+	if err != nil {
+		return 0, err
+	}
+	manager.update(accId, func(status *AccountStatus) {
+		status.State = AccountStateConfiguring
+	})
+	manager.setConfiguring(accId, true)
+	if source.Qr != "" {
+		err = manager.Rpc.AddTransportFromQr(accId, source.Qr)
+	} else {
+		err = manager.Rpc.AddTransport(accId, *source.Login)
+	}
+	manager.setConfiguring(accId, false)
+	if err != nil {
+		manager.update(accId, func(status *AccountStatus) {
+			status.State = AccountStateConfigureFailed
+			status.Error = err.Error()
+		})
+		return accId, fmt.Errorf("configuring account %v: %w", accId, err)
+	}
+	manager.update(accId, func(status *AccountStatus) {
+		status.State = AccountStateConfigured
+		status.ConfigureProgress = 1000
+		status.Error = ""
+	})
+	return accId, manager.StartIo(accId)
+}
+
+// StartIo starts the I/O of a configured account.
+func (manager *AccountManager) StartIo(accId uint32) error {
+	if err := manager.Rpc.StartIo(accId); err != nil {
+		return err
+	}
+	manager.update(accId, func(status *AccountStatus) {
+		status.State = AccountStateRunning
+	})
+	return nil
+}
+
+// StopIo stops the I/O of an account, its I/O is not restarted until StartIo() is called.
+func (manager *AccountManager) StopIo(accId uint32) error {
+	if err := manager.Rpc.StopIo(accId); err != nil {
+		return err
+	}
+	manager.update(accId, func(status *AccountStatus) {
+		if status.State == AccountStateRunning {
+			status.State = AccountStateConfigured
+		}
+	})
+	return nil
+}
+
+// Retire stops the I/O of an account and removes it.
+func (manager *AccountManager) Retire(accId uint32) error {
+	if err := manager.Rpc.StopIo(accId); err != nil {
+		return err
+	}
+	if err := manager.Rpc.RemoveAccount(accId); err != nil {
+		return err
+	}
+	manager.mu.Lock()
+	delete(manager.accounts, accId)
+	manager.mu.Unlock()
+	return nil
+}
+
+// Status returns the status of an account, false if the account is not tracked.
+func (manager *AccountManager) Status(accId uint32) (AccountStatus, bool) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	account, ok := manager.accounts[accId]
+	if !ok {
+		return AccountStatus{}, false
+	}
+	return account.status, true
+}
+
+// Statuses returns the status of the tracked accounts ordered by account id.
+func (manager *AccountManager) Statuses() []AccountStatus {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	statuses := make([]AccountStatus, 0, len(manager.accounts))
+	for _, accId := range slices.Sorted(maps.Keys(manager.accounts)) {
+		statuses = append(statuses, manager.accounts[accId].status)
+	}
+	return statuses
+}
+
+// HandleEvent updates the status of the account of the given event, and restarts its
+// I/O if a running account lost its connectivity. Events of untracked accounts, and
+// ConfigureProgress events of accounts not being configured by AddAccount(), are ignored.
+func (manager *AccountManager) HandleEvent(accId uint32, event EventType) error {
+	manager.mu.Lock()
+	account, tracked := manager.accounts[accId]
+	configuring := tracked && account.configuring
+	manager.mu.Unlock()
+	if !tracked {
+		return nil
+	}
+
+	switch event := event.(type) {
+	case *EventTypeConfigureProgress:
+		// late events must not change the state after AddAccount() returned
+		if !configuring {
+			return nil
+		}
+		manager.update(accId, func(status *AccountStatus) {
+			status.ConfigureProgress = event.Progress
+			if event.Progress == 0 {
+				status.State = AccountStateConfigureFailed
+				if event.Comment != nil {
+					status.Error = *event.Comment
+				}
+			} else if status.State == AccountStateUnconfigured || status.State == AccountStateConfigureFailed {
+				status.State = AccountStateConfiguring
+			}
+		})
+	case *EventTypeConnectivityChanged:
+		connectivity, err := manager.Rpc.GetConnectivity(accId)
+		if err != nil {
+			return err
+		}
+		restart := false
+		manager.update(accId, func(status *AccountStatus) {
+			status.Connectivity = connectivity
+		})
+		manager.mu.Lock()
+		if account, ok := manager.accounts[accId]; ok {
+			restart = account.status.State == AccountStateRunning && connectivity.IsNotConnected() &&
+				!account.restarting && time.Since(account.lastRestart) >= manager.IoRestartDelay
+			if restart {
+				account.restarting = true
+				account.lastRestart = time.Now()
+			}
+		}
+		manager.mu.Unlock()
+		if restart {
+			return manager.restartIo(accId)
+		}
+	}
+	return nil
+}
+
+// EventHandler returns an EventHandler tracking the accounts, add it with Bot.Observe().
+// Errors are ignored, the status is updated by the next event.
+func (manager *AccountManager) EventHandler() EventHandler {
+	return func(_ *Bot, accId uint32, event EventType) {
+		manager.HandleEvent(accId, event) //nolint:errcheck
+	}
+}
+
+func (manager *AccountManager) restartIo(accId uint32) error {
+	err := manager.Rpc.StopIo(accId)
+	if err == nil {
+		err = manager.Rpc.StartIo(accId)
+	}
+	manager.update(accId, func(status *AccountStatus) {
+		status.IoRestarts++
+		if err != nil {
+			status.Error = fmt.Sprintf("restarting I/O: %v", err)
+		} else {
+			status.Error = ""
+		}
+	})
+	manager.mu.Lock()
+	if account, ok := manager.accounts[accId]; ok {
+		account.restarting = false
+	}
+	manager.mu.Unlock()
+	return err
+}
+
+// setConfiguring marks whether AddAccount() is configuring a tracked account.
+func (manager *AccountManager) setConfiguring(accId uint32, configuring bool) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if account, ok := manager.accounts[accId]; ok {
+		account.configuring = configuring
+	}
+}
+
+// update applies the given change to the status of an account, tracking the account if needed.
+func (manager *AccountManager) update(accId uint32, change func(status *AccountStatus)) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	account, ok := manager.accounts[accId]
+	if !ok {
+		account = &managedAccount{status: AccountStatus{AccountId: accId}}
+		manager.accounts[accId] = account
+	}
+	change(&account.status)
+	account.status.UpdatedAt = time.Now()
+}
@@ -0,0 +1,141 @@
+package deltachat
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountManager_HandleEvent(t *testing.T) {
+	t.Parallel()
+	manager := NewAccountManager(nil)
+	// untracked accounts are ignored
+	require.Nil(t, manager.HandleEvent(1, &EventTypeConfigureProgress{Progress: 100}))
+	_, ok := manager.Status(1)
+	require.False(t, ok)
+
+	manager.update(1, func(status *AccountStatus) {})
+	manager.update(2, func(status *AccountStatus) { status.State = AccountStateRunning })
+	// progress is ignored while the account is not configured by AddAccount()
+	require.Nil(t, manager.HandleEvent(1, &EventTypeConfigureProgress{Progress: 100}))
+	status, ok := manager.Status(1)
+	require.True(t, ok)
+	require.Equal(t, AccountStateUnconfigured, status.State)
+	require.Zero(t, status.ConfigureProgress)
+
+	manager.setConfiguring(1, true)
+	require.Nil(t, manager.HandleEvent(1, &EventTypeConfigureProgress{Progress: 100}))
+	status, _ = manager.Status(1)
+	require.Equal(t, AccountStateConfiguring, status.State)
+	require.Equal(t, uint16(100), status.ConfigureProgress)
+
+	comment := "wrong password"
+	require.Nil(t, manager.HandleEvent(1, &EventTypeConfigureProgress{Progress: 0, Comment: &comment}))
+	status, _ = manager.Status(1)
+	require.Equal(t, AccountStateConfigureFailed, status.State)
+	require.Equal(t, "wrong password", status.Error)
+
+	// a late event after the configuration failed doesn't change the state
+	manager.setConfiguring(1, false)
+	require.Nil(t, manager.HandleEvent(1, &EventTypeConfigureProgress{Progress: 200}))
+	status, _ = manager.Status(1)
+	require.Equal(t, AccountStateConfigureFailed, status.State)
+
+	statuses := manager.Statuses()
+	require.Len(t, statuses, 2)
+	require.Equal(t, uint32(1), statuses[0].AccountId)
+	require.Equal(t, "Running", statuses[1].State.String())
+	require.Equal(t, "AccountState(9)", AccountState(9).String())
+}
+
+// ioTransport is a listTransport recording the calls without result, e.g. stop_io and start_io.
+type ioTransport struct {
+	listTransport
+	mu    sync.Mutex
+	calls []string
+}
+
+func (trans *ioTransport) Call(ctx context.Context, method string, params ...any) error {
+	trans.mu.Lock()
+	trans.calls = append(trans.calls, method)
+	trans.mu.Unlock()
+	return nil
+}
+
+func TestAccountManager_LoadRunning(t *testing.T) {
+	t.Parallel()
+	trans := &ioTransport{listTransport: listTransport{results: func(method string, params []any) any {
+		switch method {
+		case "get_all_account_ids":
+			return []uint32{1, 2}
+		case "is_configured":
+			return params[0].(uint32) == 1
+		}
+		return ConnectivityNotConnected
+	}}}
+	manager := NewAccountManager(&Rpc{Context: context.Background(), Transport: trans})
+	// the I/O of the accounts was started by Bot.Run()
+	require.Nil(t, manager.LoadRunning())
+	status, _ := manager.Status(1)
+	require.Equal(t, AccountStateRunning, status.State)
+	status, _ = manager.Status(2)
+	require.Equal(t, AccountStateUnconfigured, status.State)
+
+	require.Nil(t, manager.HandleEvent(1, &EventTypeConnectivityChanged{}))
+	require.Nil(t, manager.HandleEvent(2, &EventTypeConnectivityChanged{}))
+	status, _ = manager.Status(1)
+	require.Equal(t, 1, status.IoRestarts)
+	status, _ = manager.Status(2)
+	require.Zero(t, status.IoRestarts)
+	require.Equal(t, []string{"stop_io", "start_io"}, trans.calls)
+
+	// restarts are limited by IoRestartDelay
+	require.Nil(t, manager.HandleEvent(1, &EventTypeConnectivityChanged{}))
+	status, _ = manager.Status(1)
+	require.Equal(t, 1, status.IoRestarts)
+}
+
+func TestAccountManager(t *testing.T) {
+	t.Parallel()
+	acfactory.WithRpc(func(rpc *Rpc) {
+		manager := NewAccountManager(rpc)
+		manager.IoRestartDelay = 0
+		ids, err := manager.Provision(acfactory.accountSource(), AccountSource{Qr: "dcaccount:invalid.invalid"})
+		require.NotNil(t, err)
+		require.Len(t, ids, 2)
+
+		status, ok := manager.Status(ids[0])
+		require.True(t, ok)
+		require.Equal(t, AccountStateRunning, status.State)
+		require.Equal(t, uint16(1000), status.ConfigureProgress)
+		status, ok = manager.Status(ids[1])
+		require.True(t, ok)
+		require.Equal(t, AccountStateConfigureFailed, status.State)
+		require.NotEmpty(t, status.Error)
+
+		// I/O of running accounts is restarted when they are not connected
+		require.Nil(t, rpc.StopIo(ids[0]))
+		require.Nil(t, manager.HandleEvent(ids[0], &EventTypeConnectivityChanged{}))
+		status, _ = manager.Status(ids[0])
+		require.Equal(t, 1, status.IoRestarts)
+
+		// the state is loaded by a new manager
+		loaded := NewAccountManager(rpc)
+		require.Nil(t, loaded.Load())
+		status, ok = loaded.Status(ids[0])
+		require.True(t, ok)
+		require.Equal(t, AccountStateConfigured, status.State)
+		require.Nil(t, loaded.LoadRunning())
+		status, _ = loaded.Status(ids[0])
+		require.Equal(t, AccountStateRunning, status.State)
+
+		require.Nil(t, manager.Retire(ids[1]))
+		_, ok = manager.Status(ids[1])
+		require.False(t, ok)
+		accIds, err := rpc.GetAllAccountIds()
+		require.Nil(t, err)
+		require.NotContains(t, accIds, ids[1])
+	})
+}
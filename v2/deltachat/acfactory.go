@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat/mailserver"
 )
 
 // AcFactory facilitates unit testing Delta Chat clients/bots.
@@ -29,11 +31,25 @@ import (
 //	}
 type AcFactory struct {
 	// ConfigQr is the DCACCOUNT: URI used to create new accounts
-	ConfigQr  string
-	Debug     bool
-	tempDir   string
-	startTime int64
-	tearUp    bool
+	ConfigQr string
+	// If LocalServer is true, TearUp() starts an embedded mail server on localhost
+	// and new accounts are created in it instead of using ConfigQr,
+	// this allows to run the tests without internet access.
+	LocalServer bool
+	// PoolSize is the number of configured accounts to keep ready in the background,
+	// WithOnlineAccount() and WithOnlineBot() then import one of them from a backup
+	// instead of configuring a new account. If zero, accounts are configured on demand.
+	PoolSize int
+	// GoldenDir is the directory of the golden files used by AssertGolden(), defaults to "testdata".
+	GoldenDir string
+	// If UpdateGolden is true, AssertGolden() updates the golden files instead of comparing them.
+	UpdateGolden bool
+	Debug        bool
+	mailServer   *mailserver.Server
+	pool         *accountPool
+	tempDir      string
+	startTime    int64
+	tearUp       bool
 }
 
 // Prepare the AcFactory.
@@ -49,13 +65,33 @@ func (factory *AcFactory) TearUp() {
 	}
 	factory.tempDir = dir
 
+	if factory.LocalServer {
+		factory.mailServer = mailserver.NewServer()
+		if factory.Debug {
+			factory.mailServer.Log = os.Stderr
+		}
+		if err := factory.mailServer.Start(); err != nil {
+			panic(err)
+		}
+	}
+
 	factory.tearUp = true
+
+	if factory.PoolSize > 0 {
+		factory.pool = newAccountPool(factory, factory.PoolSize)
+	}
 }
 
 // Do cleanup, removing temporary directories and files created by the configured test accounts.
 // Usually TearDown() is called with defer immediately after the creation of the AcFactory instance.
 func (factory *AcFactory) TearDown() {
 	factory.ensureTearUp()
+	if factory.pool != nil {
+		factory.pool.close()
+	}
+	if factory.mailServer != nil {
+		factory.mailServer.Close()
+	}
 	if err := os.RemoveAll(factory.tempDir); err != nil {
 		panic(err)
 	}
@@ -102,9 +138,7 @@ func (factory *AcFactory) WithUnconfiguredAccount(callback func(*Rpc, uint32)) {
 // Get a new account configured and with I/O already started.
 func (factory *AcFactory) WithOnlineAccount(callback func(*Rpc, uint32)) {
 	factory.WithUnconfiguredAccount(func(rpc *Rpc, accId uint32) {
-		if err := rpc.AddTransportFromQr(accId, factory.ConfigQr); err != nil {
-			panic(err)
-		}
+		factory.configure(rpc, accId)
 		callback(rpc, accId)
 	})
 }
@@ -135,9 +169,7 @@ func (factory *AcFactory) WithUnconfiguredBot(callback func(*Bot, uint32)) {
 // Get a new bot configured and with its account I/O already started. The bot is not running yet.
 func (factory *AcFactory) WithOnlineBot(callback func(*Bot, uint32)) {
 	factory.WithUnconfiguredBot(func(bot *Bot, accId uint32) {
-		if err := bot.Rpc.AddTransportFromQr(accId, factory.ConfigQr); err != nil {
-			panic(err)
-		}
+		factory.configure(bot.Rpc, accId)
 		callback(bot, accId)
 	})
 }
@@ -352,6 +384,60 @@ func (factory *AcFactory) WaitForEvent(rpc *Rpc, accId uint32, event EventType)
 	}
 }
 
+// MailServer returns the embedded mail server used when LocalServer is true, or nil otherwise.
+func (factory *AcFactory) MailServer() *mailserver.Server {
+	factory.ensureTearUp()
+	return factory.mailServer
+}
+
+// Configure the given account importing it from the account pool if PoolSize is set,
+// or adding a new transport otherwise.
+func (factory *AcFactory) configure(rpc *Rpc, accId uint32) {
+	var err error
+	if factory.pool != nil {
+		err = factory.pool.importAccount(rpc, accId)
+	} else {
+		err = factory.addTransport(rpc, accId)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Add a transport to the given account, see AcFactory.accountSource().
+func (factory *AcFactory) addTransport(rpc *Rpc, accId uint32) error {
+	source := factory.accountSource()
+	if source.Login == nil {
+		return rpc.AddTransportFromQr(accId, source.Qr)
+	}
+	return rpc.AddTransport(accId, *source.Login)
+}
+
+// Return the configuration of a new account in the embedded mail server if LocalServer is true,
+// or using ConfigQr otherwise.
+func (factory *AcFactory) accountSource() AccountSource {
+	if factory.mailServer == nil {
+		return AccountSource{Qr: factory.ConfigQr}
+	}
+
+	addr, password := factory.mailServer.NewUser()
+	host := factory.mailServer.Host
+	imapPort, smtpPort := factory.mailServer.ImapPort(), factory.mailServer.SmtpPort()
+	socket := SocketPlain
+	return AccountSource{Login: &EnteredLoginParam{
+		Addr:         addr,
+		Password:     password,
+		ImapServer:   &host,
+		ImapPort:     &imapPort,
+		ImapSecurity: &socket,
+		ImapUser:     &addr,
+		SmtpServer:   &host,
+		SmtpPort:     &smtpPort,
+		SmtpSecurity: &socket,
+		SmtpUser:     &addr,
+	}}
+}
+
 func (factory *AcFactory) ensureTearUp() {
 	if !factory.tearUp {
 		panic("TearUp() required")
@@ -0,0 +1,270 @@
+package deltachat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GoldenT is the subset of testing.TB used by the AcFactory assertions.
+type GoldenT interface {
+	Helper()
+	Errorf(format string, args ...any)
+	FailNow()
+}
+
+// Snapshot fields with values that change on every test run.
+var (
+	snapshotTimestampFields = map[string]bool{
+		"timestamp": true, "sortTimestamp": true, "receivedTimestamp": true, "lastSeen": true,
+		"lastUpdated": true, "ephemeralTimestamp": true,
+	}
+	snapshotColorFields = map[string]bool{
+		"color": true, "authorDisplayColor": true, "authorColor": true, "chatColor": true,
+	}
+	snapshotBlobFields = map[string]bool{
+		"file": true, "profileImage": true, "avatarPath": true, "image": true, "summaryPreviewImage": true,
+		"chatProfileImage": true, "authorProfileImage": true,
+	}
+	snapshotIdFields = map[string]string{
+		"chatId": "chat", "msgId": "msg", "messageId": "msg", "parentId": "msg", "originalMsgId": "msg",
+		"savedMessageId": "msg", "lastMessageId": "msg", "fromId": "contact", "contactId": "contact",
+		"infoContactId": "contact", "verifierId": "contact", "dmChatContact": "contact", "authorId": "contact",
+		"contactIds": "contact", "pastContactIds": "contact",
+	}
+	snapshotAddrRegexp = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]+`)
+)
+
+// Snapshot returns the indented JSON representation of the given value with the fields that
+// change on every test run normalized: IDs are replaced with stable placeholders like "msg-1"
+// (special contact IDs are kept), timestamps, colors and blob paths are masked and email
+// addresses are replaced with placeholders like "addr-1".
+func (factory *AcFactory) Snapshot(value any) []byte {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		panic(err)
+	}
+	normalizer := &snapshotNormalizer{ids: make(map[string]map[string]int), addrs: make(map[string]int)}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(normalizer.normalize(tree, "")); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// ChatTranscript returns the messages of the given chat in the order returned by
+// Rpc.GetMessageListItems(). Messages that failed to load are included as
+// MessageLoadResultLoadingError.
+func (factory *AcFactory) ChatTranscript(rpc *Rpc, accId uint32, chatId uint32) []MessageLoadResult {
+	items, err := rpc.GetMessageListItems(accId, chatId, false, false)
+	if err != nil {
+		panic(err)
+	}
+	var ids []uint32
+	for _, item := range items {
+		if msgItem, ok := item.(*MessageListItemMessage); ok {
+			ids = append(ids, msgItem.MsgId)
+		}
+	}
+	if len(ids) == 0 {
+		return []MessageLoadResult{}
+	}
+	msgs, err := rpc.GetMessages(accId, ids)
+	if err != nil {
+		panic(err)
+	}
+	transcript := make([]MessageLoadResult, len(ids))
+	for i, id := range ids {
+		transcript[i] = msgs[strconv.FormatUint(uint64(id), 10)]
+	}
+	return transcript
+}
+
+// AssertGolden compares the Snapshot() of the given value with the golden file
+// GoldenDir/<name>.json (GoldenDir defaults to "testdata").
+//
+// If UpdateGolden is true, the golden file is written instead. Test packages usually set it
+// from an -update flag in TestMain() so that the golden files are updated with go test -update:
+//
+//	var update = flag.Bool("update", false, "update the golden files")
+//
+//	func TestMain(m *testing.M) {
+//		flag.Parse()
+//		acfactory = &deltachat.AcFactory{UpdateGolden: *update}
+//		...
+//	}
+func (factory *AcFactory) AssertGolden(t GoldenT, name string, value any) {
+	t.Helper()
+	factory.ensureTearUp()
+	actual := factory.Snapshot(value)
+	dir := factory.GoldenDir
+	if dir == "" {
+		dir = "testdata"
+	}
+	path := filepath.Join(dir, name+".json")
+
+	if factory.UpdateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Errorf("creating golden file directory: %v", err)
+			t.FailNow()
+			return
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Errorf("writing golden file: %v", err)
+			t.FailNow()
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("reading golden file (run with -update to create it): %v", err)
+		t.FailNow()
+		return
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("snapshot doesn't match golden file %v (run with -update to update it):\n%v", path, lineDiff(string(expected), string(actual)))
+		t.FailNow()
+	}
+}
+
+// AssertChatGolden compares the ChatTranscript() of the given chat with the golden file
+// GoldenDir/<name>.json, see AssertGolden().
+func (factory *AcFactory) AssertChatGolden(t GoldenT, rpc *Rpc, accId uint32, chatId uint32, name string) {
+	t.Helper()
+	factory.AssertGolden(t, name, factory.ChatTranscript(rpc, accId, chatId))
+}
+
+type snapshotNormalizer struct {
+	ids   map[string]map[string]int
+	addrs map[string]int
+}
+
+// normalize the given JSON tree, key is the name of the field holding the value.
+func (n *snapshotNormalizer) normalize(value any, key string) any {
+	switch value := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		// keys are sorted so the placeholders are assigned in a stable order
+		for _, k := range slices.Sorted(maps.Keys(value)) {
+			v := value[k]
+			switch {
+			case v == nil:
+				result[k] = nil
+			case k == "id":
+				result[k] = n.id(snapshotIdClass(value, key), v)
+			case snapshotIdFields[k] != "":
+				result[k] = n.normalize(v, k)
+			case snapshotTimestampFields[k]:
+				if num, ok := v.(float64); ok && num != 0 {
+					v = "<timestamp>"
+				}
+				result[k] = v
+			case snapshotColorFields[k]:
+				result[k] = "<color>"
+			case snapshotBlobFields[k]:
+				if path, ok := v.(string); ok && path != "" {
+					v = "<blob>" + filepath.Ext(path)
+				}
+				result[k] = v
+			case k == "reactionsByContact":
+				reactionsByContact, _ := v.(map[string]any)
+				byContact := make(map[string]any, len(reactionsByContact))
+				for _, contactId := range slices.Sorted(maps.Keys(reactionsByContact)) {
+					byContact[fmt.Sprint(n.id("contact", contactId))] = reactionsByContact[contactId]
+				}
+				result[k] = byContact
+			default:
+				result[k] = n.normalize(v, k)
+			}
+		}
+		return result
+	case []any:
+		result := make([]any, len(value))
+		for i, v := range value {
+			result[i] = n.normalize(v, key)
+		}
+		return result
+	case float64:
+		if class := snapshotIdFields[key]; class != "" {
+			return n.id(class, value)
+		}
+		return value
+	case string:
+		return snapshotAddrRegexp.ReplaceAllStringFunc(value, func(addr string) string {
+			addr = strings.ToLower(addr)
+			if _, ok := n.addrs[addr]; !ok {
+				n.addrs[addr] = len(n.addrs) + 1
+			}
+			return fmt.Sprintf("addr-%d", n.addrs[addr])
+		})
+	}
+	return value
+}
+
+// id returns a stable placeholder for the given ID, special contact IDs are kept as they are.
+func (n *snapshotNormalizer) id(class string, value any) any {
+	raw := fmt.Sprint(value)
+	if class == "contact" {
+		if num, err := strconv.ParseUint(raw, 10, 32); err == nil && uint32(num) <= ContactLastSpecial {
+			return value
+		}
+	}
+	if n.ids[class] == nil {
+		n.ids[class] = make(map[string]int)
+	}
+	if _, ok := n.ids[class][raw]; !ok {
+		n.ids[class][raw] = len(n.ids[class]) + 1
+	}
+	return fmt.Sprintf("%v-%d", class, n.ids[class][raw])
+}
+
+// snapshotIdClass guesses what kind of object has the given "id" field.
+func snapshotIdClass(obj map[string]any, key string) string {
+	_, hasFromId := obj["fromId"]
+	_, hasChatType := obj["chatType"]
+	_, hasAddress := obj["address"]
+	switch {
+	case hasFromId:
+		return "msg"
+	case hasChatType:
+		return "chat"
+	case hasAddress || key == "sender":
+		return "contact"
+	}
+	return "id"
+}
+
+// lineDiff returns a simple line-based diff between two texts.
+func lineDiff(expected, actual string) string {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	var diff strings.Builder
+	for i := 0; i < max(len(expectedLines), len(actualLines)); i++ {
+		var exp, act string
+		if i < len(expectedLines) {
+			exp = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			act = actualLines[i]
+		}
+		if exp != act {
+			fmt.Fprintf(&diff, "line %d:\n- %v\n+ %v\n", i+1, exp, act)
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,103 @@
+package deltachat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeGoldenT struct {
+	errors []string
+}
+
+func (t *fakeGoldenT) Helper() {}
+func (t *fakeGoldenT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+func (t *fakeGoldenT) FailNow() {}
+
+func TestAcFactory_Snapshot(t *testing.T) {
+	t.Parallel()
+	file := "/tmp/accounts/blobs/image-1f3a.png"
+	msg := Message{
+		Id:        42,
+		ChatId:    12,
+		FromId:    ContactSelf,
+		Text:      "hello alice@example.org",
+		Timestamp: 1700000000,
+		File:      &file,
+		Sender:    Contact{Id: 15, Address: "Alice@example.org", Color: "#ff0000"},
+		Reactions: &Reactions{ReactionsByContact: map[string][]string{"15": {"👍"}, "1": {"👍"}}},
+	}
+	msg2 := msg
+	msg2.Id = 57
+	msg2.ChatId = 99
+	msg2.Timestamp = 1800000000
+	msg2.Sender.Id = 20
+	msg2.Sender.Address = "alice@example.org"
+	msg2.Reactions = &Reactions{ReactionsByContact: map[string][]string{"20": {"👍"}, "1": {"👍"}}}
+
+	snapshot := string(acfactory.Snapshot(msg))
+	require.Equal(t, snapshot, string(acfactory.Snapshot(msg2)))
+	require.Contains(t, snapshot, `"id": "msg-1"`)
+	require.Contains(t, snapshot, `"chatId": "chat-1"`)
+	require.Contains(t, snapshot, `"fromId": 1`)
+	require.Contains(t, snapshot, `"id": "contact-1"`)
+	require.Contains(t, snapshot, `"contact-1": [`)
+	require.Contains(t, snapshot, `"timestamp": "<timestamp>"`)
+	require.Contains(t, snapshot, `"file": "<blob>.png"`)
+	require.Contains(t, snapshot, `"color": "<color>"`)
+	require.Contains(t, snapshot, `"text": "hello addr-1"`)
+	require.Contains(t, snapshot, `"address": "addr-1"`)
+	require.NotContains(t, snapshot, "example.org")
+}
+
+func TestAcFactory_AssertGolden(t *testing.T) {
+	t.Parallel()
+	acf := &AcFactory{GoldenDir: t.TempDir()}
+	acf.TearUp()
+	defer acf.TearDown()
+
+	fakeT := &fakeGoldenT{}
+	acf.AssertGolden(fakeT, "chat", FullChat{Id: 10, Name: "test"})
+	require.Len(t, fakeT.errors, 1) // golden file doesn't exist yet
+
+	acf.UpdateGolden = true
+	fakeT = &fakeGoldenT{}
+	acf.AssertGolden(fakeT, "chat", FullChat{Id: 10, Name: "test"})
+	require.Empty(t, fakeT.errors)
+	_, err := os.Stat(filepath.Join(acf.GoldenDir, "chat.json"))
+	require.Nil(t, err)
+
+	acf.UpdateGolden = false
+	acf.AssertGolden(fakeT, "chat", FullChat{Id: 11, Name: "test"})
+	require.Empty(t, fakeT.errors)
+	acf.AssertGolden(fakeT, "chat", FullChat{Id: 10, Name: "changed"})
+	require.Len(t, fakeT.errors, 1)
+	require.Contains(t, fakeT.errors[0], `+   "name": "changed"`)
+}
+
+func TestAcFactory_AssertChatGolden(t *testing.T) {
+	t.Parallel()
+	acf := &AcFactory{GoldenDir: t.TempDir(), LocalServer: acfactory.LocalServer}
+	acf.TearUp()
+	defer acf.TearDown()
+
+	acf.WithOnlineAccount(func(rpc1 *Rpc, accId1 uint32) {
+		acf.WithOnlineAccount(func(rpc2 *Rpc, accId2 uint32) {
+			chatId := acf.CreateChat(rpc1, accId1, rpc2, accId2)
+			_, err := rpc1.MiscSendTextMessage(accId1, chatId, "hello")
+			require.Nil(t, err)
+			msg := acf.NextMsg(rpc2, accId2)
+			require.Len(t, acf.ChatTranscript(rpc2, accId2, msg.ChatId), 1)
+
+			acf.UpdateGolden = true
+			acf.AssertChatGolden(t, rpc2, accId2, msg.ChatId, "transcript")
+			acf.UpdateGolden = false
+			acf.AssertChatGolden(t, rpc2, accId2, msg.ChatId, "transcript")
+		})
+	})
+}
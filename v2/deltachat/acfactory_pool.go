@@ -0,0 +1,115 @@
+package deltachat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// accountPool keeps backups of configured accounts ready to be imported by AcFactory.
+type accountPool struct {
+	factory *AcFactory
+	backups chan poolBackup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type poolBackup struct {
+	path string
+	err  error
+}
+
+// Start size workers, each of them keeps one configured account backup ready.
+func newAccountPool(factory *AcFactory, size int) *accountPool {
+	pool := &accountPool{factory: factory, backups: make(chan poolBackup)}
+	pool.ctx, pool.cancel = context.WithCancel(context.Background())
+	for range size {
+		pool.wg.Add(1)
+		go pool.worker()
+	}
+	return pool
+}
+
+func (pool *accountPool) worker() {
+	defer pool.wg.Done()
+	for pool.ctx.Err() == nil {
+		path, err := pool.newBackup()
+		select {
+		case pool.backups <- poolBackup{path: path, err: err}:
+		case <-pool.ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop the workers and wait for them to finish.
+func (pool *accountPool) close() {
+	pool.cancel()
+	pool.wg.Wait()
+}
+
+// Import the next available account backup into the given unconfigured account and start I/O.
+func (pool *accountPool) importAccount(rpc *Rpc, accId uint32) error {
+	backup := <-pool.backups
+	if backup.err != nil {
+		return backup.err
+	}
+	defer os.RemoveAll(filepath.Dir(backup.path)) //nolint:errcheck
+
+	// importing the backup replaces the account's configuration, keep the bot flag
+	botFlag, err := rpc.GetConfig(accId, "bot")
+	if err != nil {
+		return err
+	}
+	if err := rpc.ImportBackup(accId, backup.path, nil); err != nil {
+		return err
+	}
+	if botFlag != nil {
+		if err := rpc.SetConfig(accId, "bot", botFlag); err != nil {
+			return err
+		}
+	}
+	return rpc.StartIo(accId)
+}
+
+// Configure a new account in a separate RPC server and export it to a backup file.
+func (pool *accountPool) newBackup() (string, error) {
+	trans := NewIOTransport()
+	if !pool.factory.Debug {
+		trans.Stderr = nil
+	}
+	dir := pool.factory.MkdirTemp()
+	trans.AccountsDir = filepath.Join(dir, "accounts")
+	defer os.RemoveAll(trans.AccountsDir) //nolint:errcheck
+	if err := trans.Open(); err != nil {
+		return "", err
+	}
+	defer trans.Close()
+
+	rpc := &Rpc{Context: pool.ctx, Transport: trans}
+	accId, err := rpc.AddAccount()
+	if err != nil {
+		return "", err
+	}
+	if err := pool.factory.addTransport(rpc, accId); err != nil {
+		return "", err
+	}
+	backupDir := filepath.Join(dir, "backup")
+	if err := os.Mkdir(backupDir, 0o700); err != nil {
+		return "", err
+	}
+	if err := rpc.ExportBackup(accId, backupDir, nil); err != nil {
+		return "", err
+	}
+	files, err := filepath.Glob(filepath.Join(backupDir, "*.tar"))
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no backup file found in %v", backupDir)
+	}
+	return files[0], nil
+}
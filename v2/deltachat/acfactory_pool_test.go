@@ -0,0 +1,36 @@
+package deltachat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcFactory_PoolSize(t *testing.T) {
+	t.Parallel()
+	acf := &AcFactory{PoolSize: 2, LocalServer: acfactory.LocalServer, Debug: acfactory.Debug}
+	acf.TearUp()
+	defer acf.TearDown()
+
+	acf.WithOnlineAccount(func(rpc1 *Rpc, accId1 uint32) {
+		configured, err := rpc1.IsConfigured(accId1)
+		require.Nil(t, err)
+		require.True(t, configured)
+		addr1, err := rpc1.GetConfig(accId1, "addr")
+		require.Nil(t, err)
+
+		acf.WithOnlineBot(func(bot *Bot, accId2 uint32) {
+			addr2, err := bot.Rpc.GetConfig(accId2, "addr")
+			require.Nil(t, err)
+			require.NotEqual(t, *addr1, *addr2)
+			botFlag, err := bot.Rpc.GetConfig(accId2, "bot")
+			require.Nil(t, err)
+			require.Equal(t, "1", *botFlag)
+
+			chatId := acf.CreateChat(rpc1, accId1, bot.Rpc, accId2)
+			_, err = rpc1.MiscSendTextMessage(accId1, chatId, "hello")
+			require.Nil(t, err)
+			require.Equal(t, "hello", acf.NextMsg(bot.Rpc, accId2).Text)
+		})
+	})
+}
@@ -0,0 +1,448 @@
+package deltachat
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default time to wait for the expectations of a Scenario.
+const defaultScenarioTimeout = 2 * time.Minute
+
+// Scenario is a scripted conversation between several test accounts, see AcFactory.RunScenario().
+//
+// Scenarios can be built with the chainable methods:
+//
+//	scenario := deltachat.NewScenario().
+//		CreateGroup("alice", "G", "bob", "carol").
+//		Say("bob", "G", "hello").
+//		ExpectReceived("carol", "G", "hello")
+//
+// or parsed from a script with ParseScenario().
+type Scenario struct {
+	// Timeout is the maximum time to wait for each step, defaults to 2 minutes.
+	Timeout time.Duration
+	steps   []scenarioStep
+	chat    string
+}
+
+// ScenarioAccount is an account provisioned for one of the participants of a Scenario.
+type ScenarioAccount struct {
+	Name  string
+	Rpc   *Rpc
+	AccId uint32
+	// Chats maps the names of the scenario groups the account is a member of to their chat IDs.
+	Chats map[string]uint32
+}
+
+type scenarioAction int
+
+const (
+	scenarioCreateGroup scenarioAction = iota
+	scenarioSay
+	scenarioExpectReceived
+	scenarioReact
+	scenarioExpectReaction
+)
+
+type scenarioStep struct {
+	action   scenarioAction
+	actor    string
+	chat     string
+	text     string
+	reaction string
+	members  []string
+}
+
+// String returns the step in the syntax accepted by ParseScenario().
+func (step scenarioStep) String() string {
+	switch step.action {
+	case scenarioCreateGroup:
+		return fmt.Sprintf("%v creates group %v with %v", step.actor, step.chat, strings.Join(step.members, ", "))
+	case scenarioSay:
+		return fmt.Sprintf("%v says %q in %v", step.actor, step.text, step.chat)
+	case scenarioExpectReceived:
+		return fmt.Sprintf("expect %v receives %q in %v", step.actor, step.text, step.chat)
+	case scenarioReact:
+		return fmt.Sprintf("%v reacts %q to %q in %v", step.actor, step.reaction, step.text, step.chat)
+	case scenarioExpectReaction:
+		return fmt.Sprintf("expect %v sees reaction %q to %q in %v", step.actor, step.reaction, step.text, step.chat)
+	}
+	return "unknown step"
+}
+
+// NewScenario creates an empty Scenario.
+func NewScenario() *Scenario {
+	return &Scenario{}
+}
+
+// CreateGroup adds a step where owner creates the group with the given members.
+// The owner then sends a "<owner> created group <group>" message so the group is promoted,
+// and the step completes when every member received it and accepted the group.
+func (scenario *Scenario) CreateGroup(owner, group string, members ...string) *Scenario {
+	return scenario.add(scenarioStep{action: scenarioCreateGroup, actor: owner, chat: group, members: members})
+}
+
+// Say adds a step where actor sends a text message to the given group.
+// If group is empty, the last group used in the scenario is assumed.
+func (scenario *Scenario) Say(actor, group, text string) *Scenario {
+	return scenario.add(scenarioStep{action: scenarioSay, actor: actor, chat: group, text: text})
+}
+
+// ExpectReceived adds a step that waits until actor receives a message with the given text in the given group.
+func (scenario *Scenario) ExpectReceived(actor, group, text string) *Scenario {
+	return scenario.add(scenarioStep{action: scenarioExpectReceived, actor: actor, chat: group, text: text})
+}
+
+// React adds a step where actor reacts to the last message with the given text in the given group.
+func (scenario *Scenario) React(actor, group, text, reaction string) *Scenario {
+	return scenario.add(scenarioStep{action: scenarioReact, actor: actor, chat: group, text: text, reaction: reaction})
+}
+
+// ExpectReaction adds a step that waits until actor sees the given reaction to the last message
+// with the given text in the given group.
+func (scenario *Scenario) ExpectReaction(actor, group, text, reaction string) *Scenario {
+	return scenario.add(scenarioStep{action: scenarioExpectReaction, actor: actor, chat: group, text: text, reaction: reaction})
+}
+
+// Participants returns the names of the accounts used in the scenario in order of appearance.
+func (scenario *Scenario) Participants() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, step := range scenario.steps {
+		for _, name := range append([]string{step.actor}, step.members...) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func (scenario *Scenario) add(step scenarioStep) *Scenario {
+	if step.chat == "" {
+		step.chat = scenario.chat
+	}
+	scenario.chat = step.chat
+	scenario.steps = append(scenario.steps, step)
+	return scenario
+}
+
+// ParseScenario parses a scenario script. Steps are separated by new lines or semicolons,
+// lines starting with # are comments. The following steps are supported:
+//
+//	alice creates group G with bob, carol
+//	bob says "hello world" in G
+//	expect carol receives "hello world" in G
+//	carol reacts "👍" to "hello world" in G
+//	expect bob sees reaction "👍" to "hello world" in G
+//
+// Texts containing spaces must be quoted using Go syntax. The "in <group>" suffix is optional,
+// if omitted the last group used in the script is assumed.
+func ParseScenario(script string) (*Scenario, error) {
+	scenario := NewScenario()
+	for lineNo, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		statements, err := tokenizeScenario(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo+1, err)
+		}
+		for _, tokens := range statements {
+			if err := scenario.parseStep(tokens); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo+1, err)
+			}
+		}
+	}
+	if len(scenario.steps) == 0 {
+		return nil, fmt.Errorf("empty scenario")
+	}
+	return scenario, nil
+}
+
+func (scenario *Scenario) parseStep(tokens []string) error {
+	// optional "in <group>" suffix
+	chat := ""
+	if n := len(tokens); n > 2 && tokens[n-2] == "in" {
+		chat = tokens[n-1]
+		tokens = tokens[:n-2]
+	}
+	if chat == "" && scenario.chat == "" && !(len(tokens) > 1 && tokens[1] == "creates") {
+		return fmt.Errorf("no group given in %q", strings.Join(tokens, " "))
+	}
+
+	switch {
+	case len(tokens) >= 6 && tokens[1] == "creates" && tokens[2] == "group" && tokens[4] == "with":
+		if chat != "" {
+			return fmt.Errorf("unexpected \"in %v\"", chat)
+		}
+		scenario.CreateGroup(tokens[0], tokens[3], tokens[5:]...)
+	case len(tokens) == 3 && tokens[1] == "says":
+		scenario.Say(tokens[0], chat, tokens[2])
+	case len(tokens) == 4 && tokens[0] == "expect" && tokens[2] == "receives":
+		scenario.ExpectReceived(tokens[1], chat, tokens[3])
+	case len(tokens) == 5 && tokens[1] == "reacts" && tokens[3] == "to":
+		scenario.React(tokens[0], chat, tokens[4], tokens[2])
+	case len(tokens) == 7 && tokens[0] == "expect" && tokens[2] == "sees" && tokens[3] == "reaction" && tokens[5] == "to":
+		scenario.ExpectReaction(tokens[1], chat, tokens[6], tokens[4])
+	default:
+		return fmt.Errorf("invalid step: %q", strings.Join(tokens, " "))
+	}
+	return nil
+}
+
+// tokenizeScenario splits a line into statements of words and quoted strings.
+// Commas are treated as white space.
+func tokenizeScenario(line string) ([][]string, error) {
+	var statements [][]string
+	var tokens []string
+	for line != "" {
+		r, size := utf8.DecodeRuneInString(line)
+		switch {
+		case r == ';':
+			if len(tokens) > 0 {
+				statements = append(statements, tokens)
+			}
+			tokens = nil
+			line = line[size:]
+		case r == ',' || unicode.IsSpace(r):
+			line = line[size:]
+		case r == '"' || r == '`':
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string: %v", line)
+			}
+			text, _ := strconv.Unquote(quoted)
+			tokens = append(tokens, text)
+			line = line[len(quoted):]
+		default:
+			end := strings.IndexFunc(line, func(r rune) bool {
+				return r == ';' || r == ',' || r == '"' || unicode.IsSpace(r)
+			})
+			if end < 0 {
+				end = len(line)
+			}
+			tokens = append(tokens, line[:end])
+			line = line[end:]
+		}
+	}
+	if len(tokens) > 0 {
+		statements = append(statements, tokens)
+	}
+	return statements, nil
+}
+
+// RunScenario provisions an online account for every participant of the scenario, with the
+// participant name as display name, and executes its steps in order. If a step fails,
+// the error is reported with t.Errorf() and t.FailNow() is called.
+//
+// If callback is not nil, it is called after all the steps succeeded with the scenario
+// accounts by participant name, before the accounts are closed.
+func (factory *AcFactory) RunScenario(t GoldenT, scenario *Scenario, callback func(map[string]*ScenarioAccount)) {
+	t.Helper()
+	accounts := make(map[string]*ScenarioAccount)
+	factory.withScenarioAccounts(scenario.Participants(), accounts, func() {
+		t.Helper()
+		timeout := scenario.Timeout
+		if timeout == 0 {
+			timeout = defaultScenarioTimeout
+		}
+		for i, step := range scenario.steps {
+			if err := factory.runScenarioStep(accounts, step, time.Now().Add(timeout)); err != nil {
+				t.Errorf("scenario step %d (%v) failed: %v", i+1, step, err)
+				t.FailNow()
+				return
+			}
+		}
+		if callback != nil {
+			callback(accounts)
+		}
+	})
+}
+
+func (factory *AcFactory) withScenarioAccounts(names []string, accounts map[string]*ScenarioAccount, callback func()) {
+	if len(names) == 0 {
+		callback()
+		return
+	}
+	factory.WithOnlineAccount(func(rpc *Rpc, accId uint32) {
+		name := names[0]
+		if err := rpc.SetConfig(accId, "displayname", &name); err != nil {
+			panic(err)
+		}
+		accounts[name] = &ScenarioAccount{Name: name, Rpc: rpc, AccId: accId, Chats: make(map[string]uint32)}
+		factory.withScenarioAccounts(names[1:], accounts, callback)
+	})
+}
+
+func (factory *AcFactory) runScenarioStep(accounts map[string]*ScenarioAccount, step scenarioStep, deadline time.Time) error {
+	acc := accounts[step.actor]
+	if step.action == scenarioCreateGroup {
+		return factory.createScenarioGroup(accounts, step, deadline)
+	}
+	chatId, ok := acc.Chats[step.chat]
+	if !ok {
+		return fmt.Errorf("%v is not a member of group %v", step.actor, step.chat)
+	}
+
+	switch step.action {
+	case scenarioSay:
+		_, err := acc.Rpc.MiscSendTextMessage(acc.AccId, chatId, step.text)
+		return err
+	case scenarioExpectReceived:
+		for {
+			msgId, err := findScenarioMessage(acc, chatId, step.text, true)
+			if err != nil || msgId != 0 {
+				return err
+			}
+			if err := waitScenarioEvent(acc, chatId, deadline, &EventTypeIncomingMsg{}); err != nil {
+				return err
+			}
+		}
+	case scenarioReact:
+		msgId, err := findScenarioMessage(acc, chatId, step.text, false)
+		if err != nil {
+			return err
+		}
+		if msgId == 0 {
+			return fmt.Errorf("message %q not found", step.text)
+		}
+		_, err = acc.Rpc.SendReaction(acc.AccId, msgId, []string{step.reaction})
+		return err
+	case scenarioExpectReaction:
+		for {
+			msgId, err := findScenarioMessage(acc, chatId, step.text, false)
+			if err != nil {
+				return err
+			}
+			if msgId != 0 {
+				reactions, err := acc.Rpc.GetMessageReactions(acc.AccId, msgId)
+				if err != nil {
+					return err
+				}
+				if reactions != nil {
+					for _, reaction := range reactions.Reactions {
+						if reaction.Emoji == step.reaction {
+							return nil
+						}
+					}
+				}
+			}
+			if err := waitScenarioEvent(acc, chatId, deadline, &EventTypeReactionsChanged{}, &EventTypeIncomingMsg{}); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("unknown step")
+}
+
+func (factory *AcFactory) createScenarioGroup(accounts map[string]*ScenarioAccount, step scenarioStep, deadline time.Time) error {
+	owner := accounts[step.actor]
+	if _, ok := owner.Chats[step.chat]; ok {
+		return fmt.Errorf("group %v already exists", step.chat)
+	}
+	chatId, err := owner.Rpc.CreateGroupChat(owner.AccId, step.chat, false)
+	if err != nil {
+		return err
+	}
+	owner.Chats[step.chat] = chatId
+	for _, name := range step.members {
+		member := accounts[name]
+		contactId := factory.ImportContact(owner.Rpc, owner.AccId, member.Rpc, member.AccId)
+		if err := owner.Rpc.AddContactToChat(owner.AccId, chatId, contactId); err != nil {
+			return err
+		}
+	}
+	text := fmt.Sprintf("%v created group %v", step.actor, step.chat)
+	if _, err := owner.Rpc.MiscSendTextMessage(owner.AccId, chatId, text); err != nil {
+		return err
+	}
+
+	for _, name := range step.members {
+		member := accounts[name]
+		for member.Chats[step.chat] == 0 {
+			ctx, cancel := context.WithDeadline(context.Background(), deadline)
+			event, err := nextScenarioEvent(ctx, member, &EventTypeIncomingMsg{})
+			cancel()
+			if err != nil {
+				return fmt.Errorf("%v did not receive group %v: %w", name, step.chat, err)
+			}
+			memberChatId := event.(*EventTypeIncomingMsg).ChatId
+			chat, err := member.Rpc.GetBasicChatInfo(member.AccId, memberChatId)
+			if err != nil {
+				return err
+			}
+			if chat.Name == step.chat {
+				if err := member.Rpc.AcceptChat(member.AccId, memberChatId); err != nil {
+					return err
+				}
+				member.Chats[step.chat] = memberChatId
+			}
+		}
+	}
+	return nil
+}
+
+// findScenarioMessage returns the ID of the last message with the given text in the chat,
+// or zero if there is no such message.
+func findScenarioMessage(acc *ScenarioAccount, chatId uint32, text string, incoming bool) (uint32, error) {
+	items, err := acc.Rpc.GetMessageListItems(acc.AccId, chatId, false, false)
+	if err != nil {
+		return 0, err
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		item, ok := items[i].(*MessageListItemMessage)
+		if !ok {
+			continue
+		}
+		msg, err := acc.Rpc.GetMessage(acc.AccId, item.MsgId)
+		if err != nil {
+			return 0, err
+		}
+		if msg.Text == text && !msg.IsInfo && (!incoming || msg.FromId != ContactSelf) {
+			return msg.Id, nil
+		}
+	}
+	return 0, nil
+}
+
+// waitScenarioEvent waits for an event of one of the given types in the given chat.
+func waitScenarioEvent(acc *ScenarioAccount, chatId uint32, deadline time.Time, events ...EventType) error {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	for {
+		event, err := nextScenarioEvent(ctx, acc, events...)
+		if err != nil {
+			return err
+		}
+		if getChatId(event) == chatId {
+			return nil
+		}
+	}
+}
+
+// nextScenarioEvent returns the next event of one of the given types, events for
+// other accounts are discarded.
+func nextScenarioEvent(ctx context.Context, acc *ScenarioAccount, events ...EventType) (EventType, error) {
+	rpc := &Rpc{Context: ctx, Transport: acc.Rpc.Transport}
+	for {
+		ev, err := rpc.GetNextEvent()
+		if err != nil {
+			return nil, err
+		}
+		if ev.ContextId != acc.AccId {
+			continue
+		}
+		for _, event := range events {
+			if ev.Event.GetKind() == event.GetKind() {
+				return ev.Event, nil
+			}
+		}
+	}
+}
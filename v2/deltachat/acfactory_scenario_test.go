@@ -0,0 +1,63 @@
+package deltachat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenario(t *testing.T) {
+	t.Parallel()
+	scenario, err := ParseScenario(`
+		# comment
+		alice creates group G with bob, carol
+		bob says "hello world"; expect carol receives "hello world" in G
+		carol reacts "👍" to "hello world"
+		expect bob sees reaction "👍" to "hello world"
+		alice says hi in G
+	`)
+	require.Nil(t, err)
+	expected := NewScenario().
+		CreateGroup("alice", "G", "bob", "carol").
+		Say("bob", "G", "hello world").
+		ExpectReceived("carol", "G", "hello world").
+		React("carol", "G", "hello world", "👍").
+		ExpectReaction("bob", "G", "hello world", "👍").
+		Say("alice", "G", "hi")
+	require.Equal(t, expected, scenario)
+	require.Equal(t, []string{"alice", "bob", "carol"}, scenario.Participants())
+	require.Equal(t, `carol reacts "👍" to "hello world" in G`, scenario.steps[3].String())
+
+	for _, script := range []string{
+		"",
+		"# only a comment",
+		`bob says "hello"`,
+		"alice creates group G with",
+		"alice creates group G with bob in H",
+		"alice creates group G with bob; bob shouts hi",
+		`alice creates group G with bob; bob says "unterminated`,
+	} {
+		_, err := ParseScenario(script)
+		require.NotNil(t, err, script)
+	}
+}
+
+func TestAcFactory_RunScenario(t *testing.T) {
+	t.Parallel()
+	scenario, err := ParseScenario(`
+		alice creates group G with bob, carol
+		bob says "hello from bob"
+		expect carol receives "hello from bob"
+		expect alice receives "hello from bob"
+		carol reacts "👍" to "hello from bob"
+		expect bob sees reaction "👍" to "hello from bob"
+	`)
+	require.Nil(t, err)
+	acfactory.RunScenario(t, scenario, func(accounts map[string]*ScenarioAccount) {
+		require.Len(t, accounts, 3)
+		carol := accounts["carol"]
+		contacts, err := carol.Rpc.GetChatContacts(carol.AccId, carol.Chats["G"])
+		require.Nil(t, err)
+		require.Len(t, contacts, 3)
+	})
+}
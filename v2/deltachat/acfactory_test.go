@@ -13,6 +13,24 @@ func TestAcFactory_TearDown(t *testing.T) {
 	acf.TearDown()
 }
 
+func TestAcFactory_LocalServer(t *testing.T) {
+	t.Parallel()
+	acf := &AcFactory{LocalServer: true}
+	acf.TearUp()
+	defer acf.TearDown()
+	require.NotNil(t, acf.MailServer())
+
+	acf.WithOnlineAccount(func(rpc1 *Rpc, accId1 uint32) {
+		acf.WithOnlineAccount(func(rpc2 *Rpc, accId2 uint32) {
+			chatId := acf.CreateChat(rpc1, accId1, rpc2, accId2)
+			_, err := rpc1.MiscSendTextMessage(accId1, chatId, "hello")
+			require.Nil(t, err)
+			msg := acf.NextMsg(rpc2, accId2)
+			require.Equal(t, "hello", msg.Text)
+		})
+	})
+}
+
 func TestAcFactory_getChatId(t *testing.T) {
 	t.Parallel()
 	getChatId(&EventTypeMsgsChanged{})
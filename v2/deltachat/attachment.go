@@ -0,0 +1,177 @@
+package deltachat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxFileSize is the size limit of the files handled by Attachments without MaxSize.
+const DefaultMaxFileSize int64 = 100 * 1024 * 1024
+
+// FileSizeErr is returned by Attachments if a file is bigger than the size limit.
+type FileSizeErr struct {
+	// Size of the file, streams are only read up to Limit+1 bytes.
+	Size  int64
+	Limit int64
+}
+
+func (err *FileSizeErr) Error() string {
+	return fmt.Sprintf("file size %v exceeds limit of %v bytes", err.Size, err.Limit)
+}
+
+// Attachments sends and reads message files as streams, managing the temporary files
+// needed by Rpc.CopyToBlobDir() and Rpc.SaveMsgFile().
+//
+// The temporary files are created in the local filesystem, the RPC server must run
+// on the same machine, e.g. with IOTransport.
+type Attachments struct {
+	Rpc *Rpc
+	// Size limit of the files, DefaultMaxFileSize if zero.
+	MaxSize int64
+	// Directory of the temporary files, the default directory for temporary files if empty.
+	TempDir string
+}
+
+// Create a new Attachments with the default size limit.
+func NewAttachments(rpc *Rpc) *Attachments {
+	return &Attachments{Rpc: rpc}
+}
+
+// SendReader sends a message with the content of reader as file with the given name.
+// If viewtype is nil, it is detected from the content and the file name, see DetectViewtype().
+func (att *Attachments) SendReader(accId uint32, chatId uint32, reader io.Reader, filename string, viewtype *Viewtype) (uint32, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	head = head[:n]
+	if viewtype == nil {
+		detected := DetectViewtype(filename, head)
+		viewtype = &detected
+	}
+
+	file, err := os.CreateTemp(att.TempDir, "deltachat-*"+filepath.Ext(filename))
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name()) //nolint:errcheck
+	size, err := io.Copy(file, io.LimitReader(io.MultiReader(bytes.NewReader(head), reader), att.maxSize()+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if size > att.maxSize() {
+		return 0, &FileSizeErr{Size: size, Limit: att.maxSize()}
+	}
+
+	blob, err := att.Rpc.CopyToBlobDir(accId, file.Name())
+	if err != nil {
+		return 0, err
+	}
+	return att.Rpc.SendMsg(accId, chatId, MessageData{File: &blob, Filename: &filename, Viewtype: viewtype})
+}
+
+// OpenMsgFile returns a reader of the file of a message, the file is a copy
+// deleted when the reader is closed.
+func (att *Attachments) OpenMsgFile(accId uint32, msgId uint32) (io.ReadCloser, error) {
+	msg, err := att.Rpc.GetMessage(accId, msgId)
+	if err != nil {
+		return nil, err
+	}
+	if msg.File == nil || *msg.File == "" {
+		return nil, fmt.Errorf("message %v has no file", msgId)
+	}
+	if size := int64(msg.FileBytes); size > att.maxSize() {
+		return nil, &FileSizeErr{Size: size, Limit: att.maxSize()}
+	}
+
+	dir, err := os.MkdirTemp(att.TempDir, "deltachat-")
+	if err != nil {
+		return nil, err
+	}
+	name := "file"
+	if msg.FileName != nil && filepath.Base(*msg.FileName) != "." {
+		name = filepath.Base(*msg.FileName)
+	}
+	path := filepath.Join(dir, name)
+	if err := att.Rpc.SaveMsgFile(accId, msgId, path); err != nil {
+		os.RemoveAll(dir) //nolint:errcheck
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		os.RemoveAll(dir) //nolint:errcheck
+		return nil, err
+	}
+	return &tempFile{File: file, dir: dir}, nil
+}
+
+func (att *Attachments) maxSize() int64 {
+	if att.MaxSize > 0 {
+		return att.MaxSize
+	}
+	return DefaultMaxFileSize
+}
+
+// tempFile is a file removed with its directory when closed.
+type tempFile struct {
+	*os.File
+	dir string
+}
+
+func (file *tempFile) Close() error {
+	err := file.File.Close()
+	if removeErr := os.RemoveAll(file.dir); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// DetectViewtype returns the Viewtype of a file from the first bytes of its content,
+// or from the extension of its name if the content type is unknown.
+func DetectViewtype(filename string, head []byte) Viewtype {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".xdc":
+		return ViewtypeWebxdc
+	case ".vcf", ".vcard":
+		return ViewtypeVcard
+	}
+	mimeType := http.DetectContentType(head)
+	if mimeType == "application/octet-stream" || strings.HasPrefix(mimeType, "text/plain") {
+		if byExt := mime.TypeByExtension(ext); byExt != "" {
+			mimeType = byExt
+		}
+	}
+	switch {
+	case strings.HasPrefix(mimeType, "image/gif"):
+		return ViewtypeGif
+	case strings.HasPrefix(mimeType, "image/"):
+		return ViewtypeImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		return ViewtypeAudio
+	case strings.HasPrefix(mimeType, "video/"):
+		return ViewtypeVideo
+	}
+	return ViewtypeFile
+}
+
+// SendReader sends a message with the content of reader as file, see Attachments.SendReader().
+func (rpc *Rpc) SendReader(accId uint32, chatId uint32, reader io.Reader, filename string, viewtype *Viewtype) (uint32, error) {
+	return NewAttachments(rpc).SendReader(accId, chatId, reader, filename, viewtype)
+}
+
+// OpenMsgFile returns a reader of the file of a message, see Attachments.OpenMsgFile().
+func (rpc *Rpc) OpenMsgFile(accId uint32, msgId uint32) (io.ReadCloser, error) {
+	return NewAttachments(rpc).OpenMsgFile(accId, msgId)
+}
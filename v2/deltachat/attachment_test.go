@@ -0,0 +1,55 @@
+package deltachat
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// 1x1 transparent PNG
+var pngImage, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII=")
+
+func TestDetectViewtype(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, ViewtypeImage, DetectViewtype("image.bin", pngImage))
+	require.Equal(t, ViewtypeGif, DetectViewtype("", []byte("GIF89a")))
+	require.Equal(t, ViewtypeWebxdc, DetectViewtype("app.XDC", []byte("PK\x03\x04")))
+	require.Equal(t, ViewtypeVcard, DetectViewtype("contact.vcf", []byte("BEGIN:VCARD")))
+	require.Equal(t, ViewtypeAudio, DetectViewtype("song", []byte("ID3\x03\x00")))
+	require.Equal(t, ViewtypeImage, DetectViewtype("photo.JPG", nil))
+	require.Equal(t, ViewtypeFile, DetectViewtype("report.csv", []byte("a,b\n1,2\n")))
+	require.Equal(t, ViewtypeFile, DetectViewtype("unknown", nil))
+}
+
+func TestAttachments(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *Rpc, accId uint32) {
+		chatId, err := rpc.CreateGroupChat(accId, "test group", false)
+		require.Nil(t, err)
+
+		msgId, err := rpc.SendReader(accId, chatId, bytes.NewReader(pngImage), "image.png", nil)
+		require.Nil(t, err)
+		msg, err := rpc.GetMessage(accId, msgId)
+		require.Nil(t, err)
+		require.Equal(t, ViewtypeImage, msg.ViewType)
+
+		reader, err := rpc.OpenMsgFile(accId, msgId)
+		require.Nil(t, err)
+		data, err := io.ReadAll(reader)
+		require.Nil(t, err)
+		require.Nil(t, reader.Close())
+		require.Equal(t, pngImage, data)
+
+		att := &Attachments{Rpc: rpc, MaxSize: 10}
+		_, err = att.SendReader(accId, chatId, strings.NewReader("more than ten bytes"), "file.txt", nil)
+		var sizeErr *FileSizeErr
+		require.ErrorAs(t, err, &sizeErr)
+		require.Equal(t, int64(11), sizeErr.Size)
+		_, err = att.OpenMsgFile(accId, msgId)
+		require.ErrorAs(t, err, &sizeErr)
+	})
+}
@@ -0,0 +1,250 @@
+// Package backup manages the backups of an account: exports with progress reporting,
+// rotation of old backups and verification of backup files, and transfers of accounts
+// to other devices with TransferAccount().
+//
+//	backups := backup.New(rpc, accId, "/var/backups/bot")
+//	backups.KeepCount = 7
+//	bot.Observe(backups.EventHandler())
+//	path, err := backups.Export(func(permille uint16) { log.Printf("backup %v‰", permille) })
+//	if err == nil {
+//		err = backups.Verify(path)
+//	}
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+// Backup is a backup file.
+type Backup struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+}
+
+// InvalidBackupErr is returned by Manager.Verify() for backups that can not be restored.
+type InvalidBackupErr struct {
+	Path   string
+	Reason string
+}
+
+func (err *InvalidBackupErr) Error() string {
+	return fmt.Sprintf("invalid backup %v: %v", err.Path, err.Reason)
+}
+
+// Manager exports the backups of an account to a directory and rotates them.
+//
+// The directory must only contain the backups of the account, all the .tar files
+// in it are considered backups of the account by List() and Rotate().
+//
+// Export progress is reported from the ImexProgress and ImexFileWritten events passed
+// to HandleEvent() by the application, see Manager.EventHandler(). The Manager does not
+// read the events of the Rpc itself: without events, the exports report no progress and
+// the new backup is found by listing Dir.
+type Manager struct {
+	Rpc       *deltachat.Rpc
+	AccountId uint32
+	Dir       string
+	// Passphrase of the exported and verified backups, backups are not encrypted if empty.
+	Passphrase string
+	// Number of backups kept by Rotate(), backups are not rotated by count if zero.
+	KeepCount int
+	// Maximum age of the backups kept by Rotate(), backups are not rotated by age if zero.
+	KeepAge time.Duration
+	// deltachat-rpc-server binary used by Verify(), the default binary if empty.
+	ServerCmd string
+	mu        sync.Mutex
+	// state of the running export
+	exporting bool
+	progress  func(permille uint16)
+	written   string
+}
+
+// Create a new Manager for the backups of the given account in the given directory.
+func New(rpc *deltachat.Rpc, accId uint32, dir string) *Manager {
+	return &Manager{Rpc: rpc, AccountId: accId, Dir: dir}
+}
+
+// Export exports a backup to Dir, rotates the old backups and returns the path of the new backup.
+// The progress callback, if not nil, is called with the export progress in permille.
+func (m *Manager) Export(progress func(permille uint16)) (string, error) {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return "", err
+	}
+	before, err := m.List()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	m.exporting, m.progress, m.written = true, progress, ""
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.exporting, m.progress = false, nil
+		m.mu.Unlock()
+	}()
+
+	if err := m.Rpc.ExportBackup(m.AccountId, m.Dir, m.passphrase()); err != nil {
+		return "", err
+	}
+
+	path, err := m.newBackup(before)
+	if err != nil {
+		return "", err
+	}
+	if _, err := m.Rotate(); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// newBackup returns the file written by the last export, found in the ImexFileWritten
+// events or by comparing the backups with the given backups listed before the export.
+func (m *Manager) newBackup(before []Backup) (string, error) {
+	m.mu.Lock()
+	written := m.written
+	m.mu.Unlock()
+	if written != "" {
+		return written, nil
+	}
+	after, err := m.List()
+	if err != nil {
+		return "", err
+	}
+	for _, backup := range after {
+		if !slices.ContainsFunc(before, func(old Backup) bool { return old.Path == backup.Path }) {
+			return backup.Path, nil
+		}
+	}
+	return "", fmt.Errorf("no backup file written in %v", m.Dir)
+}
+
+// HandleEvent reports the progress of the running export from the given event of the given account.
+func (m *Manager) HandleEvent(accId uint32, event deltachat.EventType) {
+	if accId != m.AccountId {
+		return
+	}
+	switch event := event.(type) {
+	case *deltachat.EventTypeImexProgress:
+		m.mu.Lock()
+		progress := m.progress
+		m.mu.Unlock()
+		if progress != nil {
+			progress(event.Progress)
+		}
+	case *deltachat.EventTypeImexFileWritten:
+		m.mu.Lock()
+		if m.exporting && filepath.Ext(event.Path) == ".tar" {
+			m.written = event.Path
+		}
+		m.mu.Unlock()
+	}
+}
+
+// EventHandler returns an EventHandler reporting the export progress, see Bot.Observe().
+func (m *Manager) EventHandler() deltachat.EventHandler {
+	return func(_ *deltachat.Bot, accId uint32, event deltachat.EventType) {
+		m.HandleEvent(accId, event)
+	}
+}
+
+// List returns the backups in Dir, newest first.
+func (m *Manager) List() ([]Backup, error) {
+	paths, err := filepath.Glob(filepath.Join(m.Dir, "*.tar"))
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]Backup, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		backups = append(backups, Backup{Path: path, ModTime: info.ModTime(), Size: info.Size()})
+	}
+	slices.SortFunc(backups, func(a, b Backup) int { return b.ModTime.Compare(a.ModTime) })
+	return backups, nil
+}
+
+// Rotate removes the backups exceeding KeepCount and the backups older than KeepAge,
+// the newest backup is always kept. The paths of the removed backups are returned.
+func (m *Manager) Rotate() ([]string, error) {
+	backups, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	now := time.Now()
+	for i, backup := range backups {
+		if i == 0 {
+			continue
+		}
+		tooMany := m.KeepCount > 0 && i >= m.KeepCount
+		tooOld := m.KeepAge > 0 && now.Sub(backup.ModTime) > m.KeepAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(backup.Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backup.Path)
+	}
+	return removed, nil
+}
+
+// Verify imports the given backup into a new account of a throwaway deltachat-rpc-server
+// and checks that the account is configured. InvalidBackupErr is returned if the backup
+// can not be imported.
+func (m *Manager) Verify(path string) error {
+	dir, err := os.MkdirTemp("", "dc-backup-verify-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	trans := deltachat.NewIOTransport()
+	if m.ServerCmd != "" {
+		trans.Cmd = m.ServerCmd
+	}
+	trans.AccountsDir = filepath.Join(dir, "accounts")
+	trans.Stderr = nil
+	if err := trans.Open(); err != nil {
+		return err
+	}
+	defer trans.Close()
+
+	rpc := &deltachat.Rpc{Context: m.Rpc.Context, Transport: trans}
+	accId, err := rpc.AddAccount()
+	if err != nil {
+		return err
+	}
+	if err := rpc.ImportBackup(accId, path, m.passphrase()); err != nil {
+		return &InvalidBackupErr{Path: path, Reason: err.Error()}
+	}
+	configured, err := rpc.IsConfigured(accId)
+	if err != nil {
+		return err
+	}
+	if !configured {
+		return &InvalidBackupErr{Path: path, Reason: "account is not configured"}
+	}
+	return nil
+}
+
+func (m *Manager) passphrase() *string {
+	if m.Passphrase == "" {
+		return nil
+	}
+	return &m.Passphrase
+}
@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBackups writes a backup file per age in dir, modified age ago.
+func writeBackups(t *testing.T, dir string, ages ...time.Duration) []string {
+	var paths []string
+	for i, age := range ages {
+		path := filepath.Join(dir, "backup-"+string(rune('a'+i))+".tar")
+		require.Nil(t, os.WriteFile(path, []byte("tar"), 0o600))
+		modTime := time.Now().Add(-age)
+		require.Nil(t, os.Chtimes(path, modTime, modTime))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestManager_List(t *testing.T) {
+	t.Parallel()
+	m := New(nil, 1, t.TempDir())
+	paths := writeBackups(t, m.Dir, 2*time.Hour, time.Hour, 3*time.Hour)
+	require.Nil(t, os.WriteFile(filepath.Join(m.Dir, "notes.txt"), nil, 0o600))
+	backups, err := m.List()
+	require.Nil(t, err)
+	require.Len(t, backups, 3)
+	require.Equal(t, paths[1], backups[0].Path)
+	require.Equal(t, paths[0], backups[1].Path)
+	require.Equal(t, paths[2], backups[2].Path)
+	require.Equal(t, int64(3), backups[0].Size)
+}
+
+func TestManager_Rotate(t *testing.T) {
+	t.Parallel()
+	m := New(nil, 1, t.TempDir())
+	day := 24 * time.Hour
+	paths := writeBackups(t, m.Dir, 0, day, 2*day, 3*day, 10*day)
+
+	// nothing is removed without limits
+	removed, err := m.Rotate()
+	require.Nil(t, err)
+	require.Empty(t, removed)
+
+	m.KeepAge = 5 * day
+	removed, err = m.Rotate()
+	require.Nil(t, err)
+	require.Equal(t, []string{paths[4]}, removed)
+
+	m.KeepCount = 2
+	removed, err = m.Rotate()
+	require.Nil(t, err)
+	require.Equal(t, []string{paths[2], paths[3]}, removed)
+
+	// the newest backup is kept even if it is too old
+	m.KeepCount = 0
+	m.KeepAge = time.Minute
+	old := time.Now().Add(-time.Hour)
+	require.Nil(t, os.Chtimes(paths[0], old, old))
+	require.Nil(t, os.Chtimes(paths[1], old.Add(-time.Hour), old.Add(-time.Hour)))
+	removed, err = m.Rotate()
+	require.Nil(t, err)
+	require.Equal(t, []string{paths[1]}, removed)
+	require.FileExists(t, paths[0])
+}
+
+func TestManager_HandleEvent(t *testing.T) {
+	t.Parallel()
+	m := New(nil, 1, t.TempDir())
+	var progress []uint16
+	m.progress = func(permille uint16) { progress = append(progress, permille) }
+	m.exporting = true
+	m.HandleEvent(1, &deltachat.EventTypeImexProgress{Progress: 10})
+	m.HandleEvent(2, &deltachat.EventTypeImexProgress{Progress: 20})
+	m.HandleEvent(1, &deltachat.EventTypeImexFileWritten{Path: "/tmp/backup.tar"})
+	m.HandleEvent(1, &deltachat.EventTypeImexProgress{Progress: 1000})
+	require.Equal(t, []uint16{10, 1000}, progress)
+
+	path, err := m.newBackup(nil)
+	require.Nil(t, err)
+	require.Equal(t, "/tmp/backup.tar", path)
+
+	m.written = ""
+	_, err = m.newBackup(nil)
+	require.ErrorContains(t, err, "no backup file written")
+}
+
+func TestManager_ExportVerify(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *deltachat.Rpc, accId uint32) {
+		m := New(rpc, accId, filepath.Join(acfactory.MkdirTemp(), "backups"))
+		m.Passphrase = "secret"
+		m.KeepCount = 1
+		path, err := m.Export(nil)
+		require.Nil(t, err)
+		require.FileExists(t, path)
+		require.Nil(t, m.Verify(path))
+
+		m.Passphrase = "wrong"
+		var invalid *InvalidBackupErr
+		require.True(t, errors.As(m.Verify(path), &invalid))
+	})
+}
@@ -0,0 +1,154 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+// backupQrRetryDelay is the delay between the Rpc.GetBackupQr() calls while the backup is prepared.
+const backupQrRetryDelay = 200 * time.Millisecond
+
+// Transfer copies an account to another deltachat-rpc-server, e.g. to set up a second
+// device of a bot, with Rpc.ProvideBackup() on the source and Rpc.GetBackup() on the
+// destination.
+//
+// Transfer progress is reported from the ImexProgress events of the new account that the
+// application passes to HandleEvent(), see Transfer.EventHandler(); the events of the
+// destination Rpc are never read by the Transfer itself.
+type Transfer struct {
+	// Called with the progress of the new account in permille.
+	Progress func(permille uint16)
+	mu       sync.Mutex
+	// account receiving the running transfer
+	dstAccId uint32
+}
+
+// TransferAccount copies the given account of src into a new account of dst and returns
+// the id of the new account, see Transfer.Run().
+func TransferAccount(ctx context.Context, src *deltachat.Rpc, srcAccId uint32, dst *deltachat.Rpc) (uint32, error) {
+	return (&Transfer{}).Run(ctx, src, srcAccId, dst)
+}
+
+// Run copies the given account of src into a new account of dst, starts the I/O of the
+// new account and returns its id.
+//
+// If the transfer fails or ctx is canceled, the ongoing processes of both accounts are
+// stopped with Rpc.StopOngoingProcess() and the new account is removed.
+func (transfer *Transfer) Run(ctx context.Context, src *deltachat.Rpc, srcAccId uint32, dst *deltachat.Rpc) (uint32, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	srcRpc := &deltachat.Rpc{Context: ctx, Transport: src.Transport}
+	dstRpc := &deltachat.Rpc{Context: ctx, Transport: dst.Transport}
+	// cleanup calls must not be canceled with ctx
+	cleanupCtx := context.WithoutCancel(ctx)
+	srcCleanup := &deltachat.Rpc{Context: cleanupCtx, Transport: src.Transport}
+	dstCleanup := &deltachat.Rpc{Context: cleanupCtx, Transport: dst.Transport}
+
+	provider := &backupProvider{done: make(chan struct{})}
+	go func() {
+		provider.err = srcRpc.ProvideBackup(srcAccId)
+		close(provider.done)
+	}()
+	stopProviding := func() {
+		srcCleanup.StopOngoingProcess(srcAccId) //nolint:errcheck
+		<-provider.done
+	}
+
+	qr, err := provider.waitQr(srcRpc, srcAccId)
+	if err != nil {
+		stopProviding()
+		return 0, err
+	}
+
+	dstAccId, err := dstRpc.AddAccount()
+	if err != nil {
+		stopProviding()
+		return 0, err
+	}
+	transfer.mu.Lock()
+	transfer.dstAccId = dstAccId
+	transfer.mu.Unlock()
+	defer func() {
+		transfer.mu.Lock()
+		transfer.dstAccId = 0
+		transfer.mu.Unlock()
+	}()
+	fail := func(err error) (uint32, error) {
+		dstCleanup.StopOngoingProcess(dstAccId) //nolint:errcheck
+		dstCleanup.RemoveAccount(dstAccId)      //nolint:errcheck
+		return 0, err
+	}
+
+	if err := dstRpc.GetBackup(dstAccId, qr); err != nil {
+		stopProviding()
+		return fail(fmt.Errorf("receiving backup: %w", err))
+	}
+	select {
+	case <-provider.done:
+		err = provider.err
+	case <-ctx.Done():
+		stopProviding()
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fail(fmt.Errorf("providing backup: %w", err))
+	}
+	if err := dstRpc.StartIo(dstAccId); err != nil {
+		return fail(err)
+	}
+	return dstAccId, nil
+}
+
+// backupProvider is a running Rpc.ProvideBackup() call.
+type backupProvider struct {
+	// closed when ProvideBackup() returned
+	done chan struct{}
+	err  error
+}
+
+// waitQr returns the QR code of the backup provided by the given account once it is ready.
+func (provider *backupProvider) waitQr(rpc *deltachat.Rpc, accId uint32) (string, error) {
+	for {
+		qr, err := rpc.GetBackupQr(accId)
+		if err == nil {
+			return qr, nil
+		}
+		select {
+		case <-provider.done:
+			err := provider.err
+			if err == nil {
+				err = errors.New("backup provider stopped before the transfer")
+			}
+			return "", fmt.Errorf("providing backup: %w", err)
+		case <-rpc.Context.Done():
+			return "", rpc.Context.Err()
+		case <-time.After(backupQrRetryDelay):
+		}
+	}
+}
+
+// HandleEvent reports the progress of the running transfer from the given event of the given account.
+func (transfer *Transfer) HandleEvent(accId uint32, event deltachat.EventType) {
+	progress, ok := event.(*deltachat.EventTypeImexProgress)
+	if !ok || transfer.Progress == nil {
+		return
+	}
+	transfer.mu.Lock()
+	running := accId != 0 && accId == transfer.dstAccId
+	transfer.mu.Unlock()
+	if running {
+		transfer.Progress(progress.Progress)
+	}
+}
+
+// EventHandler returns an EventHandler reporting the transfer progress, see Bot.Observe().
+func (transfer *Transfer) EventHandler() deltachat.EventHandler {
+	return func(_ *deltachat.Bot, accId uint32, event deltachat.EventType) {
+		transfer.HandleEvent(accId, event)
+	}
+}
@@ -0,0 +1,162 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer simulates the backup transfer calls of deltachat-rpc-server.
+type fakeServer struct {
+	mu        sync.Mutex
+	calls     []string
+	qrCalls   int
+	stopped   chan struct{}
+	retrieved chan struct{}
+	// error returned by get_backup, get_backup blocks until canceled if errBlock
+	getBackupErr error
+}
+
+var errBlock = errors.New("block")
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{stopped: make(chan struct{}), retrieved: make(chan struct{})}
+}
+
+func (server *fakeServer) record(method string) {
+	server.mu.Lock()
+	server.calls = append(server.calls, method)
+	server.mu.Unlock()
+}
+
+func (server *fakeServer) called(method string) bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return slices.Contains(server.calls, method)
+}
+
+func (server *fakeServer) Call(ctx context.Context, method string, params ...any) error {
+	server.record(method)
+	switch method {
+	case "provide_backup":
+		select {
+		case <-server.retrieved:
+			return nil
+		case <-server.stopped:
+			return errors.New("canceled")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case "stop_ongoing_process":
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		select {
+		case <-server.stopped:
+		default:
+			close(server.stopped)
+		}
+	case "get_backup":
+		if server.getBackupErr == errBlock {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if server.getBackupErr != nil {
+			return server.getBackupErr
+		}
+		close(server.retrieved)
+	}
+	return nil
+}
+
+func (server *fakeServer) CallResult(ctx context.Context, result any, method string, params ...any) error {
+	server.record(method)
+	var value any
+	switch method {
+	case "get_backup_qr":
+		server.mu.Lock()
+		server.qrCalls++
+		ready := server.qrCalls > 1
+		server.mu.Unlock()
+		if !ready {
+			return errors.New("no backup being provided")
+		}
+		value = "DCBACKUP:test"
+	case "add_account":
+		value = 2
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+func TestTransferAccount(t *testing.T) {
+	t.Parallel()
+	server := newFakeServer()
+	rpc := &deltachat.Rpc{Context: context.Background(), Transport: server}
+	accId, err := TransferAccount(context.Background(), rpc, 1, rpc)
+	require.Nil(t, err)
+	require.Equal(t, uint32(2), accId)
+	require.True(t, server.called("start_io"))
+	require.False(t, server.called("remove_account"))
+}
+
+func TestTransferAccount_Failure(t *testing.T) {
+	t.Parallel()
+	server := newFakeServer()
+	server.getBackupErr = errors.New("connection refused")
+	rpc := &deltachat.Rpc{Context: context.Background(), Transport: server}
+	_, err := TransferAccount(context.Background(), rpc, 1, rpc)
+	require.ErrorContains(t, err, "receiving backup: connection refused")
+	require.True(t, server.called("stop_ongoing_process"))
+	require.True(t, server.called("remove_account"))
+	require.False(t, server.called("start_io"))
+}
+
+func TestTransferAccount_Cancel(t *testing.T) {
+	t.Parallel()
+	server := newFakeServer()
+	server.getBackupErr = errBlock
+	rpc := &deltachat.Rpc{Context: context.Background(), Transport: server}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err := TransferAccount(ctx, rpc, 1, rpc)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.True(t, server.called("remove_account"))
+	<-server.stopped
+}
+
+func TestTransfer_HandleEvent(t *testing.T) {
+	t.Parallel()
+	var progress []uint16
+	transfer := &Transfer{Progress: func(permille uint16) { progress = append(progress, permille) }}
+	transfer.HandleEvent(2, &deltachat.EventTypeImexProgress{Progress: 10})
+	transfer.dstAccId = 2
+	transfer.HandleEvent(1, &deltachat.EventTypeImexProgress{Progress: 20})
+	transfer.HandleEvent(2, &deltachat.EventTypeImexProgress{Progress: 500})
+	transfer.HandleEvent(2, &deltachat.EventTypeInfo{Msg: "importing"})
+	require.Equal(t, []uint16{500}, progress)
+}
+
+func TestTransferAccount_Online(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(src *deltachat.Rpc, srcAccId uint32) {
+		acfactory.WithRpc(func(dst *deltachat.Rpc) {
+			dstAccId, err := TransferAccount(context.Background(), src, srcAccId, dst)
+			require.Nil(t, err)
+			srcAddr, err := src.GetConfig(srcAccId, "configured_addr")
+			require.Nil(t, err)
+			dstAddr, err := dst.GetConfig(dstAccId, "configured_addr")
+			require.Nil(t, err)
+			require.Equal(t, *srcAddr, *dstAddr)
+		})
+	})
+}
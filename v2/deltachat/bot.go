@@ -21,6 +21,7 @@ type Bot struct {
 	newMsgHandler    NewMsgHandler
 	onUnhandledEvent EventHandler
 	handlerMap       map[string]EventHandler
+	observers        []EventHandler
 	handlerMapMutex  sync.RWMutex
 	ctxMutex         sync.Mutex
 	ctx              context.Context
@@ -46,6 +47,16 @@ func (bot *Bot) OnUnhandledEvent(handler EventHandler) {
 	bot.onUnhandledEvent = handler
 }
 
+// Add an EventHandler called with every event before the EventHandler set via On() or
+// OnUnhandledEvent(), several observers can be added. Observers are meant for helpers that
+// must see all the events without replacing the handlers of the bot, like the EventHandler()
+// of Cache, ChatListModel and AccountManager.
+func (bot *Bot) Observe(handler EventHandler) {
+	bot.handlerMapMutex.Lock()
+	bot.observers = append(bot.observers, handler)
+	bot.handlerMapMutex.Unlock()
+}
+
 // Remove EventHandler for the given event type.
 func (bot *Bot) RemoveEventHandler(event EventType) {
 	bot.handlerMapMutex.Lock()
@@ -117,7 +128,11 @@ func (bot *Bot) Stop() {
 func (bot *Bot) onEvent(accId uint32, event EventType) {
 	bot.handlerMapMutex.RLock()
 	handler, ok := bot.handlerMap[event.GetKind()]
+	observers := bot.observers
 	bot.handlerMapMutex.RUnlock()
+	for _, observer := range observers {
+		observer(bot, accId, event)
+	}
 	if ok {
 		handler(bot, accId, event)
 	} else if bot.onUnhandledEvent != nil {
@@ -112,6 +112,30 @@ func TestBot_OnUnhandledEvent(t *testing.T) {
 	})
 }
 
+func TestBot_Observe(t *testing.T) {
+	t.Parallel()
+	bot := NewBot(&Rpc{})
+	var calls []string
+	bot.On(&EventTypeInfo{}, func(bot *Bot, accId uint32, event EventType) {
+		calls = append(calls, "on")
+	})
+	bot.OnUnhandledEvent(func(bot *Bot, accId uint32, event EventType) {
+		calls = append(calls, "unhandled")
+	})
+	bot.Observe(func(bot *Bot, accId uint32, event EventType) {
+		calls = append(calls, "observer1")
+	})
+	bot.Observe(func(bot *Bot, accId uint32, event EventType) {
+		calls = append(calls, "observer2")
+	})
+
+	bot.onEvent(1, &EventTypeInfo{Msg: "info"})
+	require.Equal(t, []string{"observer1", "observer2", "on"}, calls)
+	calls = nil
+	bot.onEvent(1, &EventTypeWarning{Msg: "warning"})
+	require.Equal(t, []string{"observer1", "observer2", "unhandled"}, calls)
+}
+
 func TestBot_Stop(t *testing.T) {
 	t.Parallel()
 	acfactory.WithOnlineBot(func(bot *Bot, botAcc uint32) {
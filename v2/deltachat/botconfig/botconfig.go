@@ -0,0 +1,471 @@
+// Package botconfig configures bots from a declarative YAML, TOML or JSON file, e.g. to
+// deploy many bots with the same code:
+//
+//	accountsDir: accounts
+//	server: /usr/local/bin/deltachat-rpc-server
+//	qr: dcaccount:chat.example.org
+//	displayName: Echo Bot
+//	avatar: avatar.png
+//	status: I repeat what you say
+//	bot: true
+//	commands: [echo, help]
+//	admins: [admin@example.org]
+//	config:
+//	  delete_server_after: 3600
+//
+// The account is configured with the dcaccount: or dclogin: QR code given as qr, or with
+// the login parameters of Rpc.AddTransport() given as login, e.g. {"addr": ..., "password": ...}.
+// Relative paths are resolved relatively to the configuration file. Numbers and booleans
+// of the config section are converted to configuration strings, booleans to "1" and "0".
+//
+// Config.Apply() only changes the values that differ from the file, so it can be called on
+// every start of the bot, and reports the applied changes:
+//
+//	config, err := botconfig.Load("bot.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	trans := config.NewTransport()
+//	if err := trans.Open(); err != nil {
+//		return err
+//	}
+//	defer trans.Close()
+//	rpc := &deltachat.Rpc{Context: context.Background(), Transport: trans}
+//	accId, changes, err := config.Apply(rpc)
+package botconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a configuration file format.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// Configuration keys storing the values of the file that are not core configuration values.
+const (
+	ConfigCommands     deltachat.ConfigKey = "ui.bot.commands"
+	ConfigAdmins       deltachat.ConfigKey = "ui.bot.admins"
+	ConfigAvatarSha256 deltachat.ConfigKey = "ui.bot.avatar_sha256"
+)
+
+// Config is the configuration of a bot.
+type Config struct {
+	// Accounts directory of deltachat-rpc-server, its default directory if empty.
+	AccountsDir string `json:"accountsDir"`
+	// deltachat-rpc-server binary, the binary in PATH if empty.
+	Server string `json:"server"`
+	// dcaccount: or dclogin: QR code used to configure the account.
+	Qr string `json:"qr"`
+	// Login parameters used to configure the account if Qr is empty.
+	Login       *deltachat.EnteredLoginParam `json:"login"`
+	DisplayName *string                      `json:"displayName"`
+	// Path of the avatar image.
+	Avatar *string `json:"avatar"`
+	Status *string `json:"status"`
+	Bot    *bool   `json:"bot"`
+	// Command modules enabled in the bot, stored in ConfigCommands.
+	Commands []string `json:"commands"`
+	// Addresses of the admins, their contacts are created and the addresses are stored in ConfigAdmins.
+	Admins []string `json:"admins"`
+	// Other configuration values set with Rpc.SetConfig().
+	Config map[string]string `json:"config"`
+}
+
+// Change is a change applied by Config.Apply().
+type Change struct {
+	// Configuration key, or "account", "transport" and "contact" for the changes that are
+	// not configuration values.
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+func (change Change) String() string {
+	switch {
+	case change.Old == "":
+		return fmt.Sprintf("+ %v: %q", change.Key, change.New)
+	case change.New == "":
+		return fmt.Sprintf("- %v: %q", change.Key, change.Old)
+	}
+	return fmt.Sprintf("~ %v: %q -> %q", change.Key, change.Old, change.New)
+}
+
+// Load reads a configuration file, the format is given by the extension of the file:
+// .json, .yaml, .yml or .toml.
+func Load(path string) (*Config, error) {
+	var format Format
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = FormatJSON
+	case ".yaml", ".yml":
+		format = FormatYAML
+	case ".toml":
+		format = FormatTOML
+	default:
+		return nil, fmt.Errorf("unknown configuration file format %q, supported formats: json, yaml, toml", filepath.Ext(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	config.resolvePaths(filepath.Dir(path))
+	return config, nil
+}
+
+// Parse decodes a configuration in the given format. The keys of all the formats are
+// the JSON names of the Config fields, unknown keys are rejected.
+func Parse(data []byte, format Format) (*Config, error) {
+	var values map[string]any
+	var err error
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &values)
+	case FormatTOML:
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unknown configuration format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := configStrings(values); err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(values); err != nil {
+		return nil, err
+	}
+
+	var config Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// configStrings converts the numbers and booleans of the config section to strings.
+func configStrings(values map[string]any) error {
+	// a config section of another type is rejected when decoding the Config
+	config, ok := values["config"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	for key, value := range config {
+		switch value := value.(type) {
+		case string:
+		case bool:
+			config[key] = "0"
+			if value {
+				config[key] = "1"
+			}
+		case int, int64, uint64, float64, json.Number:
+			config[key] = fmt.Sprint(value)
+		default:
+			return fmt.Errorf("config value %v must be a string, a number or a boolean, got %T", key, value)
+		}
+	}
+	return nil
+}
+
+// Validate checks the configuration values.
+func (config *Config) Validate() error {
+	if config.Qr != "" && config.Login != nil {
+		return errors.New("qr and login are exclusive")
+	}
+	for key, value := range config.Config {
+		if err := deltachat.ValidateConfig(deltachat.ConfigKey(key), &value); err != nil {
+			return err
+		}
+	}
+	for _, command := range config.Commands {
+		if command == "" || strings.Contains(command, ",") {
+			return fmt.Errorf("invalid command module name %q", command)
+		}
+	}
+	return nil
+}
+
+// resolvePaths makes the relative paths of the configuration relative to dir.
+func (config *Config) resolvePaths(dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+	config.AccountsDir = resolve(config.AccountsDir)
+	// binaries without directory are looked up in PATH
+	if strings.ContainsRune(config.Server, filepath.Separator) {
+		config.Server = resolve(config.Server)
+	}
+	if config.Avatar != nil && *config.Avatar != "" {
+		avatar := resolve(*config.Avatar)
+		config.Avatar = &avatar
+	}
+}
+
+// NewTransport returns an IOTransport using the server and the accounts directory of the configuration.
+func (config *Config) NewTransport() *deltachat.IOTransport {
+	trans := deltachat.NewIOTransport()
+	if config.Server != "" {
+		trans.Cmd = config.Server
+	}
+	trans.AccountsDir = config.AccountsDir
+	return trans
+}
+
+// CommandEnabled returns true if the given command module is enabled.
+func (config *Config) CommandEnabled(command string) bool {
+	return slices.Contains(config.Commands, command)
+}
+
+// IsAdmin returns true if the given address is the address of an admin.
+func (config *Config) IsAdmin(addr string) bool {
+	return slices.ContainsFunc(config.Admins, func(admin string) bool { return strings.EqualFold(admin, addr) })
+}
+
+// Apply configures the bot account, the selected account or the first account of the Rpc,
+// a new account if there are none. The account is configured with Qr or Login if it is not
+// configured yet, then the values that differ from the configuration are set.
+// The id of the account and the applied changes are returned.
+func (config *Config) Apply(rpc *deltachat.Rpc) (uint32, []Change, error) {
+	var changes []Change
+	accId, created, err := botAccount(rpc)
+	if err != nil {
+		return 0, nil, err
+	}
+	if created {
+		changes = append(changes, Change{Key: "account", New: fmt.Sprint(accId)})
+	}
+
+	change, err := config.applyTransport(rpc, accId)
+	if err != nil {
+		return accId, changes, err
+	}
+	if change != nil {
+		changes = append(changes, *change)
+	}
+
+	values := map[deltachat.ConfigKey]*string{}
+	for key, value := range config.Config {
+		values[deltachat.ConfigKey(key)] = &value
+	}
+	if config.DisplayName != nil {
+		values[deltachat.ConfigDisplayname] = config.DisplayName
+	}
+	if config.Status != nil {
+		values[deltachat.ConfigSelfstatus] = config.Status
+	}
+	if config.Bot != nil {
+		bot := "0"
+		if *config.Bot {
+			bot = "1"
+		}
+		values[deltachat.ConfigBot] = &bot
+	}
+	if config.Commands != nil {
+		commands := strings.Join(config.Commands, ",")
+		values[ConfigCommands] = &commands
+	}
+	if config.Admins != nil {
+		admins := strings.Join(config.Admins, ",")
+		values[ConfigAdmins] = &admins
+	}
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		change, err := setConfig(rpc, accId, key, values[key])
+		if err != nil {
+			return accId, changes, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	if config.Avatar != nil {
+		change, err := config.applyAvatar(rpc, accId)
+		if err != nil {
+			return accId, changes, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	for _, addr := range config.Admins {
+		contactId, err := rpc.LookupContactIdByAddr(accId, addr)
+		if err != nil {
+			return accId, changes, err
+		}
+		if contactId != nil {
+			continue
+		}
+		if _, err := rpc.CreateContact(accId, addr, nil); err != nil {
+			return accId, changes, fmt.Errorf("creating admin contact %v: %w", addr, err)
+		}
+		changes = append(changes, Change{Key: "contact", New: addr})
+	}
+	return accId, changes, nil
+}
+
+// botAccount returns the selected account or the first account, adding an account if there are none.
+func botAccount(rpc *deltachat.Rpc) (uint32, bool, error) {
+	selected, err := rpc.GetSelectedAccountId()
+	if err != nil {
+		return 0, false, err
+	}
+	if selected != nil {
+		return *selected, false, nil
+	}
+	ids, err := rpc.GetAllAccountIds()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(ids) > 0 {
+		return ids[0], false, nil
+	}
+	accId, err := rpc.AddAccount()
+	return accId, true, err
+}
+
+// applyTransport configures the account if it is not configured, or adds the transport of
+// Login if the configured account does not have it.
+func (config *Config) applyTransport(rpc *deltachat.Rpc, accId uint32) (*Change, error) {
+	configured, err := rpc.IsConfigured(accId)
+	if err != nil {
+		return nil, err
+	}
+	if configured {
+		if config.Login == nil {
+			return nil, nil
+		}
+		transports, err := rpc.ListTransports(accId)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(transports, func(param deltachat.EnteredLoginParam) bool {
+			return strings.EqualFold(param.Addr, config.Login.Addr)
+		}) {
+			return nil, nil
+		}
+	}
+
+	switch {
+	case config.Qr != "":
+		err = rpc.AddTransportFromQr(accId, config.Qr)
+	case config.Login != nil:
+		err = rpc.AddTransport(accId, *config.Login)
+	default:
+		return nil, fmt.Errorf("account %v is not configured and the configuration has no qr or login", accId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("configuring account %v: %w", accId, err)
+	}
+	addr, err := rpc.GetConfig(accId, "configured_addr")
+	if err != nil {
+		return nil, err
+	}
+	change := &Change{Key: "transport", New: redactQr(config.Qr)}
+	if addr != nil {
+		change.New = *addr
+	}
+	return change, nil
+}
+
+// redactQr removes the query of a QR code, the query of dclogin: codes holds the password.
+func redactQr(qr string) string {
+	if before, _, found := strings.Cut(qr, "?"); found {
+		return before + "?…"
+	}
+	return qr
+}
+
+// setConfig sets a configuration value if it differs from the current value.
+func setConfig(rpc *deltachat.Rpc, accId uint32, key deltachat.ConfigKey, value *string) (*Change, error) {
+	current, err := rpc.GetConfig(accId, string(key))
+	if err != nil {
+		return nil, err
+	}
+	if deref(current) == deref(value) {
+		return nil, nil
+	}
+	if err := rpc.SetConfig(accId, string(key), value); err != nil {
+		return nil, fmt.Errorf("setting %v: %w", key, err)
+	}
+	return &Change{Key: string(key), Old: deref(current), New: deref(value)}, nil
+}
+
+// applyAvatar sets the avatar if the image changed since it was last set, the avatar is
+// copied to the blob directory and can not be compared directly.
+func (config *Config) applyAvatar(rpc *deltachat.Rpc, accId uint32) (*Change, error) {
+	hash := ""
+	if *config.Avatar != "" {
+		data, err := os.ReadFile(*config.Avatar)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		hash = hex.EncodeToString(sum[:])
+	}
+	current, err := rpc.GetConfig(accId, string(ConfigAvatarSha256))
+	if err != nil {
+		return nil, err
+	}
+	avatar, err := rpc.GetConfig(accId, string(deltachat.ConfigSelfavatar))
+	if err != nil {
+		return nil, err
+	}
+	if deref(current) == hash && (avatar != nil) == (hash != "") {
+		return nil, nil
+	}
+
+	var value *string
+	if hash != "" {
+		value = config.Avatar
+	}
+	if err := rpc.SetConfig(accId, string(deltachat.ConfigSelfavatar), value); err != nil {
+		return nil, fmt.Errorf("setting avatar: %w", err)
+	}
+	if err := rpc.SetConfig(accId, string(ConfigAvatarSha256), &hash); err != nil {
+		return nil, err
+	}
+	return &Change{Key: string(deltachat.ConfigSelfavatar), Old: deref(avatar), New: *config.Avatar}, nil
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,163 @@
+package botconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+const testYAML = `
+accountsDir: accounts
+server: bin/deltachat-rpc-server
+login:
+  addr: bot@example.org
+  password: secret
+  imapPort: 993
+displayName: Echo Bot
+avatar: avatar.png
+bot: true
+commands: [echo, help]
+admins: [admin@example.org]
+config:
+  delete_server_after: 3600
+  mdns_enabled: false
+`
+
+const testTOML = `
+accountsDir = "accounts"
+server = "bin/deltachat-rpc-server"
+displayName = "Echo Bot"
+avatar = "avatar.png"
+bot = true
+commands = ["echo", "help"]
+admins = ["admin@example.org"]
+
+[login]
+addr = "bot@example.org"
+password = "secret"
+imapPort = 993
+
+[config]
+delete_server_after = 3600
+mdns_enabled = false
+`
+
+const testJSON = `{
+  "accountsDir": "accounts",
+  "server": "bin/deltachat-rpc-server",
+  "login": {"addr": "bot@example.org", "password": "secret", "imapPort": 993},
+  "displayName": "Echo Bot",
+  "avatar": "avatar.png",
+  "bot": true,
+  "commands": ["echo", "help"],
+  "admins": ["admin@example.org"],
+  "config": {"delete_server_after": "3600", "mdns_enabled": "0"}
+}`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	expected, err := Parse([]byte(testJSON), FormatJSON)
+	require.Nil(t, err)
+	require.Equal(t, "bot@example.org", expected.Login.Addr)
+	require.Equal(t, uint16(993), *expected.Login.ImapPort)
+	require.True(t, *expected.Bot)
+	require.True(t, expected.CommandEnabled("help"))
+	require.False(t, expected.CommandEnabled("admin"))
+	require.True(t, expected.IsAdmin("Admin@example.org"))
+
+	for format, data := range map[Format]string{FormatYAML: testYAML, FormatTOML: testTOML} {
+		config, err := Parse([]byte(data), format)
+		require.Nil(t, err, format)
+		require.Equal(t, expected, config, format)
+	}
+
+	config, err := Parse([]byte(`{"config": {"delete_server_after": 3600, "mdns_enabled": false}}`), FormatJSON)
+	require.Nil(t, err)
+	require.Equal(t, expected.Config, config.Config)
+	_, err = Parse([]byte("config:\n  delete_server_after: [3600]\n"), FormatYAML)
+	require.ErrorContains(t, err, "config value delete_server_after must be a string, a number or a boolean")
+
+	_, err = Parse([]byte("name: Echo Bot\n"), FormatYAML)
+	require.ErrorContains(t, err, "unknown field")
+	_, err = Parse([]byte(`{"config": {"bot": "yes"}}`), FormatJSON)
+	require.ErrorContains(t, err, `invalid bool value for config key "bot"`)
+	_, err = Parse([]byte(`{"qr": "dcaccount:example.org", "login": {"addr": "bot@example.org"}}`), FormatJSON)
+	require.ErrorContains(t, err, "exclusive")
+	_, err = Parse([]byte(`{"commands": ["a,b"]}`), FormatJSON)
+	require.ErrorContains(t, err, "invalid command module name")
+}
+
+func TestRedactQr(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "dcaccount:chat.example.org", redactQr("dcaccount:chat.example.org"))
+	require.Equal(t, "dclogin:bot@example.org?…", redactQr("dclogin:bot@example.org?p=secret&v=1"))
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.yml")
+	require.Nil(t, os.WriteFile(path, []byte(testYAML), 0o644))
+	config, err := Load(path)
+	require.Nil(t, err)
+	require.Equal(t, filepath.Join(dir, "accounts"), config.AccountsDir)
+	require.Equal(t, filepath.Join(dir, "bin/deltachat-rpc-server"), config.Server)
+	require.Equal(t, filepath.Join(dir, "avatar.png"), *config.Avatar)
+
+	trans := config.NewTransport()
+	require.Equal(t, config.Server, trans.Cmd)
+	require.Equal(t, config.AccountsDir, trans.AccountsDir)
+
+	config.Server = ""
+	require.Equal(t, "deltachat-rpc-server", config.NewTransport().Cmd)
+
+	_, err = Load(filepath.Join(dir, "bot.ini"))
+	require.ErrorContains(t, err, "unknown configuration file format")
+}
+
+func TestChange_String(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, `+ displayname: "Echo Bot"`, Change{Key: "displayname", New: "Echo Bot"}.String())
+	require.Equal(t, `~ bot: "0" -> "1"`, Change{Key: "bot", Old: "0", New: "1"}.String())
+	require.Equal(t, `- selfstatus: "hi"`, Change{Key: "selfstatus", Old: "hi"}.String())
+}
+
+func TestConfig_Apply(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *deltachat.Rpc, accId uint32) {
+		require.Nil(t, rpc.SelectAccount(accId))
+		displayName := "Echo Bot"
+		bot := true
+		avatar := acfactory.TestImage()
+		config := &Config{
+			DisplayName: &displayName,
+			Avatar:      &avatar,
+			Bot:         &bot,
+			Commands:    []string{"echo"},
+			Admins:      []string{"admin@example.org"},
+		}
+		appliedId, changes, err := config.Apply(rpc)
+		require.Nil(t, err)
+		require.Equal(t, accId, appliedId)
+		keys := []string{}
+		for _, change := range changes {
+			keys = append(keys, change.Key)
+		}
+		for _, key := range []string{"displayname", "ui.bot.admins", "ui.bot.commands", "selfavatar", "contact"} {
+			require.Contains(t, keys, key)
+		}
+
+		// applying the same configuration again changes nothing
+		_, changes, err = config.Apply(rpc)
+		require.Nil(t, err)
+		require.Empty(t, changes)
+
+		displayName = "Echo"
+		_, changes, err = config.Apply(rpc)
+		require.Nil(t, err)
+		require.Equal(t, []Change{{Key: "displayname", Old: "Echo Bot", New: "Echo"}}, changes)
+	})
+}
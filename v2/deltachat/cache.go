@@ -0,0 +1,281 @@
+package deltachat
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+)
+
+// DefaultCacheSize is the maximum number of chats, contacts and messages kept by a Cache
+// created with a size of zero.
+const DefaultCacheSize = 1000
+
+// CacheStats are the counters of a Cache.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Entries removed because the cache was full.
+	Evictions uint64
+	// Entries removed because of an event.
+	Invalidations uint64
+}
+
+// Cache memoizes the results of Rpc.GetFullChatById(), Rpc.GetContact() and Rpc.GetMessage().
+//
+// Entries are invalidated by the events passed to HandleEvent(), which must receive all the
+// events of the accounts being cached, see Cache.EventHandler().
+// The returned values are shared between callers and must not be modified.
+type Cache struct {
+	Rpc        *Rpc
+	mu         sync.Mutex
+	generation uint64
+	chats      *lru[FullChat]
+	contacts   *lru[Contact]
+	messages   *lru[Message]
+	stats      CacheStats
+}
+
+// Create a new Cache keeping at most size entries of each kind, DefaultCacheSize if size is zero.
+func NewCache(rpc *Rpc, size int) *Cache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &Cache{Rpc: rpc, chats: newLru[FullChat](size), contacts: newLru[Contact](size), messages: newLru[Message](size)}
+}
+
+// GetFullChatById is the cached version of Rpc.GetFullChatById().
+func (cache *Cache) GetFullChatById(accId uint32, chatId uint32) (FullChat, error) {
+	return cached(cache, cache.chats, cacheKey{accId, chatId}, func() (FullChat, error) {
+		return cache.Rpc.GetFullChatById(accId, chatId)
+	})
+}
+
+// GetContact is the cached version of Rpc.GetContact().
+func (cache *Cache) GetContact(accId uint32, contactId uint32) (Contact, error) {
+	return cached(cache, cache.contacts, cacheKey{accId, contactId}, func() (Contact, error) {
+		return cache.Rpc.GetContact(accId, contactId)
+	})
+}
+
+// GetMessage is the cached version of Rpc.GetMessage().
+func (cache *Cache) GetMessage(accId uint32, msgId uint32) (Message, error) {
+	return cached(cache, cache.messages, cacheKey{accId, msgId}, func() (Message, error) {
+		return cache.Rpc.GetMessage(accId, msgId)
+	})
+}
+
+// Stats returns the current counters of the cache.
+func (cache *Cache) Stats() CacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.stats
+}
+
+// Clear removes all the entries, the counters are kept.
+func (cache *Cache) Clear() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.clear()
+}
+
+// HandleEvent invalidates the entries affected by the given event of the given account.
+func (cache *Cache) HandleEvent(accId uint32, event EventType) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	switch event := event.(type) {
+	case *EventTypeMsgsChanged:
+		switch {
+		case event.MsgId != 0:
+			cache.invalidateMessage(accId, event.MsgId)
+		case event.ChatId != 0:
+			cache.invalidateChatMessages(accId, event.ChatId)
+		default:
+			cache.invalidate(cache.messages.removeFunc(func(key cacheKey, _ Message) bool { return key.accId == accId }))
+		}
+		if event.ChatId != 0 {
+			cache.invalidateChat(accId, event.ChatId)
+		} else {
+			cache.invalidate(cache.chats.removeFunc(func(key cacheKey, _ FullChat) bool { return key.accId == accId }))
+		}
+	case *EventTypeIncomingMsg:
+		cache.invalidateChat(accId, event.ChatId)
+	case *EventTypeMsgsNoticed:
+		cache.invalidateChat(accId, event.ChatId)
+		cache.invalidateChatMessages(accId, event.ChatId)
+	case *EventTypeMsgDelivered:
+		cache.invalidateMessage(accId, event.MsgId)
+	case *EventTypeMsgFailed:
+		cache.invalidateMessage(accId, event.MsgId)
+	case *EventTypeMsgRead:
+		cache.invalidateMessage(accId, event.MsgId)
+	case *EventTypeReactionsChanged:
+		cache.invalidateMessage(accId, event.MsgId)
+	case *EventTypeMsgDeleted:
+		cache.invalidateMessage(accId, event.MsgId)
+		cache.invalidateChat(accId, event.ChatId)
+	case *EventTypeChatModified:
+		cache.invalidateChat(accId, event.ChatId)
+	case *EventTypeChatEphemeralTimerModified:
+		cache.invalidateChat(accId, event.ChatId)
+	case *EventTypeChatDeleted:
+		cache.invalidateChat(accId, event.ChatId)
+		cache.invalidateChatMessages(accId, event.ChatId)
+	case *EventTypeContactsChanged:
+		if event.ContactId == nil {
+			cache.invalidateAccount(accId)
+			break
+		}
+		contactId := *event.ContactId
+		cache.invalidate(cache.contacts.remove(cacheKey{accId, contactId}))
+		cache.invalidate(cache.chats.removeFunc(func(key cacheKey, chat FullChat) bool {
+			return key.accId == accId && slices.Contains(chat.ContactIds, contactId)
+		}))
+		cache.invalidate(cache.messages.removeFunc(func(key cacheKey, msg Message) bool {
+			return key.accId == accId && msg.FromId == contactId
+		}))
+	case *EventTypeAccountsChanged, *EventTypeEventChannelOverflow:
+		// accounts were removed or events were lost
+		cache.clear()
+	default:
+		return
+	}
+	// values being fetched may be outdated
+	cache.generation++
+}
+
+// EventHandler returns an EventHandler invalidating the cache, to be added with Bot.Observe()
+// so that the cache sees every event while the handlers of the bot keep working.
+func (cache *Cache) EventHandler() EventHandler {
+	return func(_ *Bot, accId uint32, event EventType) {
+		cache.HandleEvent(accId, event)
+	}
+}
+
+func (cache *Cache) invalidate(count int) {
+	cache.stats.Invalidations += uint64(count)
+}
+
+func (cache *Cache) invalidateMessage(accId uint32, msgId uint32) {
+	cache.invalidate(cache.messages.remove(cacheKey{accId, msgId}))
+}
+
+func (cache *Cache) invalidateChat(accId uint32, chatId uint32) {
+	cache.invalidate(cache.chats.remove(cacheKey{accId, chatId}))
+}
+
+func (cache *Cache) invalidateChatMessages(accId uint32, chatId uint32) {
+	cache.invalidate(cache.messages.removeFunc(func(key cacheKey, msg Message) bool {
+		return key.accId == accId && msg.ChatId == chatId
+	}))
+}
+
+func (cache *Cache) invalidateAccount(accId uint32) {
+	cache.invalidate(cache.chats.removeFunc(func(key cacheKey, _ FullChat) bool { return key.accId == accId }))
+	cache.invalidate(cache.contacts.removeFunc(func(key cacheKey, _ Contact) bool { return key.accId == accId }))
+	cache.invalidate(cache.messages.removeFunc(func(key cacheKey, _ Message) bool { return key.accId == accId }))
+}
+
+func (cache *Cache) clear() {
+	cache.invalidate(cache.chats.removeFunc(func(cacheKey, FullChat) bool { return true }))
+	cache.invalidate(cache.contacts.removeFunc(func(cacheKey, Contact) bool { return true }))
+	cache.invalidate(cache.messages.removeFunc(func(cacheKey, Message) bool { return true }))
+	cache.generation++
+}
+
+// cached returns the value of key from store, fetching and storing it on a miss.
+// Values fetched while an event invalidated entries are returned but not stored.
+func cached[V any](cache *Cache, store *lru[V], key cacheKey, fetch func() (V, error)) (V, error) {
+	cache.mu.Lock()
+	if value, ok := store.get(key); ok {
+		cache.stats.Hits++
+		cache.mu.Unlock()
+		return value, nil
+	}
+	cache.stats.Misses++
+	generation := cache.generation
+	cache.mu.Unlock()
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.generation == generation && store.put(key, value) {
+		cache.stats.Evictions++
+	}
+	return value, nil
+}
+
+type cacheKey struct {
+	accId uint32
+	id    uint32
+}
+
+// lru is a map with a maximum size evicting the least recently used entries.
+type lru[V any] struct {
+	size  int
+	order *list.List
+	items map[cacheKey]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key   cacheKey
+	value V
+}
+
+func newLru[V any](size int) *lru[V] {
+	return &lru[V]{size: size, order: list.New(), items: make(map[cacheKey]*list.Element)}
+}
+
+func (l *lru[V]) get(key cacheKey) (V, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[V]).value, true
+}
+
+// put stores the value and returns true if an entry was evicted.
+func (l *lru[V]) put(key cacheKey, value V) bool {
+	if elem, ok := l.items[key]; ok {
+		elem.Value.(*lruEntry[V]).value = value
+		l.order.MoveToFront(elem)
+		return false
+	}
+	l.items[key] = l.order.PushFront(&lruEntry[V]{key: key, value: value})
+	if l.order.Len() <= l.size {
+		return false
+	}
+	oldest := l.order.Back()
+	l.order.Remove(oldest)
+	delete(l.items, oldest.Value.(*lruEntry[V]).key)
+	return true
+}
+
+// remove removes the entry with the given key and returns the number of removed entries.
+func (l *lru[V]) remove(key cacheKey) int {
+	elem, ok := l.items[key]
+	if !ok {
+		return 0
+	}
+	l.order.Remove(elem)
+	delete(l.items, key)
+	return 1
+}
+
+// removeFunc removes the entries matching the given function and returns their number.
+func (l *lru[V]) removeFunc(match func(cacheKey, V) bool) int {
+	count := 0
+	for key, elem := range l.items {
+		if match(key, elem.Value.(*lruEntry[V]).value) {
+			l.order.Remove(elem)
+			delete(l.items, key)
+			count++
+		}
+	}
+	return count
+}
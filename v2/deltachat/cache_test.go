@@ -0,0 +1,129 @@
+package deltachat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLru(t *testing.T) {
+	t.Parallel()
+	store := newLru[string](2)
+	require.False(t, store.put(cacheKey{1, 1}, "a"))
+	require.False(t, store.put(cacheKey{1, 2}, "b"))
+	_, ok := store.get(cacheKey{1, 1})
+	require.True(t, ok)
+
+	// {1, 2} is the least recently used entry
+	require.True(t, store.put(cacheKey{1, 3}, "c"))
+	_, ok = store.get(cacheKey{1, 2})
+	require.False(t, ok)
+	value, ok := store.get(cacheKey{1, 1})
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+
+	require.False(t, store.put(cacheKey{1, 3}, "d"))
+	value, _ = store.get(cacheKey{1, 3})
+	require.Equal(t, "d", value)
+
+	require.Equal(t, 1, store.remove(cacheKey{1, 1}))
+	require.Equal(t, 0, store.remove(cacheKey{1, 1}))
+	require.Equal(t, 1, store.removeFunc(func(key cacheKey, _ string) bool { return key.accId == 1 }))
+	require.Equal(t, 0, store.order.Len())
+}
+
+func TestCache_HandleEvent(t *testing.T) {
+	t.Parallel()
+	contactId := uint32(10)
+	seed := func() *Cache {
+		cache := NewCache(nil, 0)
+		for _, accId := range []uint32{1, 2} {
+			cache.chats.put(cacheKey{accId, 20}, FullChat{Id: 20, ContactIds: []uint32{contactId}})
+			cache.chats.put(cacheKey{accId, 21}, FullChat{Id: 21})
+			cache.contacts.put(cacheKey{accId, contactId}, Contact{Id: contactId})
+			cache.messages.put(cacheKey{accId, 30}, Message{Id: 30, ChatId: 20, FromId: contactId})
+			cache.messages.put(cacheKey{accId, 31}, Message{Id: 31, ChatId: 21, FromId: ContactSelf})
+		}
+		return cache
+	}
+	cases := []struct {
+		event    EventType
+		chats    []uint32
+		contacts []uint32
+		messages []uint32
+	}{
+		{&EventTypeMsgsChanged{ChatId: 21, MsgId: 31}, []uint32{20}, []uint32{contactId}, []uint32{30}},
+		{&EventTypeMsgsChanged{ChatId: 20}, []uint32{21}, []uint32{contactId}, []uint32{31}},
+		{&EventTypeMsgsChanged{}, nil, []uint32{contactId}, nil},
+		{&EventTypeIncomingMsg{ChatId: 20, MsgId: 32}, []uint32{21}, []uint32{contactId}, []uint32{30, 31}},
+		{&EventTypeMsgsNoticed{ChatId: 20}, []uint32{21}, []uint32{contactId}, []uint32{31}},
+		{&EventTypeMsgRead{ChatId: 21, MsgId: 31}, []uint32{20, 21}, []uint32{contactId}, []uint32{30}},
+		{&EventTypeReactionsChanged{ChatId: 20, MsgId: 30}, []uint32{20, 21}, []uint32{contactId}, []uint32{31}},
+		{&EventTypeMsgDeleted{ChatId: 20, MsgId: 30}, []uint32{21}, []uint32{contactId}, []uint32{31}},
+		{&EventTypeChatModified{ChatId: 21}, []uint32{20}, []uint32{contactId}, []uint32{30, 31}},
+		{&EventTypeChatDeleted{ChatId: 21}, []uint32{20}, []uint32{contactId}, []uint32{30}},
+		{&EventTypeContactsChanged{ContactId: &contactId}, []uint32{21}, nil, []uint32{31}},
+		{&EventTypeContactsChanged{}, nil, nil, nil},
+		{&EventTypeInfo{Msg: "ignored"}, []uint32{20, 21}, []uint32{contactId}, []uint32{30, 31}},
+	}
+	for _, tc := range cases {
+		cache := seed()
+		cache.HandleEvent(1, tc.event)
+		require.ElementsMatch(t, tc.chats, cachedIds(cache.chats, 1), tc.event.GetKind())
+		require.ElementsMatch(t, tc.contacts, cachedIds(cache.contacts, 1), tc.event.GetKind())
+		require.ElementsMatch(t, tc.messages, cachedIds(cache.messages, 1), tc.event.GetKind())
+		// other accounts are not affected
+		require.Len(t, cachedIds(cache.chats, 2), 2)
+		require.Len(t, cachedIds(cache.contacts, 2), 1)
+		require.Len(t, cachedIds(cache.messages, 2), 2)
+	}
+
+	cache := seed()
+	cache.HandleEvent(1, &EventTypeAccountsChanged{})
+	require.Zero(t, cache.chats.order.Len()+cache.contacts.order.Len()+cache.messages.order.Len())
+	require.Equal(t, uint64(10), cache.Stats().Invalidations)
+}
+
+func cachedIds[V any](store *lru[V], accId uint32) []uint32 {
+	var ids []uint32
+	for key := range store.items {
+		if key.accId == accId {
+			ids = append(ids, key.id)
+		}
+	}
+	return ids
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+	acfactory.WithUnconfiguredAccount(func(rpc *Rpc, accId uint32) {
+		contactId, err := rpc.CreateContact(accId, "null@localhost", strptr("Alice"))
+		require.Nil(t, err)
+
+		cache := NewCache(rpc, 0)
+		contact, err := cache.GetContact(accId, contactId)
+		require.Nil(t, err)
+		require.Equal(t, "Alice", contact.DisplayName)
+		_, err = cache.GetContact(accId, contactId)
+		require.Nil(t, err)
+		require.Equal(t, CacheStats{Hits: 1, Misses: 1}, cache.Stats())
+
+		require.Nil(t, rpc.ChangeContactName(accId, contactId, "Bob"))
+		cache.HandleEvent(accId, &EventTypeContactsChanged{ContactId: &contactId})
+		contact, err = cache.GetContact(accId, contactId)
+		require.Nil(t, err)
+		require.Equal(t, "Bob", contact.DisplayName)
+		require.Equal(t, CacheStats{Hits: 1, Misses: 2, Invalidations: 1}, cache.Stats())
+
+		chatId, err := rpc.CreateGroupChat(accId, "test group", false)
+		require.Nil(t, err)
+		chat, err := cache.GetFullChatById(accId, chatId)
+		require.Nil(t, err)
+		require.Equal(t, "test group", chat.Name)
+		msgId, err := rpc.MiscSendTextMessage(accId, chatId, "hi")
+		require.Nil(t, err)
+		msg, err := cache.GetMessage(accId, msgId)
+		require.Nil(t, err)
+		require.Equal(t, "hi", msg.Text)
+	})
+}
@@ -0,0 +1,327 @@
+package deltachat
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// ListChangeKind is the kind of a change of a ChatListModel or MessageListModel.
+type ListChangeKind int
+
+const (
+	ListInsert ListChangeKind = iota
+	ListUpdate
+	ListRemove
+	ListMove
+)
+
+func (kind ListChangeKind) String() string {
+	switch kind {
+	case ListInsert:
+		return "Insert"
+	case ListUpdate:
+		return "Update"
+	case ListRemove:
+		return "Remove"
+	case ListMove:
+		return "Move"
+	}
+	return "ListChangeKind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+// ChatListChange is a change of a ChatListModel. Applying the changes in the order
+// they are received to a copy of the list keeps the copy equal to ChatListModel.Items().
+type ChatListChange struct {
+	Kind ListChangeKind
+	// Id of the chatlist entry, ChatIdArchivedLink for the archive link.
+	Id uint32
+	// Index of the item after an insert, update or move, or before a remove.
+	Index int
+	// Index of the item before a move.
+	OldIndex int
+	// New value of the item, nil for removes and moves.
+	Item ChatListItemFetchResult
+}
+
+// ChatListModel keeps the chatlist of an account up to date using the ChatlistChanged
+// and ChatlistItemChanged events, and sends the changes to the list on Changes().
+//
+// The events of the account must be passed to HandleEvent(), see ChatListModel.EventHandler().
+// The changes are queued until they are received, so updating the list never blocks.
+type ChatListModel struct {
+	Rpc       *Rpc
+	AccountId uint32
+	// Filters of Rpc.GetChatlistEntries().
+	Flags          *ChatListFlags
+	Query          *string
+	QueryContactId *uint32
+
+	mu      sync.Mutex
+	ids     []uint32
+	items   map[uint32]ChatListItemFetchResult
+	changes *changeQueue[ChatListChange]
+	closed  bool
+}
+
+// Create a new ChatListModel with the given filters, see Rpc.GetChatlistEntries().
+// Reload() must be called to load the list.
+//
+// The model starts a goroutine delivering the Changes(), Close() must be called when the
+// model is not used anymore to stop it and discard the changes that were not received.
+func NewChatListModel(rpc *Rpc, accId uint32, listFlags *ChatListFlags, queryString *string, queryContactId *uint32) *ChatListModel {
+	return &ChatListModel{
+		Rpc:            rpc,
+		AccountId:      accId,
+		Flags:          listFlags,
+		Query:          queryString,
+		QueryContactId: queryContactId,
+		items:          make(map[uint32]ChatListItemFetchResult),
+		changes:        newChangeQueue[ChatListChange](),
+	}
+}
+
+// Changes returns the channel receiving the changes of the list, it is closed by Close().
+func (model *ChatListModel) Changes() <-chan ChatListChange {
+	return model.changes.out
+}
+
+// Items returns a copy of the current list.
+func (model *ChatListModel) Items() []ChatListItemFetchResult {
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	items := make([]ChatListItemFetchResult, len(model.ids))
+	for i, id := range model.ids {
+		items[i] = model.items[id]
+	}
+	return items
+}
+
+// Reload fetches the list again, sending the differences with the current list as changes.
+func (model *ChatListModel) Reload() error {
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	return model.reload()
+}
+
+// SetQuery changes the query string filter and reloads the list.
+func (model *ChatListModel) SetQuery(queryString *string) error {
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	model.Query = queryString
+	return model.reload()
+}
+
+// HandleEvent updates the list for ChatlistChanged and ChatlistItemChanged events of the account.
+func (model *ChatListModel) HandleEvent(accId uint32, event EventType) error {
+	if accId != model.AccountId {
+		return nil
+	}
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	switch event := event.(type) {
+	case *EventTypeChatlistChanged:
+		return model.reload()
+	case *EventTypeChatlistItemChanged:
+		if event.ChatId == nil {
+			return model.refresh(model.ids)
+		}
+		if slices.Contains(model.ids, *event.ChatId) {
+			return model.refresh([]uint32{*event.ChatId})
+		}
+		// the chat may be archived, its fresh messages are counted by the archive link
+		if slices.Contains(model.ids, ChatIdArchivedLink) {
+			return model.refresh([]uint32{ChatIdArchivedLink})
+		}
+	}
+	return nil
+}
+
+// EventHandler returns an EventHandler for Bot.Observe() updating the chatlist.
+// Errors are ignored, the list is updated by the next event.
+func (model *ChatListModel) EventHandler() EventHandler {
+	return func(_ *Bot, accId uint32, event EventType) {
+		model.HandleEvent(accId, event) //nolint:errcheck
+	}
+}
+
+// Close closes the Changes() channel, the list is not updated anymore and the changes
+// that were not received yet are discarded.
+func (model *ChatListModel) Close() {
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	if !model.closed {
+		model.closed = true
+		model.changes.close()
+	}
+}
+
+func (model *ChatListModel) reload() error {
+	if model.closed {
+		return nil
+	}
+	ids, err := model.Rpc.GetChatlistEntries(model.AccountId, model.Flags, model.Query, model.QueryContactId)
+	if err != nil {
+		return err
+	}
+	var missing []uint32
+	for _, id := range ids {
+		// the fresh message counter of the archive link is not covered by events of listed chats
+		if _, ok := model.items[id]; !ok || id == ChatIdArchivedLink {
+			missing = append(missing, id)
+		}
+	}
+	fetched, err := model.fetch(missing)
+	if err != nil {
+		return err
+	}
+	for _, diff := range diffList(model.ids, ids) {
+		change := ChatListChange{Kind: diff.kind, Id: diff.key, Index: diff.index, OldIndex: diff.oldIndex}
+		if change.Kind == ListInsert {
+			change.Item = fetched[change.Id]
+			model.items[change.Id] = change.Item
+			delete(fetched, change.Id)
+		}
+		model.changes.push(change)
+	}
+	for id := range model.items {
+		if !slices.Contains(ids, id) {
+			delete(model.items, id)
+		}
+	}
+	model.ids = ids
+	for id, item := range fetched {
+		model.update(id, item)
+	}
+	return nil
+}
+
+// refresh fetches the given items of the list again and sends updates for the changed ones.
+func (model *ChatListModel) refresh(ids []uint32) error {
+	if model.closed || len(ids) == 0 {
+		return nil
+	}
+	fetched, err := model.fetch(ids)
+	if err != nil {
+		return err
+	}
+	for id, item := range fetched {
+		model.update(id, item)
+	}
+	return nil
+}
+
+// update stores the item of a listed entry, sending an update if it changed.
+func (model *ChatListModel) update(id uint32, item ChatListItemFetchResult) {
+	index := slices.Index(model.ids, id)
+	if index < 0 || reflect.DeepEqual(model.items[id], item) {
+		return
+	}
+	model.items[id] = item
+	model.changes.push(ChatListChange{Kind: ListUpdate, Id: id, Index: index, Item: item})
+}
+
+func (model *ChatListModel) fetch(ids []uint32) (map[uint32]ChatListItemFetchResult, error) {
+	items := make(map[uint32]ChatListItemFetchResult, len(ids))
+	if len(ids) == 0 {
+		return items, nil
+	}
+	results, err := model.Rpc.GetChatlistItemsByEntries(model.AccountId, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		items[id] = results[strconv.FormatUint(uint64(id), 10)]
+	}
+	return items, nil
+}
+
+// changeQueue is an unbounded FIFO queue of list changes delivered on a channel, so that
+// the models can queue changes while holding their lock without waiting for the receiver.
+type changeQueue[T any] struct {
+	out     chan T
+	mu      sync.Mutex
+	pending []T
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+func newChangeQueue[T any]() *changeQueue[T] {
+	queue := &changeQueue[T]{out: make(chan T), wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go queue.run()
+	return queue
+}
+
+func (queue *changeQueue[T]) push(change T) {
+	queue.mu.Lock()
+	queue.pending = append(queue.pending, change)
+	queue.mu.Unlock()
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close discards the pending changes and closes the out channel.
+func (queue *changeQueue[T]) close() {
+	close(queue.done)
+}
+
+func (queue *changeQueue[T]) run() {
+	defer close(queue.out)
+	for {
+		queue.mu.Lock()
+		if len(queue.pending) == 0 {
+			queue.mu.Unlock()
+			select {
+			case <-queue.wake:
+				continue
+			case <-queue.done:
+				return
+			}
+		}
+		change := queue.pending[0]
+		queue.pending = slices.Delete(queue.pending, 0, 1)
+		queue.mu.Unlock()
+
+		select {
+		case queue.out <- change:
+		case <-queue.done:
+			return
+		}
+	}
+}
+
+// listChange is a remove, move or insert returned by diffList.
+type listChange[K comparable] struct {
+	kind     ListChangeKind
+	key      K
+	index    int
+	oldIndex int
+}
+
+// diffList returns the removes, moves and inserts transforming oldKeys into newKeys.
+func diffList[K comparable](oldKeys, newKeys []K) []listChange[K] {
+	var changes []listChange[K]
+	keys := slices.Clone(oldKeys)
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !slices.Contains(newKeys, keys[i]) {
+			changes = append(changes, listChange[K]{kind: ListRemove, key: keys[i], index: i})
+			keys = slices.Delete(keys, i, i+1)
+		}
+	}
+	for i, key := range newKeys {
+		if i < len(keys) && keys[i] == key {
+			continue
+		}
+		if oldIndex := slices.Index(keys, key); oldIndex >= 0 {
+			changes = append(changes, listChange[K]{kind: ListMove, key: key, index: i, oldIndex: oldIndex})
+			keys = slices.Delete(keys, oldIndex, oldIndex+1)
+		} else {
+			changes = append(changes, listChange[K]{kind: ListInsert, key: key, index: i})
+		}
+		keys = slices.Insert(keys, i, key)
+	}
+	return changes
+}
@@ -0,0 +1,163 @@
+package deltachat
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func applyListChanges[K comparable](keys []K, changes []listChange[K]) []K {
+	keys = slices.Clone(keys)
+	for _, change := range changes {
+		switch change.kind {
+		case ListInsert:
+			keys = slices.Insert(keys, change.index, change.key)
+		case ListRemove:
+			keys = slices.Delete(keys, change.index, change.index+1)
+		case ListMove:
+			keys = slices.Delete(keys, change.oldIndex, change.oldIndex+1)
+			keys = slices.Insert(keys, change.index, change.key)
+		}
+	}
+	return keys
+}
+
+func TestDiffList(t *testing.T) {
+	t.Parallel()
+	changes := diffList([]uint32{10, 11, 12}, []uint32{12, 10, 13})
+	require.Equal(t, []listChange[uint32]{
+		{kind: ListRemove, key: 11, index: 1},
+		{kind: ListMove, key: 12, index: 0, oldIndex: 1},
+		{kind: ListInsert, key: 13, index: 2},
+	}, changes)
+	require.Empty(t, diffList([]uint32{10, 11}, []uint32{10, 11}))
+
+	random := rand.New(rand.NewPCG(1, 2))
+	for range 200 {
+		oldIds := random.Perm(10)[:random.IntN(10)]
+		newIds := random.Perm(10)[:random.IntN(10)]
+		old := make([]uint32, len(oldIds))
+		for i, id := range oldIds {
+			old[i] = uint32(id)
+		}
+		expected := make([]uint32, len(newIds))
+		for i, id := range newIds {
+			expected[i] = uint32(id)
+		}
+		actual := applyListChanges(old, diffList(old, expected))
+		// compare as non-nil slices
+		require.Equal(t, expected, append([]uint32{}, actual...))
+	}
+}
+
+func TestChangeQueue(t *testing.T) {
+	t.Parallel()
+	queue := newChangeQueue[int]()
+	// pushing never blocks, even without receiver
+	for i := range 1000 {
+		queue.push(i)
+	}
+	for i := range 1000 {
+		require.Equal(t, i, <-queue.out)
+	}
+	queue.push(1)
+	queue.close()
+	for range queue.out {
+	}
+}
+
+// listTransport answers the calls of the list models with the JSON encoding of the values
+// returned by results.
+type listTransport struct {
+	results func(method string, params []any) any
+}
+
+func (trans *listTransport) Call(ctx context.Context, method string, params ...any) error {
+	return nil
+}
+
+func (trans *listTransport) CallResult(ctx context.Context, result any, method string, params ...any) error {
+	data, err := json.Marshal(trans.results(method, params))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+func TestChatListModel_ReloadWithoutReceiver(t *testing.T) {
+	t.Parallel()
+	var ids []uint32
+	for id := range uint32(250) {
+		ids = append(ids, id+10)
+	}
+	trans := &listTransport{results: func(method string, params []any) any {
+		if method == "get_chatlist_entries" {
+			return ids
+		}
+		items := make(map[string]any)
+		for _, id := range params[1].([]uint32) {
+			items[strconv.Itoa(int(id))] = map[string]any{"kind": "ChatListItem", "id": id, "name": strconv.Itoa(int(id))}
+		}
+		return items
+	}}
+	model := NewChatListModel(&Rpc{Context: context.Background(), Transport: trans}, 1, nil, nil, nil)
+	defer model.Close()
+
+	// the changes are not received while reloading
+	require.Nil(t, model.Reload())
+	require.Len(t, model.Items(), len(ids))
+	require.Nil(t, model.SetQuery(nil))
+	for i := range ids {
+		change := <-model.Changes()
+		require.Equal(t, ListInsert, change.Kind)
+		require.Equal(t, i, change.Index)
+	}
+}
+
+func TestChatListModel(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *Rpc, accId uint32) {
+		model := NewChatListModel(rpc, accId, nil, nil, nil)
+		defer model.Close()
+		require.Nil(t, model.Reload())
+		for range model.Items() {
+			require.Equal(t, ListInsert, (<-model.Changes()).Kind)
+		}
+
+		chatId, err := rpc.CreateGroupChat(accId, "test group", false)
+		require.Nil(t, err)
+		require.Nil(t, model.HandleEvent(accId, &EventTypeChatlistChanged{}))
+		change := <-model.Changes()
+		require.Equal(t, ListInsert, change.Kind)
+		require.Equal(t, chatId, change.Id)
+		require.Equal(t, "test group", change.Item.(*ChatListItemFetchResultChatListItem).Name)
+
+		require.Nil(t, rpc.SetChatName(accId, chatId, "renamed"))
+		require.Nil(t, model.HandleEvent(accId, &EventTypeChatlistItemChanged{ChatId: &chatId}))
+		change = <-model.Changes()
+		require.Equal(t, ListUpdate, change.Kind)
+		require.Equal(t, "renamed", change.Item.(*ChatListItemFetchResultChatListItem).Name)
+
+		// events of other accounts are ignored
+		require.Nil(t, model.HandleEvent(accId+1, &EventTypeChatlistChanged{}))
+		require.Nil(t, rpc.SetChatVisibility(accId, chatId, ChatVisibilityArchived))
+		require.Nil(t, model.HandleEvent(accId, &EventTypeChatlistChanged{}))
+		select {
+		case change = <-model.Changes():
+			require.NotEqual(t, ListInsert, change.Kind)
+		case <-time.After(time.Second):
+			t.Fatal("no change after archiving chat")
+		}
+		for _, item := range model.Items() {
+			if item, ok := item.(*ChatListItemFetchResultChatListItem); ok {
+				require.NotEqual(t, chatId, item.Id)
+			}
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package deltachat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ConfigureErr is returned by Rpc.ConfigureWithProgress() if the configuration failed or was canceled.
+type ConfigureErr struct {
+	AccountId uint32
+	// Last progress in permille and last non-empty progress comment, usually the reason of the failure.
+	Progress int
+	Comment  string
+	// Error returned by the configuration, the context error if it was canceled.
+	Err error
+}
+
+func (err *ConfigureErr) Error() string {
+	if err.Comment == "" {
+		return fmt.Sprintf("configuring account %v: %v", err.AccountId, err.Err)
+	}
+	return fmt.Sprintf("configuring account %v: %v (%v)", err.AccountId, err.Err, err.Comment)
+}
+
+func (err *ConfigureErr) Unwrap() error {
+	return err.Err
+}
+
+// ConfigureTracker reports the progress of a configuration running in Rpc.ConfigureWithProgress()
+// from the ConfigureProgress events that the application passes to HandleEvent(), see
+// ConfigureTracker.EventHandler(). The events of the Rpc are never read by the tracker itself.
+type ConfigureTracker struct {
+	// Called with the progress in permille and the comment of the ConfigureProgress events
+	// of the account being configured.
+	Progress func(permille int, comment string)
+	mu       sync.Mutex
+	// account being configured, 0 if no configuration is running
+	accId    uint32
+	last     int
+	comment  string
+	finished bool
+}
+
+// ConfigureWithProgress configures an account with Rpc.AddTransportFromQr() if source.Qr is set,
+// with Rpc.AddTransport() if source.Login is set, or with Rpc.Configure() otherwise, and reports
+// its progress with the given tracker, which may be nil.
+//
+// If ctx is canceled, the configuration is stopped with Rpc.StopOngoingProcess().
+// Errors are returned as ConfigureErr, with the progress and comment of the last events
+// the tracker received.
+func (rpc *Rpc) ConfigureWithProgress(ctx context.Context, accId uint32, source AccountSource, tracker *ConfigureTracker) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	callRpc := &Rpc{Context: ctx, Transport: rpc.Transport}
+	if tracker == nil {
+		tracker = &ConfigureTracker{}
+	}
+	tracker.start(accId)
+	defer tracker.stop()
+
+	var err error
+	switch {
+	case source.Qr != "":
+		err = callRpc.AddTransportFromQr(accId, source.Qr)
+	case source.Login != nil:
+		err = callRpc.AddTransport(accId, *source.Login)
+	default:
+		err = callRpc.Configure(accId)
+	}
+	if ctx.Err() != nil {
+		stopRpc := &Rpc{Context: context.WithoutCancel(ctx), Transport: rpc.Transport}
+		stopRpc.StopOngoingProcess(accId) //nolint:errcheck
+		err = ctx.Err()
+	}
+
+	if err == nil {
+		// the last event may be received after the configuration returned
+		tracker.report(1000, "")
+		return nil
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return &ConfigureErr{AccountId: accId, Progress: tracker.last, Comment: tracker.comment, Err: err}
+}
+
+func (tracker *ConfigureTracker) start(accId uint32) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.accId = accId
+	tracker.last = 0
+	tracker.comment = ""
+	tracker.finished = false
+}
+
+func (tracker *ConfigureTracker) stop() {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.accId = 0
+}
+
+// HandleEvent reports the progress of the running configuration from the given event of the given account.
+func (tracker *ConfigureTracker) HandleEvent(accId uint32, event EventType) {
+	progress, ok := event.(*EventTypeConfigureProgress)
+	if !ok {
+		return
+	}
+	tracker.mu.Lock()
+	running := accId != 0 && accId == tracker.accId
+	tracker.mu.Unlock()
+	if !running {
+		return
+	}
+	comment := ""
+	if progress.Comment != nil {
+		comment = *progress.Comment
+	}
+	tracker.report(int(progress.Progress), comment)
+}
+
+// EventHandler returns an EventHandler reporting the configuration progress, see Bot.Observe().
+func (tracker *ConfigureTracker) EventHandler() EventHandler {
+	return func(_ *Bot, accId uint32, event EventType) {
+		tracker.HandleEvent(accId, event)
+	}
+}
+
+// report records the given progress and passes it to Progress, the progress is not
+// reported anymore once it reached 0 or 1000.
+func (tracker *ConfigureTracker) report(permille int, comment string) {
+	tracker.mu.Lock()
+	if tracker.finished {
+		tracker.mu.Unlock()
+		return
+	}
+	tracker.last = permille
+	if comment != "" {
+		tracker.comment = comment
+	}
+	tracker.finished = permille == 0 || permille == 1000
+	tracker.mu.Unlock()
+	if tracker.Progress != nil {
+		tracker.Progress(permille, comment)
+	}
+}
@@ -0,0 +1,161 @@
+package deltachat
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// configureTransport simulates a configuration sending ConfigureProgress events to a ConfigureTracker.
+type configureTransport struct {
+	tracker *ConfigureTracker
+	// called by configure, add_transport and add_transport_from_qr
+	configure func(ctx context.Context) error
+	mu        sync.Mutex
+	calls     []string
+}
+
+func (trans *configureTransport) Call(ctx context.Context, method string, params ...any) error {
+	trans.mu.Lock()
+	trans.calls = append(trans.calls, method)
+	trans.mu.Unlock()
+	if method == "stop_ongoing_process" {
+		return nil
+	}
+	return trans.configure(ctx)
+}
+
+func (trans *configureTransport) CallResult(ctx context.Context, result any, method string, params ...any) error {
+	return errors.New("unexpected call of " + method)
+}
+
+func (trans *configureTransport) send(accId uint32, progress uint16, comment string) {
+	event := &EventTypeConfigureProgress{Progress: progress}
+	if comment != "" {
+		event.Comment = &comment
+	}
+	trans.tracker.HandleEvent(accId, event)
+}
+
+func TestRpc_ConfigureWithProgress(t *testing.T) {
+	t.Parallel()
+	var progress []int
+	tracker := &ConfigureTracker{Progress: func(permille int, comment string) {
+		progress = append(progress, permille)
+	}}
+	trans := &configureTransport{tracker: tracker}
+	trans.configure = func(ctx context.Context) error {
+		trans.send(1, 100, "connecting")
+		trans.send(2, 500, "")
+		trans.send(1, 500, "")
+		trans.send(1, 1000, "")
+		return nil
+	}
+	rpc := &Rpc{Context: context.Background(), Transport: trans}
+	require.Nil(t, rpc.ConfigureWithProgress(context.Background(), 1, AccountSource{Qr: "dcaccount:example.org"}, tracker))
+	require.Equal(t, []int{100, 500, 1000}, progress)
+	require.Equal(t, []string{"add_transport_from_qr"}, trans.calls)
+
+	// the last event is reported when the configuration returned before it was received,
+	// events received after the configuration are ignored
+	progress = nil
+	trans.configure = func(ctx context.Context) error {
+		trans.send(1, 100, "connecting")
+		return nil
+	}
+	require.Nil(t, rpc.ConfigureWithProgress(context.Background(), 1, AccountSource{}, tracker))
+	trans.send(1, 1000, "")
+	require.Equal(t, []int{100, 1000}, progress)
+
+	require.Nil(t, rpc.ConfigureWithProgress(context.Background(), 1, AccountSource{}, nil))
+}
+
+func TestRpc_ConfigureWithProgress_Failure(t *testing.T) {
+	t.Parallel()
+	tracker := &ConfigureTracker{}
+	trans := &configureTransport{tracker: tracker}
+	trans.configure = func(ctx context.Context) error {
+		trans.send(1, 300, "logging in")
+		trans.send(1, 0, "authentication failed")
+		return errors.New("configuration failed")
+	}
+	rpc := &Rpc{Context: context.Background(), Transport: trans}
+	err := rpc.ConfigureWithProgress(context.Background(), 1, AccountSource{}, tracker)
+	var configureErr *ConfigureErr
+	require.True(t, errors.As(err, &configureErr))
+	require.Equal(t, 0, configureErr.Progress)
+	require.Equal(t, "authentication failed", configureErr.Comment)
+	require.EqualError(t, err, "configuring account 1: configuration failed (authentication failed)")
+	require.Equal(t, []string{"configure"}, trans.calls)
+
+	// the error is returned without waiting for the last event
+	trans.configure = func(ctx context.Context) error {
+		trans.send(1, 300, "logging in")
+		return errors.New("configuration failed")
+	}
+	start := time.Now()
+	err = rpc.ConfigureWithProgress(context.Background(), 1, AccountSource{}, tracker)
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+	require.True(t, errors.As(err, &configureErr))
+	require.Equal(t, 300, configureErr.Progress)
+	require.Equal(t, "logging in", configureErr.Comment)
+}
+
+func TestRpc_ConfigureWithProgress_Cancel(t *testing.T) {
+	t.Parallel()
+	trans := &configureTransport{}
+	trans.configure = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	rpc := &Rpc{Context: context.Background(), Transport: trans}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := rpc.ConfigureWithProgress(ctx, 1, AccountSource{Login: &EnteredLoginParam{Addr: "bot@example.org"}}, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+	trans.mu.Lock()
+	defer trans.mu.Unlock()
+	require.True(t, slices.Contains(trans.calls, "stop_ongoing_process"))
+}
+
+func TestRpc_ConfigureWithProgress_Online(t *testing.T) {
+	t.Parallel()
+	acfactory.WithUnconfiguredAccount(func(rpc *Rpc, accId uint32) {
+		var mu sync.Mutex
+		var last int
+		tracker := &ConfigureTracker{Progress: func(permille int, _ string) {
+			mu.Lock()
+			last = permille
+			mu.Unlock()
+		}}
+		ctx, stopEvents := context.WithCancel(context.Background())
+		defer stopEvents()
+		go func() {
+			eventsRpc := &Rpc{Context: ctx, Transport: rpc.Transport}
+			for {
+				events, err := eventsRpc.GetNextEventBatch()
+				if err != nil {
+					return
+				}
+				for _, event := range events {
+					tracker.HandleEvent(event.ContextId, event.Event)
+				}
+			}
+		}()
+		err := rpc.ConfigureWithProgress(context.Background(), accId, acfactory.accountSource(), tracker)
+		require.Nil(t, err)
+		mu.Lock()
+		require.Equal(t, 1000, last)
+		mu.Unlock()
+		configured, err := rpc.IsConfigured(accId)
+		require.Nil(t, err)
+		require.True(t, configured)
+	})
+}
@@ -1,32 +1,237 @@
 package deltachat
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	//Special contact ids
 	ContactSelf        uint32 = 1
 	ContactInfo        uint32 = 2
 	ContactDevice      uint32 = 5
 	ContactLastSpecial uint32 = 9
+)
 
-	// Chatlist Flags
-	ChatListFlagArchivedOnly   uint32 = 0x01
-	ChatListFlagNoSpecials     uint32 = 0x02
-	ChatListFlagAddAlldoneHint uint32 = 0x04
-	ChatListFlagForForwarding  uint32 = 0x08
-
-	// Contact Flags
-	ContactFlagVerifiedOnly uint32 = 0x01
-	ContactFlagAddSelf      uint32 = 0x02
-	ContactFlagAddress      uint32 = 0x04
-
-	// Message State
-	MsgStateUndefined    uint32 = 0  // Message just created.
-	MsgStateInFresh      uint32 = 10 // Incoming fresh message.
-	MsgStateInNoticed    uint32 = 13 // Incoming noticed message.
-	MsgStateInSeen       uint32 = 16 // Incoming seen message.
-	MsgStateOutPreparing uint32 = 18 // Outgoing message being prepared.
-	MsgStateOutDraft     uint32 = 19 // Outgoing message drafted.
-	MsgStateOutPending   uint32 = 20 // Outgoing message waiting to be sent.
-	MsgStateOutFailed    uint32 = 24 // Outgoing message failed sending.
-	MsgStateOutDelivered uint32 = 26 // Outgoing message sent.
-	MsgStateOutMdnRcvd   uint32 = 28 // Outgoing message sent and seen by recipients(s).
+const (
+	//Special chat ids
+	ChatIdTrash        uint32 = 3
+	ChatIdArchivedLink uint32 = 6
+	ChatIdAlldoneHint  uint32 = 7
+	ChatIdLastSpecial  uint32 = 9
 )
+
+// ChatListFlags are the flags of Rpc.GetChatlistEntries().
+type ChatListFlags uint32
+
+const (
+	ChatListFlagArchivedOnly   ChatListFlags = 0x01
+	ChatListFlagNoSpecials     ChatListFlags = 0x02
+	ChatListFlagAddAlldoneHint ChatListFlags = 0x04
+	ChatListFlagForForwarding  ChatListFlags = 0x08
+)
+
+var chatListFlagNames = []string{"ArchivedOnly", "NoSpecials", "AddAlldoneHint", "ForForwarding"}
+
+// Has returns true if all the given flags are set.
+func (flags ChatListFlags) Has(other ChatListFlags) bool {
+	return flags&other == other
+}
+
+// With returns the flags with the given flags set.
+func (flags ChatListFlags) With(other ChatListFlags) ChatListFlags {
+	return flags | other
+}
+
+// Without returns the flags with the given flags cleared.
+func (flags ChatListFlags) Without(other ChatListFlags) ChatListFlags {
+	return flags &^ other
+}
+
+func (flags ChatListFlags) String() string {
+	return flagsString(uint32(flags), chatListFlagNames)
+}
+
+func (flags ChatListFlags) MarshalText() ([]byte, error) {
+	return []byte(flags.String()), nil
+}
+
+// MarshalJSON encodes the flags as a number, as expected by the RPC server.
+func (flags ChatListFlags) MarshalJSON() ([]byte, error) {
+	return strconv.AppendUint(nil, uint64(flags), 10), nil
+}
+
+// ContactFlags are the flags of Rpc.GetContacts() and Rpc.GetContactIds().
+type ContactFlags uint32
+
+const (
+	ContactFlagVerifiedOnly ContactFlags = 0x01
+	ContactFlagAddSelf      ContactFlags = 0x02
+	ContactFlagAddress      ContactFlags = 0x04
+)
+
+var contactFlagNames = []string{"VerifiedOnly", "AddSelf", "Address"}
+
+// Has returns true if all the given flags are set.
+func (flags ContactFlags) Has(other ContactFlags) bool {
+	return flags&other == other
+}
+
+// With returns the flags with the given flags set.
+func (flags ContactFlags) With(other ContactFlags) ContactFlags {
+	return flags | other
+}
+
+// Without returns the flags with the given flags cleared.
+func (flags ContactFlags) Without(other ContactFlags) ContactFlags {
+	return flags &^ other
+}
+
+func (flags ContactFlags) String() string {
+	return flagsString(uint32(flags), contactFlagNames)
+}
+
+func (flags ContactFlags) MarshalText() ([]byte, error) {
+	return []byte(flags.String()), nil
+}
+
+// MarshalJSON encodes the flags as a number, as expected by the RPC server.
+func (flags ContactFlags) MarshalJSON() ([]byte, error) {
+	return strconv.AppendUint(nil, uint64(flags), 10), nil
+}
+
+// flagsString returns the names of the set flags separated by "|", names[i] is the name of bit i.
+func flagsString(flags uint32, names []string) string {
+	if flags == 0 {
+		return "0"
+	}
+	var parts []string
+	for i, name := range names {
+		if bit := uint32(1) << i; flags&bit != 0 {
+			parts = append(parts, name)
+			flags &^= bit
+		}
+	}
+	if flags != 0 {
+		parts = append(parts, fmt.Sprintf("%#x", flags))
+	}
+	return strings.Join(parts, "|")
+}
+
+// MsgState is the state of a message.
+type MsgState uint32
+
+const (
+	MsgStateUndefined    MsgState = 0  // Message just created.
+	MsgStateInFresh      MsgState = 10 // Incoming fresh message.
+	MsgStateInNoticed    MsgState = 13 // Incoming noticed message.
+	MsgStateInSeen       MsgState = 16 // Incoming seen message.
+	MsgStateOutPreparing MsgState = 18 // Outgoing message being prepared.
+	MsgStateOutDraft     MsgState = 19 // Outgoing message drafted.
+	MsgStateOutPending   MsgState = 20 // Outgoing message waiting to be sent.
+	MsgStateOutFailed    MsgState = 24 // Outgoing message failed sending.
+	MsgStateOutDelivered MsgState = 26 // Outgoing message sent.
+	MsgStateOutMdnRcvd   MsgState = 28 // Outgoing message sent and seen by recipients(s).
+)
+
+var msgStateNames = map[MsgState]string{
+	MsgStateUndefined:    "Undefined",
+	MsgStateInFresh:      "InFresh",
+	MsgStateInNoticed:    "InNoticed",
+	MsgStateInSeen:       "InSeen",
+	MsgStateOutPreparing: "OutPreparing",
+	MsgStateOutDraft:     "OutDraft",
+	MsgStateOutPending:   "OutPending",
+	MsgStateOutFailed:    "OutFailed",
+	MsgStateOutDelivered: "OutDelivered",
+	MsgStateOutMdnRcvd:   "OutMdnRcvd",
+}
+
+// IsIncoming returns true if the state is the state of an incoming message.
+func (state MsgState) IsIncoming() bool {
+	return state >= MsgStateInFresh && state <= MsgStateInSeen
+}
+
+// IsOutgoing returns true if the state is the state of an outgoing message, including drafts.
+func (state MsgState) IsOutgoing() bool {
+	return state >= MsgStateOutPreparing
+}
+
+// IsFailed returns true if the message could not be sent.
+func (state MsgState) IsFailed() bool {
+	return state == MsgStateOutFailed
+}
+
+func (state MsgState) String() string {
+	if name, ok := msgStateNames[state]; ok {
+		return name
+	}
+	return fmt.Sprintf("MsgState(%d)", uint32(state))
+}
+
+func (state MsgState) MarshalText() ([]byte, error) {
+	return []byte(state.String()), nil
+}
+
+// MarshalJSON encodes the state as a number, as sent by the RPC server.
+func (state MsgState) MarshalJSON() ([]byte, error) {
+	return strconv.AppendUint(nil, uint64(state), 10), nil
+}
+
+// Connectivity is the connectivity returned by Rpc.GetConnectivity().
+//
+// The values are ranges, e.g. every value from 2000 to 2999 means "connecting",
+// compare with the constants using the predicates instead of equality.
+type Connectivity uint32
+
+const (
+	ConnectivityNotConnected Connectivity = 1000
+	ConnectivityConnecting   Connectivity = 2000
+	ConnectivityWorking      Connectivity = 3000
+	ConnectivityConnected    Connectivity = 4000
+)
+
+// IsNotConnected returns true if there is no connection to the server.
+func (conn Connectivity) IsNotConnected() bool {
+	return conn < ConnectivityConnecting
+}
+
+// IsConnecting returns true if the connection to the server is being established.
+func (conn Connectivity) IsConnecting() bool {
+	return conn >= ConnectivityConnecting && conn < ConnectivityWorking
+}
+
+// IsWorking returns true if new messages are being fetched.
+func (conn Connectivity) IsWorking() bool {
+	return conn >= ConnectivityWorking && conn < ConnectivityConnected
+}
+
+// IsConnected returns true if the account is connected and idle.
+func (conn Connectivity) IsConnected() bool {
+	return conn >= ConnectivityConnected
+}
+
+func (conn Connectivity) String() string {
+	switch {
+	case conn < ConnectivityNotConnected:
+		return fmt.Sprintf("Connectivity(%d)", uint32(conn))
+	case conn.IsNotConnected():
+		return "NotConnected"
+	case conn.IsConnecting():
+		return "Connecting"
+	case conn.IsWorking():
+		return "Working"
+	default:
+		return "Connected"
+	}
+}
+
+func (conn Connectivity) MarshalText() ([]byte, error) {
+	return []byte(conn.String()), nil
+}
+
+// MarshalJSON encodes the connectivity as a number, as sent by the RPC server.
+func (conn Connectivity) MarshalJSON() ([]byte, error) {
+	return strconv.AppendUint(nil, uint64(conn), 10), nil
+}
@@ -0,0 +1,89 @@
+package deltachat
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatListFlags(t *testing.T) {
+	t.Parallel()
+	flags := ChatListFlagArchivedOnly.With(ChatListFlagNoSpecials)
+	require.True(t, flags.Has(ChatListFlagArchivedOnly))
+	require.True(t, flags.Has(ChatListFlagArchivedOnly|ChatListFlagNoSpecials))
+	require.False(t, flags.Has(ChatListFlagForForwarding))
+	require.Equal(t, ChatListFlagNoSpecials, flags.Without(ChatListFlagArchivedOnly))
+	require.Equal(t, "ArchivedOnly|NoSpecials", flags.String())
+	require.Equal(t, "0", ChatListFlags(0).String())
+	require.Equal(t, "ForForwarding|0x30", (ChatListFlagForForwarding | 0x30).String())
+
+	data, err := json.Marshal(flags)
+	require.Nil(t, err)
+	require.Equal(t, "3", string(data))
+	text, err := flags.MarshalText()
+	require.Nil(t, err)
+	require.Equal(t, "ArchivedOnly|NoSpecials", string(text))
+}
+
+func TestContactFlags(t *testing.T) {
+	t.Parallel()
+	flags := ContactFlagAddSelf | ContactFlagAddress
+	require.True(t, flags.Has(ContactFlagAddress))
+	require.False(t, flags.Has(ContactFlagVerifiedOnly))
+	require.Equal(t, "AddSelf|Address", flags.String())
+	require.Equal(t, ContactFlagAddress, flags.Without(ContactFlagAddSelf))
+	require.Equal(t, ContactFlagVerifiedOnly|ContactFlagAddSelf|ContactFlagAddress, flags.With(ContactFlagVerifiedOnly))
+
+	data, err := json.Marshal(map[string]ContactFlags{"flags": flags})
+	require.Nil(t, err)
+	require.Equal(t, `{"flags":6}`, string(data))
+}
+
+func TestMsgState(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "OutDelivered", MsgStateOutDelivered.String())
+	require.Equal(t, "MsgState(42)", MsgState(42).String())
+	require.Equal(t, "state: InSeen", fmt.Sprintf("state: %v", MsgStateInSeen))
+
+	require.True(t, MsgStateInFresh.IsIncoming())
+	require.False(t, MsgStateInFresh.IsOutgoing())
+	require.True(t, MsgStateOutPending.IsOutgoing())
+	require.False(t, MsgStateOutPending.IsIncoming())
+	require.True(t, MsgStateOutFailed.IsFailed())
+	require.False(t, MsgStateOutDelivered.IsFailed())
+	require.False(t, MsgStateUndefined.IsIncoming() || MsgStateUndefined.IsOutgoing())
+
+	var msg Message
+	require.Nil(t, json.Unmarshal([]byte(`{"id":12,"state":26}`), &msg))
+	require.Equal(t, MsgStateOutDelivered, msg.State)
+	data, err := json.Marshal(msg)
+	require.Nil(t, err)
+	require.Contains(t, string(data), `"state":26`)
+	text, err := msg.State.MarshalText()
+	require.Nil(t, err)
+	require.Equal(t, "OutDelivered", string(text))
+}
+
+func TestConnectivity(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "NotConnected", Connectivity(1500).String())
+	require.Equal(t, "Connecting", ConnectivityConnecting.String())
+	require.Equal(t, "Working", Connectivity(3999).String())
+	require.Equal(t, "Connected", Connectivity(5000).String())
+	require.Equal(t, "Connectivity(0)", Connectivity(0).String())
+
+	require.True(t, Connectivity(2500).IsConnecting())
+	require.False(t, Connectivity(2500).IsConnected())
+	require.True(t, ConnectivityWorking.IsWorking())
+	require.True(t, ConnectivityNotConnected.IsNotConnected())
+	require.True(t, ConnectivityConnected.IsConnected())
+
+	var conn Connectivity
+	require.Nil(t, json.Unmarshal([]byte(`4000`), &conn))
+	require.Equal(t, ConnectivityConnected, conn)
+	data, err := json.Marshal(conn)
+	require.Nil(t, err)
+	require.Equal(t, "4000", string(data))
+}
@@ -0,0 +1,267 @@
+// Package exporter exports the messages of a chat to archive it, as a JSON dump,
+// a self-contained HTML transcript or an RFC 4155 mbox file.
+//
+// An Exporter loads a Transcript of the chat, the Transcript is then written
+// in the desired format:
+//
+//	transcript, err := exporter.New(rpc, accId).Load(chatId)
+//	if err != nil {
+//		return err
+//	}
+//	return transcript.WriteHTML(file)
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+// Format is an export format.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatHTML Format = "html"
+	FormatMbox Format = "mbox"
+)
+
+// Formats are the supported export formats.
+var Formats = []Format{FormatJSON, FormatHTML, FormatMbox}
+
+// Transcript is the exported content of a chat.
+type Transcript struct {
+	AccountId  uint32    `json:"accountId"`
+	ChatId     uint32    `json:"chatId"`
+	ChatName   string    `json:"chatName"`
+	ChatType   string    `json:"chatType"`
+	ExportedAt time.Time `json:"exportedAt"`
+	// Directory of the copied attachments, empty if attachments were not copied.
+	AttachmentsDir string    `json:"attachmentsDir,omitempty"`
+	Messages       []Message `json:"messages"`
+}
+
+// Message is an exported message.
+type Message struct {
+	Id        uint32    `json:"id"`
+	Sender    Sender    `json:"sender"`
+	Timestamp time.Time `json:"timestamp"`
+	Subject   string    `json:"subject,omitempty"`
+	Text      string    `json:"text"`
+	IsInfo    bool      `json:"isInfo,omitempty"`
+	IsEdited  bool      `json:"isEdited,omitempty"`
+	// State of outgoing messages, e.g. "OutDelivered".
+	State      string      `json:"state"`
+	Quote      *Quote      `json:"quote,omitempty"`
+	Reactions  []Reaction  `json:"reactions,omitempty"`
+	Attachment *Attachment `json:"attachment,omitempty"`
+	// Reason why the message could not be loaded, only the id is exported in this case.
+	LoadingError string `json:"loadingError,omitempty"`
+}
+
+// Sender is the author of a message.
+type Sender struct {
+	ContactId uint32 `json:"contactId"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+}
+
+// Quote is the message quoted by a reply.
+type Quote struct {
+	// Id of the quoted message, 0 if only the text is known.
+	MessageId uint32 `json:"messageId,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Text      string `json:"text"`
+}
+
+// Reaction is an emoji reaction to a message and the number of contacts that sent it.
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	Count uint   `json:"count"`
+}
+
+// Attachment is the file of a message.
+type Attachment struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType,omitempty"`
+	Size     uint64 `json:"size"`
+	// Path of the copied file relative to Transcript.AttachmentsDir, empty if the file was not copied.
+	Path string `json:"path,omitempty"`
+}
+
+// Exporter loads the transcripts of the chats of an account.
+type Exporter struct {
+	Rpc       *deltachat.Rpc
+	AccountId uint32
+	// Directory where the attachments are copied with Rpc.SaveMsgFile(),
+	// attachments are not copied if empty.
+	AttachmentsDir string
+}
+
+// Create a new Exporter for the given account.
+func New(rpc *deltachat.Rpc, accId uint32) *Exporter {
+	return &Exporter{Rpc: rpc, AccountId: accId}
+}
+
+// Load returns the transcript of a chat, copying the attachments if AttachmentsDir is set.
+//
+// Messages that fail to load don't stop the export, they are recorded in the transcript
+// with their id and Message.LoadingError.
+func (exp *Exporter) Load(chatId uint32) (*Transcript, error) {
+	chat, err := exp.Rpc.GetBasicChatInfo(exp.AccountId, chatId)
+	if err != nil {
+		return nil, err
+	}
+	transcript := &Transcript{
+		AccountId:  exp.AccountId,
+		ChatId:     chatId,
+		ChatName:   chat.Name,
+		ChatType:   string(chat.ChatType),
+		ExportedAt: time.Now().UTC(),
+		Messages:   []Message{},
+	}
+	if exp.AttachmentsDir != "" {
+		if err := os.MkdirAll(exp.AttachmentsDir, 0o755); err != nil {
+			return nil, err
+		}
+		transcript.AttachmentsDir = exp.AttachmentsDir
+	}
+
+	pager := deltachat.NewPager(exp.Rpc)
+	for msg, err := range pager.Messages(exp.AccountId, chatId) {
+		var loadingErr *deltachat.ItemLoadingErr
+		if errors.As(err, &loadingErr) {
+			transcript.Messages = append(transcript.Messages, Message{Id: loadingErr.Id, LoadingError: loadingErr.Reason})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		exported := convertMessage(msg)
+		if exported.Attachment != nil && exp.AttachmentsDir != "" {
+			if exported.Attachment.Path, err = exp.saveFile(msg.Id, exported.Attachment.Name); err != nil {
+				return nil, err
+			}
+		}
+		transcript.Messages = append(transcript.Messages, exported)
+	}
+	return transcript, nil
+}
+
+// saveFile copies the file of a message to AttachmentsDir, the returned path is
+// the message id followed by the file name, e.g. "12-report.pdf".
+func (exp *Exporter) saveFile(msgId uint32, name string) (string, error) {
+	path := strconv.FormatUint(uint64(msgId), 10) + "-" + name
+	target := filepath.Join(exp.AttachmentsDir, path)
+	// Rpc.SaveMsgFile() fails if the file exists, e.g. from a previous export
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	if err := exp.Rpc.SaveMsgFile(exp.AccountId, msgId, absTarget); err != nil {
+		return "", fmt.Errorf("copying file of message %v: %w", msgId, err)
+	}
+	return path, nil
+}
+
+func convertMessage(msg deltachat.Message) Message {
+	exported := Message{
+		Id: msg.Id,
+		Sender: Sender{
+			ContactId: msg.FromId,
+			Name:      msg.Sender.DisplayName,
+			Address:   msg.Sender.Address,
+		},
+		Timestamp: time.Unix(msg.Timestamp, 0).UTC(),
+		Subject:   msg.Subject,
+		Text:      msg.Text,
+		IsInfo:    msg.IsInfo,
+		IsEdited:  msg.IsEdited,
+		State:     msg.State.String(),
+	}
+	if msg.OverrideSenderName != nil {
+		exported.Sender.Name = *msg.OverrideSenderName
+	}
+	if msg.Quote != nil {
+		switch quote := (*msg.Quote).(type) {
+		case *deltachat.MessageQuoteWithMessage:
+			exported.Quote = &Quote{MessageId: quote.MessageId, Author: quote.AuthorDisplayName, Text: quote.Text}
+		case *deltachat.MessageQuoteJustText:
+			exported.Quote = &Quote{Text: quote.Text}
+		}
+	}
+	if msg.Reactions != nil {
+		for _, reaction := range msg.Reactions.Reactions {
+			exported.Reactions = append(exported.Reactions, Reaction{Emoji: reaction.Emoji, Count: reaction.Count})
+		}
+	}
+	if msg.File != nil && *msg.File != "" {
+		attachment := &Attachment{Name: "file", Size: msg.FileBytes}
+		if msg.FileName != nil && *msg.FileName != "" {
+			attachment.Name = safeFileName(*msg.FileName)
+		}
+		if msg.FileMime != nil {
+			attachment.MimeType = *msg.FileMime
+		}
+		exported.Attachment = attachment
+	}
+	return exported
+}
+
+// safeFileName returns the base name of name without path separators.
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+// WriteJSON writes the transcript as indented JSON.
+func (transcript *Transcript) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(transcript)
+}
+
+// Write writes the transcript in the given format.
+func (transcript *Transcript) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatJSON:
+		return transcript.WriteJSON(w)
+	case FormatHTML:
+		return transcript.WriteHTML(w)
+	case FormatMbox:
+		return transcript.WriteMbox(w)
+	}
+	return unknownFormatErr(string(format))
+}
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(name))
+	if !slices.Contains(Formats, format) {
+		return "", unknownFormatErr(name)
+	}
+	return format, nil
+}
+
+func unknownFormatErr(name string) error {
+	return fmt.Errorf("unknown export format %q, supported formats: %v", name, Formats)
+}
@@ -0,0 +1,209 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+func testTranscript(t *testing.T) *Transcript {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "2-image.png"), []byte("png data"), 0o644))
+	timestamp := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	return &Transcript{
+		AccountId:      1,
+		ChatId:         10,
+		ChatName:       "Support <team>",
+		ChatType:       "Group",
+		ExportedAt:     timestamp,
+		AttachmentsDir: dir,
+		Messages: []Message{
+			{Id: 1, Sender: Sender{ContactId: 11, Name: "Alice", Address: "alice@example.org"}, Timestamp: timestamp, Text: "Hello\nFrom the team", State: "InSeen"},
+			{
+				Id:         2,
+				Sender:     Sender{ContactId: 1, Name: "Bøb", Address: "bob@example.org"},
+				Timestamp:  timestamp.Add(time.Minute),
+				Text:       "<b>see image</b>",
+				IsEdited:   true,
+				State:      "OutDelivered",
+				Quote:      &Quote{MessageId: 1, Author: "Alice", Text: "Hello"},
+				Reactions:  []Reaction{{Emoji: "👍", Count: 2}},
+				Attachment: &Attachment{Name: "image.png", MimeType: "image/png", Size: 8, Path: "2-image.png"},
+			},
+			{Id: 3, Timestamp: timestamp, Text: "Member added", IsInfo: true},
+			{Id: 4, LoadingError: "database error"},
+		},
+	}
+}
+
+func TestTranscript_WriteJSON(t *testing.T) {
+	t.Parallel()
+	transcript := testTranscript(t)
+	var buf bytes.Buffer
+	require.Nil(t, transcript.Write(&buf, FormatJSON))
+	var decoded Transcript
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, *transcript, decoded)
+}
+
+func TestTranscript_WriteHTML(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.Nil(t, testTranscript(t).Write(&buf, FormatHTML))
+	html := buf.String()
+	require.Contains(t, html, "<title>Support &lt;team&gt;</title>")
+	require.Contains(t, html, "Hello<br>From the team")
+	require.Contains(t, html, "&lt;b&gt;see image&lt;/b&gt;")
+	require.Contains(t, html, `<a href="#msg-1">Alice</a>: Hello`)
+	require.Contains(t, html, `<img src="data:image/png;base64,cG5nIGRhdGE=" alt="image.png">`)
+	require.Contains(t, html, "(edited)")
+	require.Contains(t, html, "<span>👍 2</span>")
+	require.Contains(t, html, `<div class="msg info" id="msg-3">Member added</div>`)
+	require.Contains(t, html, `<div class="msg info" id="msg-4">message 4 could not be loaded: database error</div>`)
+}
+
+func TestTranscript_WriteMbox(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.Nil(t, testTranscript(t).Write(&buf, FormatMbox))
+	mbox := buf.String()
+	require.True(t, strings.HasPrefix(mbox, "From alice@example.org Wed May  1 10:30:00 2024\n"))
+	require.Contains(t, mbox, "\n>From the team\n")
+	require.Contains(t, mbox, "\nFrom MAILER-DAEMON Wed May  1 10:30:00 2024\n")
+
+	// each message can be parsed after splitting and unquoting, message 4 is left out
+	messages := strings.Split(mbox, "\nFrom ")
+	require.Len(t, messages, 3)
+	_, raw, _ := strings.Cut(messages[1], "\n")
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	require.Nil(t, err)
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	require.Nil(t, err)
+	require.Equal(t, "Bøb", from.Name)
+	require.Equal(t, "<1.10.1@export.delta.chat>", msg.Header.Get("In-Reply-To"))
+	require.Contains(t, msg.Header.Get("Content-Type"), "multipart/mixed")
+	body, err := io.ReadAll(msg.Body)
+	require.Nil(t, err)
+	require.Contains(t, string(body), "> Hello\n")
+	require.Contains(t, string(body), "Reactions: 👍 2")
+	require.Contains(t, string(body), "cG5nIGRhdGE=")
+}
+
+func TestFormatAddress(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"Alice", "Doe, John", `a"b`, "Bøb, Jr."} {
+		address, err := mail.ParseAddress(formatAddress(name, "alice@example.org"))
+		require.Nil(t, err, name)
+		require.Equal(t, name, address.Name)
+		require.Equal(t, "alice@example.org", address.Address)
+	}
+	require.Equal(t, "<alice@example.org>", formatAddress("", "alice@example.org"))
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+	format, err := ParseFormat("HTML")
+	require.Nil(t, err)
+	require.Equal(t, FormatHTML, format)
+	_, err = ParseFormat("pdf")
+	require.ErrorContains(t, err, "unknown export format")
+	require.ErrorContains(t, (&Transcript{}).Write(io.Discard, "pdf"), "unknown export format")
+}
+
+func TestConvertMessage(t *testing.T) {
+	t.Parallel()
+	name := "../report.pdf"
+	quote := deltachat.MessageQuote(&deltachat.MessageQuoteJustText{Text: "quoted"})
+	msg := convertMessage(deltachat.Message{
+		Id:        5,
+		FromId:    11,
+		Sender:    deltachat.Contact{DisplayName: "Alice", Address: "alice@example.org"},
+		Timestamp: 1714559400,
+		Text:      "report",
+		State:     deltachat.MsgStateInSeen,
+		Quote:     &quote,
+		Reactions: &deltachat.Reactions{Reactions: []deltachat.Reaction{{Emoji: "❤️", Count: 1}}},
+		File:      &name,
+		FileName:  &name,
+		FileBytes: 100,
+	})
+	require.Equal(t, Sender{ContactId: 11, Name: "Alice", Address: "alice@example.org"}, msg.Sender)
+	require.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC), msg.Timestamp)
+	require.Equal(t, "InSeen", msg.State)
+	require.Equal(t, &Quote{Text: "quoted"}, msg.Quote)
+	require.Equal(t, []Reaction{{Emoji: "❤️", Count: 1}}, msg.Reactions)
+	require.Equal(t, &Attachment{Name: ".._report.pdf", Size: 100}, msg.Attachment)
+}
+
+// fakeTransport answers the calls with the raw JSON results of the methods.
+type fakeTransport struct {
+	results map[string]string
+}
+
+func (trans *fakeTransport) Call(ctx context.Context, method string, params ...any) error {
+	return nil
+}
+
+func (trans *fakeTransport) CallResult(ctx context.Context, result any, method string, params ...any) error {
+	data, ok := trans.results[method]
+	if !ok {
+		return fmt.Errorf("unexpected call of %v", method)
+	}
+	return json.Unmarshal([]byte(data), result)
+}
+
+func TestExporter_LoadLoadingError(t *testing.T) {
+	t.Parallel()
+	trans := &fakeTransport{results: map[string]string{
+		"get_basic_chat_info": `{"id": 10, "name": "support", "chatType": "Group"}`,
+		"get_message_ids":     `[1, 2]`,
+		"get_messages": `{
+			"1": {"kind": "loadingError", "error": "database error"},
+			"2": {"kind": "message", "id": 2, "fromId": 11, "text": "hello", "sender": {"displayName": "Alice", "address": "alice@example.org"}}
+		}`,
+	}}
+	transcript, err := New(&deltachat.Rpc{Context: context.Background(), Transport: trans}, 1).Load(10)
+	require.Nil(t, err)
+	require.Len(t, transcript.Messages, 2)
+	require.Equal(t, Message{Id: 1, LoadingError: "database error"}, transcript.Messages[0])
+	require.Equal(t, "hello", transcript.Messages[1].Text)
+
+	delete(trans.results, "get_messages")
+	_, err = New(&deltachat.Rpc{Context: context.Background(), Transport: trans}, 1).Load(10)
+	require.ErrorContains(t, err, "unexpected call of get_messages")
+}
+
+func TestExporter_Load(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *deltachat.Rpc, accId uint32) {
+		chatId, err := rpc.CreateGroupChat(accId, "support", false)
+		require.Nil(t, err)
+		_, err = rpc.MiscSendTextMessage(accId, chatId, "hello")
+		require.Nil(t, err)
+		_, err = rpc.SendReader(accId, chatId, strings.NewReader("a,b\n1,2\n"), "report.csv", nil)
+		require.Nil(t, err)
+
+		exp := New(rpc, accId)
+		exp.AttachmentsDir = acfactory.MkdirTemp()
+		transcript, err := exp.Load(chatId)
+		require.Nil(t, err)
+		require.Equal(t, "support", transcript.ChatName)
+		last := transcript.Messages[len(transcript.Messages)-1]
+		require.Equal(t, "report.csv", last.Attachment.Name)
+		data, err := os.ReadFile(filepath.Join(exp.AttachmentsDir, last.Attachment.Path))
+		require.Nil(t, err)
+		require.Equal(t, "a,b\n1,2\n", string(data))
+		require.Equal(t, "hello", transcript.Messages[len(transcript.Messages)-2].Text)
+	})
+}
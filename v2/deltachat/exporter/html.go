@@ -0,0 +1,94 @@
+package exporter
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxInlineImageSize is the size limit of the images embedded in HTML transcripts.
+const maxInlineImageSize = 10 * 1024 * 1024
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"lines": func(text string) []string { return strings.Split(text, "\n") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.ChatName}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: auto; padding: 1em; background: #f4f4f4; }
+.msg { background: #fff; border-radius: 0.5em; padding: 0.5em 1em; margin: 0.5em 0; }
+.info { text-align: center; color: #666; font-style: italic; }
+.meta { color: #666; font-size: 0.8em; }
+.sender { font-weight: bold; }
+.quote { border-left: 3px solid #999; padding-left: 0.5em; color: #555; margin: 0.3em 0; }
+.reactions span { background: #eee; border-radius: 1em; padding: 0 0.5em; margin-right: 0.3em; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.ChatName}}</h1>
+<p class="meta">{{.ChatType}} chat #{{.ChatId}} of account #{{.AccountId}}, exported at {{.ExportedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+{{range .Messages}}{{if .LoadingError}}<div class="msg info" id="msg-{{.Id}}">message {{.Id}} could not be loaded: {{.LoadingError}}</div>
+{{else if .IsInfo}}<div class="msg info" id="msg-{{.Id}}">{{.Text}}</div>
+{{else}}<div class="msg" id="msg-{{.Id}}">
+<div class="meta"><span class="sender">{{.Sender.Name}}</span> &lt;{{.Sender.Address}}&gt; {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}{{if .IsEdited}} (edited){{end}}</div>
+{{with .Quote}}<div class="quote">{{if .MessageId}}<a href="#msg-{{.MessageId}}">{{.Author}}</a>: {{end}}{{.Text}}</div>
+{{end}}{{with .Attachment}}<div class="attachment">{{if .Image}}<img src="{{.Image}}" alt="{{.Name}}">{{else if .Path}}<a href="{{.Path}}">{{.Name}}</a>{{else}}{{.Name}}{{end}} ({{.Size}} bytes)</div>
+{{end}}<div class="text">{{range $i, $line := lines .Text}}{{if $i}}<br>{{end}}{{$line}}{{end}}</div>
+{{with .Reactions}}<div class="reactions">{{range .}}<span>{{.Emoji}} {{.Count}}</span>{{end}}</div>
+{{end}}</div>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// htmlMessage is a Message with the attachment prepared for the HTML template.
+type htmlMessage struct {
+	Message
+	Attachment *htmlAttachment
+}
+
+type htmlAttachment struct {
+	Attachment
+	// Path of the copied file relative to the HTML file.
+	Path string
+	// Image embedded as data URL.
+	Image template.URL
+}
+
+// WriteHTML writes the transcript as a self-contained HTML page. Copied images are embedded,
+// other copied attachments are linked with their path in AttachmentsDir, which is resolved
+// relatively to the HTML file if AttachmentsDir is relative.
+func (transcript *Transcript) WriteHTML(w io.Writer) error {
+	messages := make([]htmlMessage, len(transcript.Messages))
+	for i, msg := range transcript.Messages {
+		messages[i] = htmlMessage{Message: msg}
+		if msg.Attachment != nil {
+			messages[i].Attachment = transcript.htmlAttachment(*msg.Attachment)
+		}
+	}
+	return htmlTemplate.Execute(w, struct {
+		*Transcript
+		Messages []htmlMessage
+	}{transcript, messages})
+}
+
+func (transcript *Transcript) htmlAttachment(attachment Attachment) *htmlAttachment {
+	result := &htmlAttachment{Attachment: attachment}
+	if attachment.Path == "" {
+		return result
+	}
+	path := filepath.Join(transcript.AttachmentsDir, attachment.Path)
+	result.Path = filepath.ToSlash(path)
+	if !strings.HasPrefix(attachment.MimeType, "image/") || attachment.Size > maxInlineImageSize {
+		return result
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		result.Image = template.URL("data:" + attachment.MimeType + ";base64," + base64.StdEncoding.EncodeToString(data))
+	}
+	return result
+}
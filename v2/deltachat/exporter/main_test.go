@@ -0,0 +1,20 @@
+package exporter
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+var acfactory *deltachat.AcFactory
+
+func TestMain(m *testing.M) {
+	acfactory = &deltachat.AcFactory{
+		Debug:       os.Getenv("TEST_DEBUG") == "1",
+		LocalServer: os.Getenv("TEST_LOCAL_SERVER") == "1",
+	}
+	acfactory.TearUp()
+	defer acfactory.TearDown()
+	m.Run()
+}
@@ -0,0 +1,159 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// mboxFromLine matches the body lines that must be quoted in mboxrd format.
+var mboxFromLine = regexp.MustCompile(`(?m)^(>*From )`)
+
+// WriteMbox writes the transcript in mboxrd format (RFC 4155), one message per chat message.
+// Copied attachments are included as MIME parts, quotes and reactions are added to the text.
+// Messages that could not be loaded are left out.
+func (transcript *Transcript) WriteMbox(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	for _, msg := range transcript.Messages {
+		if msg.LoadingError != "" {
+			continue
+		}
+		data, err := transcript.mboxMessage(msg)
+		if err != nil {
+			return err
+		}
+		sender := msg.Sender.Address
+		if sender == "" || strings.ContainsAny(sender, " \t") {
+			sender = "MAILER-DAEMON"
+		}
+		// asctime format required by RFC 4155
+		fmt.Fprintf(buf, "From %v %v\n", sender, msg.Timestamp.UTC().Format(time.ANSIC))
+		buf.Write(mboxFromLine.ReplaceAll(data, []byte(">$1"))) //nolint:errcheck
+		buf.WriteString("\n")                                   //nolint:errcheck
+	}
+	return buf.Flush()
+}
+
+// mboxMessage returns the RFC 5322 message of a chat message with LF line endings.
+func (transcript *Transcript) mboxMessage(msg Message) ([]byte, error) {
+	var body strings.Builder
+	if msg.Quote != nil {
+		if msg.Quote.Author != "" {
+			body.WriteString(msg.Quote.Author + " wrote:\n")
+		}
+		for line := range strings.SplitSeq(msg.Quote.Text, "\n") {
+			body.WriteString("> " + line + "\n")
+		}
+		body.WriteString("\n")
+	}
+	body.WriteString(msg.Text + "\n")
+	if msg.IsEdited {
+		body.WriteString("\n(edited)\n")
+	}
+	if len(msg.Reactions) > 0 {
+		var reactions []string
+		for _, reaction := range msg.Reactions {
+			reactions = append(reactions, fmt.Sprintf("%v %v", reaction.Emoji, reaction.Count))
+		}
+		body.WriteString("\nReactions: " + strings.Join(reactions, ", ") + "\n")
+	}
+
+	subject := msg.Subject
+	if subject == "" {
+		subject = transcript.ChatName
+	}
+	var out bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", formatAddress(msg.Sender.Name, msg.Sender.Address))
+	header.Set("Date", msg.Timestamp.Format(time.RFC1123Z))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header.Set("Message-ID", transcript.messageId(msg.Id))
+	if msg.Quote != nil && msg.Quote.MessageId != 0 {
+		header.Set("In-Reply-To", transcript.messageId(msg.Quote.MessageId))
+	}
+	header.Set("MIME-Version", "1.0")
+
+	var attachment []byte
+	if msg.Attachment != nil && msg.Attachment.Path != "" {
+		data, err := os.ReadFile(filepath.Join(transcript.AttachmentsDir, msg.Attachment.Path))
+		if err != nil {
+			return nil, err
+		}
+		attachment = data
+	}
+	if attachment == nil {
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "8bit")
+		writeHeader(&out, header)
+		out.WriteString(body.String())
+		return out.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&out)
+	header.Set("Content-Type", "multipart/mixed; boundary="+parts.Boundary())
+	writeHeader(&out, header)
+	text, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	io.WriteString(text, body.String()) //nolint:errcheck
+	mimeType := msg.Attachment.MimeType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	file, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mimeType},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": msg.Attachment.Name})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(attachment)
+	for len(encoded) > 76 {
+		io.WriteString(file, encoded[:76]+"\r\n") //nolint:errcheck
+		encoded = encoded[76:]
+	}
+	io.WriteString(file, encoded) //nolint:errcheck
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	// mbox files use the line endings of the system
+	return bytes.ReplaceAll(out.Bytes(), []byte("\r\n"), []byte("\n")), nil
+}
+
+func (transcript *Transcript) messageId(msgId uint32) string {
+	return fmt.Sprintf("<%v.%v.%v@export.delta.chat>", msgId, transcript.ChatId, transcript.AccountId)
+}
+
+func formatAddress(name string, addr string) string {
+	if name == "" {
+		return "<" + addr + ">"
+	}
+	// quotes names with special characters like commas and encodes non-ASCII names
+	return (&mail.Address{Name: name, Address: addr}).String()
+}
+
+// writeHeader writes the header fields in a stable order.
+func writeHeader(out *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "Date", "Subject", "Message-ID", "In-Reply-To", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(key); value != "" {
+			out.WriteString(key + ": " + value + "\n")
+		}
+	}
+	out.WriteString("\n")
+}
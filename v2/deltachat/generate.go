@@ -0,0 +1,4 @@
+package deltachat
+
+// Regenerate rpc.go and types.go from the schema of the deltachat-rpc-server in PATH.
+//go:generate go run ../cmd/dcrpcgen-go -o .
@@ -0,0 +1,223 @@
+// Package handle provides thin object-oriented wrappers around the ids used by deltachat.Rpc,
+// similar to the Python deltachat-rpc-client.
+//
+// A handle only holds an id and the Rpc it belongs to, it does not cache any state: every
+// method is a call to the RPC server. Handles can be created at any time from known ids, e.g.
+// from events, with Account.Chat(), Account.Message() and Account.Contact().
+package handle
+
+import (
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+// Account is a handle for a Delta Chat account.
+type Account struct {
+	Rpc *deltachat.Rpc
+	Id  uint32
+}
+
+// Create a new handle for the account with the given id.
+func NewAccount(rpc *deltachat.Rpc, accId uint32) *Account {
+	return &Account{Rpc: rpc, Id: accId}
+}
+
+// Add a new account and return its handle.
+func AddAccount(rpc *deltachat.Rpc) (*Account, error) {
+	accId, err := rpc.AddAccount()
+	if err != nil {
+		return nil, err
+	}
+	return NewAccount(rpc, accId), nil
+}
+
+// Get handles for all the accounts.
+func AllAccounts(rpc *deltachat.Rpc) ([]*Account, error) {
+	ids, err := rpc.GetAllAccountIds()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]*Account, len(ids))
+	for i, id := range ids {
+		accounts[i] = NewAccount(rpc, id)
+	}
+	return accounts, nil
+}
+
+// Get the handle of the chat with the given id in this account.
+func (acc *Account) Chat(chatId uint32) *Chat {
+	return &Chat{Account: acc, Id: chatId}
+}
+
+// Get the handle of the message with the given id in this account.
+func (acc *Account) Message(msgId uint32) *Message {
+	return &Message{Account: acc, Id: msgId}
+}
+
+// Get the handle of the contact with the given id in this account.
+func (acc *Account) Contact(contactId uint32) *Contact {
+	return &Contact{Account: acc, Id: contactId}
+}
+
+// Get the handle of the contact representing the account itself.
+func (acc *Account) SelfContact() *Contact {
+	return acc.Contact(deltachat.ContactSelf)
+}
+
+// Get the typed configuration of the account.
+func (acc *Account) Config() *deltachat.AccountConfig {
+	return deltachat.NewAccountConfig(acc.Rpc, acc.Id)
+}
+
+// Get top-level info of the account.
+func (acc *Account) Info() (deltachat.Account, error) {
+	return acc.Rpc.GetAccountInfo(acc.Id)
+}
+
+// Return true if the account is configured.
+func (acc *Account) IsConfigured() (bool, error) {
+	return acc.Rpc.IsConfigured(acc.Id)
+}
+
+// Configure the account with the login parameters previously set.
+func (acc *Account) Configure() error {
+	return acc.Rpc.Configure(acc.Id)
+}
+
+// Start the account I/O.
+func (acc *Account) StartIo() error {
+	return acc.Rpc.StartIo(acc.Id)
+}
+
+// Stop the account I/O.
+func (acc *Account) StopIo() error {
+	return acc.Rpc.StopIo(acc.Id)
+}
+
+// Remove the account.
+func (acc *Account) Remove() error {
+	return acc.Rpc.RemoveAccount(acc.Id)
+}
+
+// Get the account connectivity.
+func (acc *Account) Connectivity() (deltachat.Connectivity, error) {
+	return acc.Rpc.GetConnectivity(acc.Id)
+}
+
+// Create a new contact or update the name of an existing one.
+func (acc *Account) CreateContact(addr string, name string) (*Contact, error) {
+	var namePtr *string
+	if name != "" {
+		namePtr = &name
+	}
+	contactId, err := acc.Rpc.CreateContact(acc.Id, addr, namePtr)
+	if err != nil {
+		return nil, err
+	}
+	return acc.Contact(contactId), nil
+}
+
+// Get the contact with the given e-mail address, nil is returned if there is no such contact.
+func (acc *Account) GetContactByAddr(addr string) (*Contact, error) {
+	contactId, err := acc.Rpc.LookupContactIdByAddr(acc.Id, addr)
+	if err != nil || contactId == nil {
+		return nil, err
+	}
+	return acc.Contact(*contactId), nil
+}
+
+// Import the contacts of the given vCard.
+func (acc *Account) ImportVcard(vcard string) ([]*Contact, error) {
+	ids, err := acc.Rpc.ImportVcardContents(acc.Id, vcard)
+	if err != nil {
+		return nil, err
+	}
+	return acc.contacts(ids), nil
+}
+
+// Get the contacts of the account matching the given flags and query, query can be empty.
+func (acc *Account) Contacts(flags deltachat.ContactFlags, query string) ([]*Contact, error) {
+	var queryPtr *string
+	if query != "" {
+		queryPtr = &query
+	}
+	ids, err := acc.Rpc.GetContactIds(acc.Id, flags, queryPtr)
+	if err != nil {
+		return nil, err
+	}
+	return acc.contacts(ids), nil
+}
+
+// Create a new encrypted group chat.
+func (acc *Account) CreateGroup(name string) (*Chat, error) {
+	chatId, err := acc.Rpc.CreateGroupChat(acc.Id, name, false)
+	if err != nil {
+		return nil, err
+	}
+	return acc.Chat(chatId), nil
+}
+
+// Create a new broadcast channel.
+func (acc *Account) CreateBroadcast(name string) (*Chat, error) {
+	chatId, err := acc.Rpc.CreateBroadcast(acc.Id, name)
+	if err != nil {
+		return nil, err
+	}
+	return acc.Chat(chatId), nil
+}
+
+// Get the chats of the chatlist matching the given flags and query, query can be empty.
+func (acc *Account) Chats(flags deltachat.ChatListFlags, query string) ([]*Chat, error) {
+	var queryPtr *string
+	if query != "" {
+		queryPtr = &query
+	}
+	ids, err := acc.Rpc.GetChatlistEntries(acc.Id, &flags, queryPtr, nil)
+	if err != nil {
+		return nil, err
+	}
+	chats := make([]*Chat, len(ids))
+	for i, id := range ids {
+		chats[i] = acc.Chat(id)
+	}
+	return chats, nil
+}
+
+// Get the fresh messages of all the chats.
+func (acc *Account) FreshMessages() ([]*Message, error) {
+	ids, err := acc.Rpc.GetFreshMsgs(acc.Id)
+	if err != nil {
+		return nil, err
+	}
+	return acc.messages(ids), nil
+}
+
+// Get the QR code to set up a verified 1:1 chat with this account.
+func (acc *Account) QrCode() (string, error) {
+	return acc.Rpc.GetChatSecurejoinQrCode(acc.Id, nil)
+}
+
+// Start the secure join protocol with the given QR code and return the chat that will
+// be used once the protocol completes.
+func (acc *Account) SecureJoin(qr string) (*Chat, error) {
+	chatId, err := acc.Rpc.SecureJoin(acc.Id, qr)
+	if err != nil {
+		return nil, err
+	}
+	return acc.Chat(chatId), nil
+}
+
+func (acc *Account) contacts(ids []uint32) []*Contact {
+	contacts := make([]*Contact, len(ids))
+	for i, id := range ids {
+		contacts[i] = acc.Contact(id)
+	}
+	return contacts
+}
+
+func (acc *Account) messages(ids []uint32) []*Message {
+	msgs := make([]*Message, len(ids))
+	for i, id := range ids {
+		msgs[i] = acc.Message(id)
+	}
+	return msgs
+}
@@ -0,0 +1,64 @@
+package handle
+
+import (
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAccount(t *testing.T) {
+	t.Parallel()
+	acfactory.WithRpc(func(rpc *deltachat.Rpc) {
+		acc, err := AddAccount(rpc)
+		require.Nil(t, err)
+		configured, err := acc.IsConfigured()
+		require.Nil(t, err)
+		require.False(t, configured)
+
+		accounts, err := AllAccounts(rpc)
+		require.Nil(t, err)
+		require.Contains(t, accounts, acc)
+
+		require.Nil(t, acc.Remove())
+	})
+}
+
+func TestAccount_Contacts(t *testing.T) {
+	t.Parallel()
+	acfactory.WithUnconfiguredAccount(func(rpc *deltachat.Rpc, accId uint32) {
+		acc := NewAccount(rpc, accId)
+		contact, err := acc.CreateContact("null@localhost", "Null")
+		require.Nil(t, err)
+
+		found, err := acc.GetContactByAddr("null@localhost")
+		require.Nil(t, err)
+		require.Equal(t, contact, found)
+		found, err = acc.GetContactByAddr("unknown@localhost")
+		require.Nil(t, err)
+		require.Nil(t, found)
+
+		contacts, err := acc.Contacts(deltachat.ContactFlagAddress, "null")
+		require.Nil(t, err)
+		require.Equal(t, []*Contact{contact}, contacts)
+	})
+}
+
+func TestAccount_CreateGroup(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *deltachat.Rpc, accId uint32) {
+		acc := NewAccount(rpc, accId)
+		chat, err := acc.CreateGroup("test group")
+		require.Nil(t, err)
+		_, err = chat.Send("hi")
+		require.Nil(t, err)
+
+		chats, err := acc.Chats(0, "test group")
+		require.Nil(t, err)
+		require.Equal(t, []*Chat{chat}, chats)
+
+		info, err := chat.BasicSnapshot()
+		require.Nil(t, err)
+		require.Equal(t, "test group", info.Name)
+	})
+}
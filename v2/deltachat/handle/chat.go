@@ -0,0 +1,126 @@
+package handle
+
+import (
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+// Chat is a handle for a chat of an account.
+type Chat struct {
+	Account *Account
+	Id      uint32
+}
+
+// Send a text message to the chat.
+func (chat *Chat) Send(text string) (*Message, error) {
+	msgId, err := chat.Account.Rpc.MiscSendTextMessage(chat.Account.Id, chat.Id, text)
+	if err != nil {
+		return nil, err
+	}
+	return chat.Account.Message(msgId), nil
+}
+
+// Send a message with the given data to the chat.
+func (chat *Chat) SendMsg(data deltachat.MessageData) (*Message, error) {
+	msgId, err := chat.Account.Rpc.SendMsg(chat.Account.Id, chat.Id, data)
+	if err != nil {
+		return nil, err
+	}
+	return chat.Account.Message(msgId), nil
+}
+
+// Send a sticker to the chat.
+func (chat *Chat) SendSticker(path string) (*Message, error) {
+	msgId, err := chat.Account.Rpc.SendSticker(chat.Account.Id, chat.Id, path)
+	if err != nil {
+		return nil, err
+	}
+	return chat.Account.Message(msgId), nil
+}
+
+// Get the full information of the chat.
+func (chat *Chat) FullSnapshot() (deltachat.FullChat, error) {
+	return chat.Account.Rpc.GetFullChatById(chat.Account.Id, chat.Id)
+}
+
+// Get the basic information of the chat, cheaper than FullSnapshot().
+func (chat *Chat) BasicSnapshot() (deltachat.BasicChat, error) {
+	return chat.Account.Rpc.GetBasicChatInfo(chat.Account.Id, chat.Id)
+}
+
+// Accept the contact request.
+func (chat *Chat) Accept() error {
+	return chat.Account.Rpc.AcceptChat(chat.Account.Id, chat.Id)
+}
+
+// Block the chat.
+func (chat *Chat) Block() error {
+	return chat.Account.Rpc.BlockChat(chat.Account.Id, chat.Id)
+}
+
+// Delete the chat.
+func (chat *Chat) Delete() error {
+	return chat.Account.Rpc.DeleteChat(chat.Account.Id, chat.Id)
+}
+
+// Leave the group.
+func (chat *Chat) Leave() error {
+	return chat.Account.Rpc.LeaveGroup(chat.Account.Id, chat.Id)
+}
+
+// Mark all the messages of the chat as noticed.
+func (chat *Chat) MarkNoticed() error {
+	return chat.Account.Rpc.MarknoticedChat(chat.Account.Id, chat.Id)
+}
+
+// Set the name of the group.
+func (chat *Chat) SetName(name string) error {
+	return chat.Account.Rpc.SetChatName(chat.Account.Id, chat.Id, name)
+}
+
+// Set the visibility of the chat: normal, archived or pinned.
+func (chat *Chat) SetVisibility(visibility deltachat.ChatVisibility) error {
+	return chat.Account.Rpc.SetChatVisibility(chat.Account.Id, chat.Id, visibility)
+}
+
+// Mute the chat for the given duration.
+func (chat *Chat) SetMuteDuration(duration deltachat.MuteDuration) error {
+	return chat.Account.Rpc.SetChatMuteDuration(chat.Account.Id, chat.Id, duration)
+}
+
+// Add a contact to the group.
+func (chat *Chat) AddContact(contact *Contact) error {
+	return chat.Account.Rpc.AddContactToChat(chat.Account.Id, chat.Id, contact.Id)
+}
+
+// Remove a contact from the group.
+func (chat *Chat) RemoveContact(contact *Contact) error {
+	return chat.Account.Rpc.RemoveContactFromChat(chat.Account.Id, chat.Id, contact.Id)
+}
+
+// Get the members of the chat.
+func (chat *Chat) Contacts() ([]*Contact, error) {
+	ids, err := chat.Account.Rpc.GetChatContacts(chat.Account.Id, chat.Id)
+	if err != nil {
+		return nil, err
+	}
+	return chat.Account.contacts(ids), nil
+}
+
+// Get the messages of the chat, without day markers.
+func (chat *Chat) Messages() ([]*Message, error) {
+	ids, err := chat.Account.Rpc.GetMessageIds(chat.Account.Id, chat.Id, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return chat.Account.messages(ids), nil
+}
+
+// Get the number of fresh messages in the chat.
+func (chat *Chat) FreshMessageCount() (uint, error) {
+	return chat.Account.Rpc.GetFreshMsgCnt(chat.Account.Id, chat.Id)
+}
+
+// Get the QR code to join the group.
+func (chat *Chat) QrCode() (string, error) {
+	return chat.Account.Rpc.GetChatSecurejoinQrCode(chat.Account.Id, &chat.Id)
+}
@@ -0,0 +1,34 @@
+package handle
+
+import (
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChat_Members(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *deltachat.Rpc, accId uint32) {
+		acc := NewAccount(rpc, accId)
+		chat, err := acc.CreateGroup("members")
+		require.Nil(t, err)
+		contact, err := acc.CreateContact("null@localhost", "")
+		require.Nil(t, err)
+
+		require.Nil(t, chat.AddContact(contact))
+		members, err := chat.Contacts()
+		require.Nil(t, err)
+		require.Contains(t, members, contact)
+		require.Contains(t, members, acc.SelfContact())
+
+		require.Nil(t, chat.RemoveContact(contact))
+		members, err = chat.Contacts()
+		require.Nil(t, err)
+		require.NotContains(t, members, contact)
+
+		msgs, err := chat.Messages()
+		require.Nil(t, err)
+		require.NotEmpty(t, msgs)
+	})
+}
@@ -0,0 +1,64 @@
+package handle
+
+import (
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+// Contact is a handle for a contact of an account.
+type Contact struct {
+	Account *Account
+	Id      uint32
+}
+
+// Get the current state of the contact.
+func (contact *Contact) Snapshot() (deltachat.Contact, error) {
+	return contact.Account.Rpc.GetContact(contact.Account.Id, contact.Id)
+}
+
+// Create or get the 1:1 chat with the contact.
+func (contact *Contact) CreateChat() (*Chat, error) {
+	chatId, err := contact.Account.Rpc.CreateChatByContactId(contact.Account.Id, contact.Id)
+	if err != nil {
+		return nil, err
+	}
+	return contact.Account.Chat(chatId), nil
+}
+
+// Get the 1:1 chat with the contact, nil is returned if there is no such chat.
+func (contact *Contact) Chat() (*Chat, error) {
+	chatId, err := contact.Account.Rpc.GetChatIdByContactId(contact.Account.Id, contact.Id)
+	if err != nil || chatId == nil {
+		return nil, err
+	}
+	return contact.Account.Chat(*chatId), nil
+}
+
+// Block the contact.
+func (contact *Contact) Block() error {
+	return contact.Account.Rpc.BlockContact(contact.Account.Id, contact.Id)
+}
+
+// Unblock the contact.
+func (contact *Contact) Unblock() error {
+	return contact.Account.Rpc.UnblockContact(contact.Account.Id, contact.Id)
+}
+
+// Delete the contact.
+func (contact *Contact) Delete() error {
+	return contact.Account.Rpc.DeleteContact(contact.Account.Id, contact.Id)
+}
+
+// Set the name of the contact, an empty name resets it to the name chosen by the contact.
+func (contact *Contact) SetName(name string) error {
+	return contact.Account.Rpc.ChangeContactName(contact.Account.Id, contact.Id, name)
+}
+
+// Get a vCard of the contact.
+func (contact *Contact) MakeVcard() (string, error) {
+	return contact.Account.Rpc.MakeVcard(contact.Account.Id, []uint32{contact.Id})
+}
+
+// Get a human readable description of the encryption state with the contact.
+func (contact *Contact) EncryptionInfo() (string, error) {
+	return contact.Account.Rpc.GetContactEncryptionInfo(contact.Account.Id, contact.Id)
+}
@@ -0,0 +1,35 @@
+package handle
+
+import (
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContact_CreateChat(t *testing.T) {
+	t.Parallel()
+	acfactory.WithUnconfiguredAccount(func(rpc *deltachat.Rpc, accId uint32) {
+		acc := NewAccount(rpc, accId)
+		contact, err := acc.CreateContact("null@localhost", "")
+		require.Nil(t, err)
+
+		chat, err := contact.Chat()
+		require.Nil(t, err)
+		require.Nil(t, chat)
+
+		chat, err = contact.CreateChat()
+		require.Nil(t, err)
+		existing, err := contact.Chat()
+		require.Nil(t, err)
+		require.Equal(t, chat, existing)
+
+		require.Nil(t, contact.SetName("Null"))
+		snapshot, err := contact.Snapshot()
+		require.Nil(t, err)
+		require.Equal(t, "Null", snapshot.DisplayName)
+
+		require.Nil(t, contact.Block())
+		require.Nil(t, contact.Unblock())
+	})
+}
@@ -0,0 +1,116 @@
+package handle
+
+import (
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+)
+
+// Message is a handle for a message of an account.
+type Message struct {
+	Account *Account
+	Id      uint32
+}
+
+// Get the current state of the message.
+func (msg *Message) Snapshot() (deltachat.Message, error) {
+	return msg.Account.Rpc.GetMessage(msg.Account.Id, msg.Id)
+}
+
+// Get the chat the message belongs to.
+func (msg *Message) Chat() (*Chat, error) {
+	snapshot, err := msg.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return msg.Account.Chat(snapshot.ChatId), nil
+}
+
+// Get the sender of the message.
+func (msg *Message) Sender() (*Contact, error) {
+	snapshot, err := msg.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return msg.Account.Contact(snapshot.FromId), nil
+}
+
+// Send a text message quoting this message to the same chat.
+func (msg *Message) Reply(text string) (*Message, error) {
+	return msg.ReplyMsg(deltachat.MessageData{Text: &text})
+}
+
+// Send a message quoting this message to the same chat, data.QuotedMessageId is overridden.
+func (msg *Message) ReplyMsg(data deltachat.MessageData) (*Message, error) {
+	chat, err := msg.Chat()
+	if err != nil {
+		return nil, err
+	}
+	data.QuotedMessageId = &msg.Id
+	return chat.SendMsg(data)
+}
+
+// React to the message, the previous reaction of this account is replaced.
+// Calling React() without reactions removes the reaction.
+func (msg *Message) React(reactions ...string) (*Message, error) {
+	if reactions == nil {
+		reactions = []string{}
+	}
+	msgId, err := msg.Account.Rpc.SendReaction(msg.Account.Id, msg.Id, reactions)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Account.Message(msgId), nil
+}
+
+// Get the reactions to the message, nil is returned if there are none.
+func (msg *Message) Reactions() (*deltachat.Reactions, error) {
+	return msg.Account.Rpc.GetMessageReactions(msg.Account.Id, msg.Id)
+}
+
+// Replace the text of the message, only possible for outgoing messages.
+func (msg *Message) Edit(text string) error {
+	return msg.Account.Rpc.SendEditRequest(msg.Account.Id, msg.Id, text)
+}
+
+// Forward the message to the given chat.
+func (msg *Message) Forward(chat *Chat) error {
+	return msg.Account.Rpc.ForwardMessages(msg.Account.Id, []uint32{msg.Id}, chat.Id)
+}
+
+// Mark the message as seen.
+func (msg *Message) MarkSeen() error {
+	return msg.Account.Rpc.MarkseenMsgs(msg.Account.Id, []uint32{msg.Id})
+}
+
+// Delete the message on this device.
+func (msg *Message) Delete() error {
+	return msg.Account.Rpc.DeleteMessages(msg.Account.Id, []uint32{msg.Id})
+}
+
+// Delete the message for all the chat members.
+func (msg *Message) DeleteForAll() error {
+	return msg.Account.Rpc.DeleteMessagesForAll(msg.Account.Id, []uint32{msg.Id})
+}
+
+// Get the HTML part of the message, nil is returned if there is none.
+func (msg *Message) Html() (*string, error) {
+	return msg.Account.Rpc.GetMessageHtml(msg.Account.Id, msg.Id)
+}
+
+// Get a human readable description of the message state and headers.
+func (msg *Message) Info() (string, error) {
+	return msg.Account.Rpc.GetMessageInfo(msg.Account.Id, msg.Id)
+}
+
+// Save the attachment of the message to the given path.
+func (msg *Message) SaveFile(path string) error {
+	return msg.Account.Rpc.SaveMsgFile(msg.Account.Id, msg.Id, path)
+}
+
+// Send a status update to the webxdc app of the message, description can be empty.
+func (msg *Message) SendWebxdcStatusUpdate(update string, description string) error {
+	var descriptionPtr *string
+	if description != "" {
+		descriptionPtr = &description
+	}
+	return msg.Account.Rpc.SendWebxdcStatusUpdate(msg.Account.Id, msg.Id, update, descriptionPtr)
+}
@@ -0,0 +1,43 @@
+package handle
+
+import (
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_ReplyAndReact(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc1 *deltachat.Rpc, accId1 uint32) {
+		acfactory.WithOnlineAccount(func(rpc2 *deltachat.Rpc, accId2 uint32) {
+			acc1 := NewAccount(rpc1, accId1)
+			acc2 := NewAccount(rpc2, accId2)
+			chat := acc1.Chat(acfactory.CreateChat(rpc1, accId1, rpc2, accId2))
+			_, err := chat.Send("hello")
+			require.Nil(t, err)
+
+			snapshot := acfactory.NextMsg(rpc2, accId2)
+			msg := acc2.Message(snapshot.Id)
+			sender, err := msg.Sender()
+			require.Nil(t, err)
+			require.Equal(t, snapshot.FromId, sender.Id)
+
+			reply, err := msg.Reply("hi")
+			require.Nil(t, err)
+			replySnapshot, err := reply.Snapshot()
+			require.Nil(t, err)
+			require.Equal(t, msg.Id, replySnapshot.QuotedMessageId())
+			require.Equal(t, "hello", replySnapshot.QuoteText())
+
+			_, err = msg.React("👍")
+			require.Nil(t, err)
+			reactions, err := msg.Reactions()
+			require.Nil(t, err)
+			require.NotNil(t, reactions)
+
+			received := acfactory.NextMsg(rpc1, accId1)
+			require.Equal(t, "hi", received.Text)
+		})
+	})
+}
@@ -0,0 +1,345 @@
+// Package importer imports chat transcripts from other systems into Delta Chat,
+// e.g. to migrate the history of a support channel.
+//
+// Transcripts use the JSON format written by the exporter package, so external
+// systems only need to produce a document like:
+//
+//	{
+//	  "chatName": "Support",
+//	  "attachmentsDir": "files",
+//	  "messages": [
+//	    {
+//	      "id": 1,
+//	      "sender": {"name": "Alice", "address": "alice@example.org"},
+//	      "timestamp": "2024-05-01T10:30:00Z",
+//	      "text": "Hello"
+//	    },
+//	    {
+//	      "id": 2,
+//	      "sender": {"name": "Bob", "address": "bob@example.org"},
+//	      "timestamp": "2024-05-01T10:31:00Z",
+//	      "text": "see the report",
+//	      "quote": {"messageId": 1, "text": "Hello"},
+//	      "attachment": {"name": "report.pdf", "path": "report.pdf"}
+//	    }
+//	  ]
+//	}
+//
+// Messages need an id unique in the transcript, used to resolve quotes, and a text
+// or an attachment. Attachment paths are relative to attachmentsDir, which is
+// relative to the transcript file. Info messages are skipped, the other fields of
+// exporter.Message are ignored.
+//
+// Messages are replayed in the device chat with Rpc.AddDeviceMessage(), or in a new
+// group created with Rpc.CreateGroupChat() and sent with Rpc.SendMsg(), with the
+// original sender as OverrideSenderName. With DryRun, the transcript is validated
+// and the returned Report describes the import without changing the account.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/chatmail/rpc-client-go/v2/deltachat/exporter"
+)
+
+// Target is the chat where the messages of a transcript are imported.
+type Target string
+
+const (
+	// Import the messages in the device chat, the sender and the time of the
+	// messages are added to their text.
+	TargetDevice Target = "device"
+	// Import the messages in a new group named after the transcript chat.
+	TargetGroup Target = "group"
+)
+
+// Importer imports transcripts into an account.
+type Importer struct {
+	Rpc       *deltachat.Rpc
+	AccountId uint32
+	Target    Target
+	// Add the senders of the transcript as members of the imported group. The imported
+	// messages are then sent to them, otherwise the group only contains the account.
+	AddMembers bool
+	// Validate the transcript and report the import without changing the account.
+	DryRun bool
+}
+
+// Report describes an import.
+type Report struct {
+	DryRun bool   `json:"dryRun"`
+	Target Target `json:"target"`
+	// Id of the chat the messages were imported in, 0 in dry-run mode for groups.
+	ChatId uint32 `json:"chatId"`
+	// Name of the transcript chat.
+	ChatName string `json:"chatName"`
+	// Addresses of the contacts created for the senders.
+	Contacts []string `json:"contacts"`
+	// Ids of the imported messages by transcript message id, 0 in dry-run mode.
+	Messages    map[uint32]uint32 `json:"messages"`
+	Attachments int               `json:"attachments"`
+	Skipped     []SkippedMessage  `json:"skipped"`
+}
+
+// SkippedMessage is a transcript message that was not imported.
+type SkippedMessage struct {
+	Id     uint32 `json:"id"`
+	Reason string `json:"reason"`
+}
+
+func (report *Report) String() string {
+	verb := "imported"
+	if report.DryRun {
+		verb = "would import"
+	}
+	return fmt.Sprintf("%v %v messages with %v attachments from %q into %v chat, %v contacts, %v skipped messages",
+		verb, len(report.Messages), report.Attachments, report.ChatName, report.Target, len(report.Contacts), len(report.Skipped))
+}
+
+// InvalidTranscriptErr is returned for transcripts that can not be imported.
+type InvalidTranscriptErr struct {
+	// Id of the invalid message, 0 if the transcript itself is invalid.
+	MessageId uint32
+	Reason    string
+}
+
+func (err *InvalidTranscriptErr) Error() string {
+	if err.MessageId == 0 {
+		return "invalid transcript: " + err.Reason
+	}
+	return fmt.Sprintf("invalid transcript: message %v: %v", err.MessageId, err.Reason)
+}
+
+// Create a new Importer for the given account, importing in the device chat.
+func New(rpc *deltachat.Rpc, accId uint32) *Importer {
+	return &Importer{Rpc: rpc, AccountId: accId, Target: TargetDevice}
+}
+
+// ReadTranscript decodes a JSON transcript.
+func ReadTranscript(r io.Reader) (*exporter.Transcript, error) {
+	var transcript exporter.Transcript
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&transcript); err != nil {
+		return nil, &InvalidTranscriptErr{Reason: err.Error()}
+	}
+	return &transcript, nil
+}
+
+// OpenTranscript reads a JSON transcript file, resolving its AttachmentsDir relatively to the file.
+func OpenTranscript(path string) (*exporter.Transcript, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck
+	transcript, err := ReadTranscript(file)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(transcript.AttachmentsDir) {
+		transcript.AttachmentsDir = filepath.Join(filepath.Dir(path), transcript.AttachmentsDir)
+	}
+	return transcript, nil
+}
+
+// Validate checks that the messages of the transcript have unique ids and that
+// their attachments exist in the attachments directory: absolute paths and paths
+// leaving the directory are rejected, the attachments are sent to the chat members.
+func Validate(transcript *exporter.Transcript) error {
+	if transcript.ChatName == "" {
+		return &InvalidTranscriptErr{Reason: "missing chatName"}
+	}
+	ids := make(map[uint32]bool, len(transcript.Messages))
+	for _, msg := range transcript.Messages {
+		if msg.Id == 0 {
+			return &InvalidTranscriptErr{Reason: "message without id"}
+		}
+		if ids[msg.Id] {
+			return &InvalidTranscriptErr{MessageId: msg.Id, Reason: "duplicate id"}
+		}
+		ids[msg.Id] = true
+		if msg.Attachment == nil || msg.Attachment.Path == "" {
+			continue
+		}
+		if !filepath.IsLocal(msg.Attachment.Path) {
+			return &InvalidTranscriptErr{MessageId: msg.Id, Reason: "attachment path " + msg.Attachment.Path + " is outside of the attachments directory"}
+		}
+		info, err := os.Stat(attachmentPath(transcript, msg.Attachment))
+		if err != nil {
+			return &InvalidTranscriptErr{MessageId: msg.Id, Reason: err.Error()}
+		}
+		if info.IsDir() {
+			return &InvalidTranscriptErr{MessageId: msg.Id, Reason: msg.Attachment.Path + " is a directory"}
+		}
+	}
+	return nil
+}
+
+// Import validates the transcript and imports its messages in the order of the transcript.
+func (imp *Importer) Import(transcript *exporter.Transcript) (*Report, error) {
+	if imp.Target != TargetDevice && imp.Target != TargetGroup {
+		return nil, fmt.Errorf("unknown import target %q", imp.Target)
+	}
+	if err := Validate(transcript); err != nil {
+		return nil, err
+	}
+	report := &Report{
+		DryRun:   imp.DryRun,
+		Target:   imp.Target,
+		ChatName: transcript.ChatName,
+		Contacts: []string{},
+		Messages: map[uint32]uint32{},
+		Skipped:  []SkippedMessage{},
+	}
+
+	contacts := map[string]uint32{}
+	for _, msg := range transcript.Messages {
+		addr := msg.Sender.Address
+		if msg.IsInfo || addr == "" || msg.Sender.ContactId == deltachat.ContactSelf {
+			continue
+		}
+		if _, ok := contacts[addr]; ok {
+			continue
+		}
+		contacts[addr] = 0
+		report.Contacts = append(report.Contacts, addr)
+	}
+	if !imp.DryRun {
+		if err := imp.createChat(report, contacts); err != nil {
+			return report, err
+		}
+	}
+
+	for _, msg := range transcript.Messages {
+		if msg.IsInfo {
+			report.Skipped = append(report.Skipped, SkippedMessage{Id: msg.Id, Reason: "info message"})
+			continue
+		}
+		hasFile := msg.Attachment != nil && msg.Attachment.Path != ""
+		if msg.Text == "" && !hasFile {
+			report.Skipped = append(report.Skipped, SkippedMessage{Id: msg.Id, Reason: "no text or attachment"})
+			continue
+		}
+		if imp.DryRun {
+			report.Messages[msg.Id] = 0
+		} else {
+			msgId, err := imp.importMessage(transcript, msg, report)
+			if err != nil {
+				return report, fmt.Errorf("importing message %v: %w", msg.Id, err)
+			}
+			if msgId == 0 {
+				report.Skipped = append(report.Skipped, SkippedMessage{Id: msg.Id, Reason: "already imported"})
+				continue
+			}
+			report.Messages[msg.Id] = msgId
+		}
+		if hasFile {
+			report.Attachments++
+		}
+	}
+	return report, nil
+}
+
+// createChat creates the contacts and the target chat.
+func (imp *Importer) createChat(report *Report, contacts map[string]uint32) error {
+	for _, addr := range report.Contacts {
+		contactId, err := imp.Rpc.CreateContact(imp.AccountId, addr, nil)
+		if err != nil {
+			return fmt.Errorf("creating contact %v: %w", addr, err)
+		}
+		contacts[addr] = contactId
+	}
+	if imp.Target == TargetDevice {
+		return nil
+	}
+	chatId, err := imp.Rpc.CreateGroupChat(imp.AccountId, report.ChatName, false)
+	if err != nil {
+		return err
+	}
+	report.ChatId = chatId
+	if !imp.AddMembers {
+		return nil
+	}
+	for _, addr := range report.Contacts {
+		if err := imp.Rpc.AddContactToChat(imp.AccountId, chatId, contacts[addr]); err != nil {
+			return fmt.Errorf("adding %v to the group: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+// importMessage imports a message, the returned id is 0 if the device message was already imported.
+func (imp *Importer) importMessage(transcript *exporter.Transcript, msg exporter.Message, report *Report) (uint32, error) {
+	data := deltachat.MessageData{}
+	if msg.Attachment != nil && msg.Attachment.Path != "" {
+		path, err := filepath.Abs(attachmentPath(transcript, msg.Attachment))
+		if err != nil {
+			return 0, err
+		}
+		name := msg.Attachment.Name
+		data.File = &path
+		data.Filename = &name
+	}
+	if msg.Quote != nil {
+		if quoteId := report.Messages[msg.Quote.MessageId]; quoteId != 0 {
+			data.QuotedMessageId = &quoteId
+		} else if msg.Quote.Text != "" {
+			data.QuotedText = &msg.Quote.Text
+		}
+	}
+
+	if imp.Target == TargetDevice {
+		text := deviceText(msg)
+		data.Text = &text
+		// device messages with the same label are only added once
+		label := fmt.Sprintf("import-%v-%v-%q-%v", transcript.AccountId, transcript.ChatId, transcript.ChatName, msg.Id)
+		msgId, err := imp.Rpc.AddDeviceMessage(imp.AccountId, label, &data)
+		if err != nil || msgId == nil {
+			return 0, err
+		}
+		if report.ChatId == 0 {
+			if message, err := imp.Rpc.GetMessage(imp.AccountId, *msgId); err == nil {
+				report.ChatId = message.ChatId
+			}
+		}
+		return *msgId, nil
+	}
+
+	if msg.Text != "" {
+		data.Text = &msg.Text
+	}
+	if name := senderName(msg.Sender); name != "" && msg.Sender.ContactId != deltachat.ContactSelf {
+		data.OverrideSenderName = &name
+	}
+	return imp.Rpc.SendMsg(imp.AccountId, report.ChatId, data)
+}
+
+// deviceText returns the text of a device message, prefixed with the sender and the original time.
+func deviceText(msg exporter.Message) string {
+	header := msg.Timestamp.UTC().Format(time.DateTime)
+	if name := senderName(msg.Sender); name != "" {
+		header = name + ", " + header
+	}
+	if msg.Text == "" {
+		return header
+	}
+	return header + ":\n" + msg.Text
+}
+
+func senderName(sender exporter.Sender) string {
+	if sender.Name != "" {
+		return sender.Name
+	}
+	return sender.Address
+}
+
+func attachmentPath(transcript *exporter.Transcript, attachment *exporter.Attachment) string {
+	return filepath.Join(transcript.AttachmentsDir, attachment.Path)
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chatmail/rpc-client-go/v2/deltachat"
+	"github.com/chatmail/rpc-client-go/v2/deltachat/exporter"
+	"github.com/stretchr/testify/require"
+)
+
+const testTranscript = `{
+  "chatName": "Support",
+  "attachmentsDir": "files",
+  "messages": [
+    {"id": 1, "sender": {"name": "Alice", "address": "alice@example.org"}, "timestamp": "2024-05-01T10:30:00Z", "text": "Hello"},
+    {"id": 2, "timestamp": "2024-05-01T10:30:00Z", "text": "Member added", "isInfo": true},
+    {
+      "id": 3,
+      "sender": {"name": "Bob", "address": "bob@example.org"},
+      "timestamp": "2024-05-01T10:31:00Z",
+      "text": "see the report",
+      "quote": {"messageId": 1, "text": "Hello"},
+      "attachment": {"name": "report.csv", "path": "report.csv"}
+    },
+    {"id": 4, "sender": {"address": "alice@example.org"}, "timestamp": "2024-05-01T10:32:00Z", "text": ""}
+  ]
+}`
+
+func writeTranscript(t *testing.T, content string) string {
+	dir := t.TempDir()
+	require.Nil(t, os.Mkdir(filepath.Join(dir, "files"), 0o755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "files", "report.csv"), []byte("a,b\n1,2\n"), 0o644))
+	path := filepath.Join(dir, "transcript.json")
+	require.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestOpenTranscript(t *testing.T) {
+	t.Parallel()
+	path := writeTranscript(t, testTranscript)
+	transcript, err := OpenTranscript(path)
+	require.Nil(t, err)
+	require.Equal(t, filepath.Join(filepath.Dir(path), "files"), transcript.AttachmentsDir)
+	require.Len(t, transcript.Messages, 4)
+	require.Equal(t, time.Date(2024, 5, 1, 10, 31, 0, 0, time.UTC), transcript.Messages[2].Timestamp)
+
+	_, err = ReadTranscript(strings.NewReader(`{"chatName": "Support", "mesages": []}`))
+	var invalid *InvalidTranscriptErr
+	require.True(t, errors.As(err, &invalid))
+	require.ErrorContains(t, err, "mesages")
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	transcript, err := OpenTranscript(writeTranscript(t, testTranscript))
+	require.Nil(t, err)
+	require.Nil(t, Validate(transcript))
+
+	transcript.Messages[1].Id = 1
+	require.EqualError(t, Validate(transcript), "invalid transcript: message 1: duplicate id")
+	transcript.Messages[1].Id = 2
+
+	transcript.Messages[2].Attachment.Path = "missing.csv"
+	var invalid *InvalidTranscriptErr
+	require.True(t, errors.As(Validate(transcript), &invalid))
+	require.Equal(t, uint32(3), invalid.MessageId)
+
+	// existing files outside of the attachments directory are rejected
+	secret := filepath.Join(filepath.Dir(transcript.AttachmentsDir), "secret")
+	require.Nil(t, os.WriteFile(secret, []byte("secret"), 0o600))
+	for _, path := range []string{"../secret", "a/../../secret", secret} {
+		transcript.Messages[2].Attachment.Path = path
+		require.ErrorContains(t, Validate(transcript), "outside of the attachments directory", path)
+	}
+	_, err = (&Importer{Target: TargetDevice, DryRun: true}).Import(transcript)
+	require.True(t, errors.As(err, &invalid))
+
+	require.EqualError(t, Validate(&exporter.Transcript{}), "invalid transcript: missing chatName")
+}
+
+func TestImporter_DryRun(t *testing.T) {
+	t.Parallel()
+	transcript, err := OpenTranscript(writeTranscript(t, testTranscript))
+	require.Nil(t, err)
+	// dry runs do not call the server
+	imp := New(nil, 1)
+	imp.Target = TargetGroup
+	imp.DryRun = true
+	report, err := imp.Import(transcript)
+	require.Nil(t, err)
+	require.Equal(t, []string{"alice@example.org", "bob@example.org"}, report.Contacts)
+	require.Equal(t, map[uint32]uint32{1: 0, 3: 0}, report.Messages)
+	require.Equal(t, 1, report.Attachments)
+	require.Equal(t, []SkippedMessage{{Id: 2, Reason: "info message"}, {Id: 4, Reason: "no text or attachment"}}, report.Skipped)
+	require.Equal(t, `would import 2 messages with 1 attachments from "Support" into group chat, 2 contacts, 2 skipped messages`, report.String())
+
+	imp.Target = "channel"
+	_, err = imp.Import(transcript)
+	require.ErrorContains(t, err, "unknown import target")
+}
+
+func TestDeviceText(t *testing.T) {
+	t.Parallel()
+	msg := exporter.Message{
+		Sender:    exporter.Sender{Address: "alice@example.org"},
+		Timestamp: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		Text:      "Hello",
+	}
+	require.Equal(t, "alice@example.org, 2024-05-01 10:30:00:\nHello", deviceText(msg))
+	msg.Sender.Name = "Alice"
+	msg.Text = ""
+	require.Equal(t, "Alice, 2024-05-01 10:30:00", deviceText(msg))
+}
+
+func TestImporter_Import(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *deltachat.Rpc, accId uint32) {
+		transcript, err := OpenTranscript(writeTranscript(t, testTranscript))
+		require.Nil(t, err)
+
+		imp := New(rpc, accId)
+		imp.Target = TargetGroup
+		report, err := imp.Import(transcript)
+		require.Nil(t, err)
+		require.Len(t, report.Messages, 2)
+		chat, err := rpc.GetBasicChatInfo(accId, report.ChatId)
+		require.Nil(t, err)
+		require.Equal(t, "Support", chat.Name)
+		reply, err := rpc.GetMessage(accId, report.Messages[3])
+		require.Nil(t, err)
+		require.Equal(t, "Bob", *reply.OverrideSenderName)
+		require.Equal(t, "report.csv", *reply.FileName)
+		require.Equal(t, report.Messages[1], reply.QuotedMessageId())
+
+		// device messages are only imported once
+		imp.Target = TargetDevice
+		report, err = imp.Import(transcript)
+		require.Nil(t, err)
+		require.Len(t, report.Messages, 2)
+		msg, err := rpc.GetMessage(accId, report.Messages[1])
+		require.Nil(t, err)
+		require.Equal(t, "Alice, 2024-05-01 10:30:00:\nHello", msg.Text)
+		report, err = imp.Import(transcript)
+		require.Nil(t, err)
+		require.Empty(t, report.Messages)
+		require.Contains(t, report.Skipped, SkippedMessage{Id: 1, Reason: "already imported"})
+	})
+}
@@ -18,12 +18,15 @@ type IOTransport struct {
 	Stderr      io.Writer
 	AccountsDir string
 	Cmd         string
-	cmd         *exec.Cmd
-	stdin       io.WriteCloser
-	client      *jrpc2.Client
-	ctx         context.Context
-	cancel      context.CancelFunc
-	mu          sync.Mutex
+	// VerifySchema makes Open() check the server schema with CheckSchema() and fail
+	// with SchemaMismatchErr if the server is not compatible with the bindings.
+	VerifySchema bool
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+	client       *jrpc2.Client
+	ctx          context.Context
+	cancel       context.CancelFunc
+	mu           sync.Mutex
 }
 
 // NewIOTransport creates a new IOTransport using the default deltachat-rpc-server binary.
@@ -33,6 +36,24 @@ func NewIOTransport() *IOTransport {
 
 // Open starts the deltachat-rpc-server process and connects to it.
 func (trans *IOTransport) Open() error {
+	if err := trans.start(); err != nil {
+		return err
+	}
+	if !trans.VerifySchema {
+		return nil
+	}
+	report, err := trans.CheckSchema(context.Background())
+	if err == nil && !report.Compatible() {
+		err = &SchemaMismatchErr{Report: report}
+	}
+	if err != nil {
+		trans.Close()
+		return err
+	}
+	return nil
+}
+
+func (trans *IOTransport) start() error {
 	trans.mu.Lock()
 	defer trans.mu.Unlock()
 
@@ -0,0 +1,1202 @@
+package mailserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	imapCapabilities = "IMAP4rev1 LITERAL+ IDLE MOVE UIDPLUS UNSELECT ID ENABLE NAMESPACE XCHATMAIL AUTH=PLAIN"
+	imapSystemFlags  = `\Answered \Flagged \Deleted \Seen \Draft`
+	imapDateLayout   = "02-Jan-2006 15:04:05 -0700"
+	maxLiteralSize   = 64 * 1024 * 1024
+)
+
+type imapSession struct {
+	srv      *Server
+	conn     net.Conn
+	r        *bufio.Reader
+	out      bytes.Buffer
+	user     *mailbox
+	selected *folder
+	readOnly bool
+	// UIDs of the selected folder known by the client, the sequence number of a message is its index+1.
+	view    []uint32
+	updates chan struct{}
+}
+
+// imapErr is returned by the command handlers to reply with a tagged NO or BAD response.
+type imapErr struct {
+	status string
+	text   string
+}
+
+func (e *imapErr) Error() string {
+	return e.status + " " + e.text
+}
+
+func errNo(format string, args ...any) error {
+	return &imapErr{status: "NO", text: fmt.Sprintf(format, args...)}
+}
+
+func errBad(format string, args ...any) error {
+	return &imapErr{status: "BAD", text: fmt.Sprintf(format, args...)}
+}
+
+func (srv *Server) serveImap(conn net.Conn) {
+	session := &imapSession{srv: srv, conn: conn, r: bufio.NewReader(conn), updates: make(chan struct{}, 1)}
+	defer func() {
+		srv.mu.Lock()
+		session.unselect()
+		srv.mu.Unlock()
+	}()
+
+	session.send("* OK [CAPABILITY %s] IMAP4rev1 Service Ready", imapCapabilities)
+	for session.flush() == nil {
+		data, err := session.readCommand()
+		if err != nil {
+			return
+		}
+		srv.logf("imap C: %s", data)
+		args, err := parseImapCommand(data)
+		if err != nil || len(args) < 2 {
+			session.send("* BAD Invalid command")
+			continue
+		}
+		tag, ok1 := args[0].(string)
+		name, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			session.send("* BAD Invalid command")
+			continue
+		}
+		name = strings.ToUpper(name)
+		args = args[2:]
+		if name == "UID" && len(args) > 0 {
+			if sub, ok := args[0].(string); ok {
+				name = "UID " + strings.ToUpper(sub)
+				args = args[1:]
+			}
+		}
+		if !session.handle(tag, name, args) {
+			session.flush() //nolint:errcheck
+			return
+		}
+	}
+}
+
+// handle processes a single IMAP command, it returns false if the connection must be closed.
+func (session *imapSession) handle(tag, name string, args []any) bool {
+	switch name {
+	case "LOGOUT":
+		session.send("* BYE Logging out")
+		session.send("%s OK LOGOUT completed", tag)
+		return false
+	case "IDLE":
+		return session.idle(tag)
+	case "AUTHENTICATE":
+		return session.authenticate(tag, args)
+	}
+
+	session.srv.mu.Lock()
+	defer session.srv.mu.Unlock()
+
+	var text string
+	var err error
+	if session.user == nil && !slices.Contains([]string{"CAPABILITY", "NOOP", "LOGIN", "ID", "ENABLE"}, name) {
+		err = errNo("Not authenticated")
+	} else {
+		text, err = session.dispatch(name, args)
+	}
+	switch err := err.(type) {
+	case nil:
+		if text == "" {
+			text = name + " completed"
+		}
+		session.send("%s OK %s", tag, text)
+	case *imapErr:
+		session.send("%s %s %s", tag, err.status, err.text)
+	default:
+		session.send("%s NO %s", tag, err)
+	}
+	return true
+}
+
+// dispatch must be called with srv.mu locked.
+func (session *imapSession) dispatch(name string, args []any) (string, error) {
+	switch name {
+	case "CAPABILITY":
+		session.send("* CAPABILITY %s", imapCapabilities)
+		return "", nil
+	case "NOOP", "CHECK":
+		session.sendUpdates(true)
+		return "", nil
+	case "ID":
+		session.send(`* ID ("name" "mailserver")`)
+		return "", nil
+	case "ENABLE":
+		session.send("* ENABLED")
+		return "", nil
+	case "NAMESPACE":
+		session.send(`* NAMESPACE (("" ".")) NIL NIL`)
+		return "", nil
+	case "LOGIN":
+		return session.login(args)
+	case "SELECT", "EXAMINE":
+		return session.selectFolder(args, name == "EXAMINE")
+	case "CREATE":
+		return session.create(args)
+	case "DELETE":
+		return session.delete(args)
+	case "SUBSCRIBE", "UNSUBSCRIBE":
+		return "", nil
+	case "LIST", "LSUB":
+		return session.list(name, args)
+	case "STATUS":
+		return session.status(args)
+	case "APPEND":
+		return session.appendMsg(args)
+	}
+
+	if session.selected == nil {
+		return "", errBad("No mailbox selected")
+	}
+	switch name {
+	case "CLOSE":
+		if !session.readOnly {
+			session.selected.remove(func(msg *message) bool { return msg.hasFlag(`\Deleted`) })
+		}
+		session.unselect()
+		return "", nil
+	case "UNSELECT":
+		session.unselect()
+		return "", nil
+	case "EXPUNGE":
+		return session.expunge(nil)
+	case "UID EXPUNGE":
+		if len(args) != 1 {
+			return "", errBad("Missing UID set")
+		}
+		set, err := parseSeqSet(atom(args[0]))
+		if err != nil {
+			return "", err
+		}
+		return session.expunge(set)
+	case "FETCH", "UID FETCH":
+		return session.fetch(args, name == "UID FETCH")
+	case "STORE", "UID STORE":
+		return session.store(args, name == "UID STORE")
+	case "COPY", "UID COPY":
+		return session.copyMsgs(args, name == "UID COPY", false)
+	case "MOVE", "UID MOVE":
+		return session.copyMsgs(args, name == "UID MOVE", true)
+	case "SEARCH", "UID SEARCH":
+		return session.search(args, name == "UID SEARCH")
+	}
+	return "", errBad("Unknown command")
+}
+
+func (session *imapSession) login(args []any) (string, error) {
+	if len(args) != 2 {
+		return "", errBad("LOGIN expects user and password")
+	}
+	if session.user != nil {
+		return "", errBad("Already authenticated")
+	}
+	session.user = session.srv.lookup(atom(args[0]), atom(args[1]))
+	if session.user == nil {
+		return "", errNo("[AUTHENTICATIONFAILED] Invalid credentials")
+	}
+	return fmt.Sprintf("[CAPABILITY %s] Logged in", imapCapabilities), nil
+}
+
+func (session *imapSession) authenticate(tag string, args []any) bool {
+	if len(args) == 0 || !strings.EqualFold(atom(args[0]), "PLAIN") {
+		session.send("%s NO Unsupported authentication mechanism", tag)
+		return true
+	}
+	var response string
+	if len(args) > 1 {
+		response = atom(args[1])
+	} else {
+		session.send("+ ")
+		if session.flush() != nil {
+			return false
+		}
+		line, err := session.r.ReadString('\n')
+		if err != nil {
+			return false
+		}
+		response = strings.TrimRight(line, "\r\n")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	parts := strings.SplitN(string(decoded), "\x00", 3)
+	if err != nil || len(parts) != 3 {
+		session.send("%s BAD Invalid response", tag)
+		return true
+	}
+
+	session.srv.mu.Lock()
+	defer session.srv.mu.Unlock()
+	if session.user != nil {
+		session.send("%s BAD Already authenticated", tag)
+		return true
+	}
+	session.user = session.srv.lookup(parts[1], parts[2])
+	if session.user == nil {
+		session.send("%s NO [AUTHENTICATIONFAILED] Invalid credentials", tag)
+		return true
+	}
+	session.send("%s OK [CAPABILITY %s] Logged in", tag, imapCapabilities)
+	return true
+}
+
+func (session *imapSession) idle(tag string) bool {
+	session.srv.mu.Lock()
+	authenticated := session.user != nil
+	session.srv.mu.Unlock()
+	if !authenticated {
+		session.send("%s NO Not authenticated", tag)
+		return true
+	}
+
+	session.send("+ idling")
+	done := make(chan error, 1)
+	go func() {
+		line, err := session.r.ReadString('\n')
+		if err == nil && !strings.EqualFold(strings.TrimSpace(line), "DONE") {
+			err = errBad("Expected DONE")
+		}
+		done <- err
+	}()
+	for {
+		session.srv.mu.Lock()
+		session.sendUpdates(true)
+		session.srv.mu.Unlock()
+		if session.flush() != nil {
+			return false
+		}
+		select {
+		case err := <-done:
+			var badErr *imapErr
+			if errors.As(err, &badErr) {
+				session.send("%s %s", tag, badErr)
+				return true
+			} else if err != nil {
+				return false
+			}
+			session.send("%s OK IDLE terminated", tag)
+			return true
+		case <-session.updates:
+		}
+	}
+}
+
+func (session *imapSession) selectFolder(args []any, readOnly bool) (string, error) {
+	if len(args) < 1 {
+		return "", errBad("Missing mailbox name")
+	}
+	session.unselect()
+	f := session.user.folder(atom(args[0]))
+	if f == nil {
+		return "", errNo("[NONEXISTENT] Mailbox doesn't exist")
+	}
+	session.selected = f
+	session.readOnly = readOnly
+	session.view = f.uids()
+	f.subscribers[session.updates] = struct{}{}
+
+	session.send("* FLAGS (%s)", imapSystemFlags)
+	session.send("* %d EXISTS", len(session.view))
+	session.send("* 0 RECENT")
+	session.send(`* OK [PERMANENTFLAGS (%s \*)] Flags permitted`, imapSystemFlags)
+	session.send("* OK [UIDVALIDITY %d] UIDs valid", f.uidValidity)
+	session.send("* OK [UIDNEXT %d] Predicted next UID", f.uidNext)
+	if readOnly {
+		return "[READ-ONLY] EXAMINE completed", nil
+	}
+	return "[READ-WRITE] SELECT completed", nil
+}
+
+// unselect must be called with srv.mu locked.
+func (session *imapSession) unselect() {
+	if session.selected != nil {
+		delete(session.selected.subscribers, session.updates)
+	}
+	session.selected = nil
+	session.view = nil
+}
+
+func (session *imapSession) create(args []any) (string, error) {
+	if len(args) < 1 {
+		return "", errBad("Missing mailbox name")
+	}
+	name := strings.TrimSuffix(atom(args[0]), ".")
+	if session.user.folder(name) != nil {
+		return "", errNo("[ALREADYEXISTS] Mailbox already exists")
+	}
+	session.srv.addFolder(session.user, name)
+	return "", nil
+}
+
+func (session *imapSession) delete(args []any) (string, error) {
+	if len(args) < 1 {
+		return "", errBad("Missing mailbox name")
+	}
+	f := session.user.folder(atom(args[0]))
+	if f == nil {
+		return "", errNo("[NONEXISTENT] Mailbox doesn't exist")
+	}
+	if f.name == "INBOX" {
+		return "", errNo("Cannot delete INBOX")
+	}
+	if session.selected == f {
+		session.unselect()
+	}
+	delete(session.user.folders, folderKey(f.name))
+	return "", nil
+}
+
+func (session *imapSession) list(name string, args []any) (string, error) {
+	// skip LIST-EXTENDED selection options
+	if len(args) > 0 {
+		if _, ok := args[0].([]any); ok {
+			args = args[1:]
+		}
+	}
+	if len(args) < 2 {
+		return "", errBad("LIST expects reference and mailbox name")
+	}
+	pattern := atom(args[0]) + atom(args[1])
+	if atom(args[1]) == "" {
+		session.send(`* %s (\Noselect) "." ""`, name)
+		return "", nil
+	}
+	names := make([]string, 0, len(session.user.folders))
+	for _, f := range session.user.folders {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	for _, folderName := range names {
+		if matchMailbox(pattern, folderName) {
+			session.send(`* %s (\HasNoChildren) "." %s`, name, quote(folderName))
+		}
+	}
+	return "", nil
+}
+
+func (session *imapSession) status(args []any) (string, error) {
+	if len(args) != 2 {
+		return "", errBad("STATUS expects mailbox name and items")
+	}
+	f := session.user.folder(atom(args[0]))
+	if f == nil {
+		return "", errNo("[NONEXISTENT] Mailbox doesn't exist")
+	}
+	items, _ := args[1].([]any)
+	var result []string
+	for _, item := range items {
+		switch item := strings.ToUpper(atom(item)); item {
+		case "MESSAGES":
+			result = append(result, fmt.Sprintf("MESSAGES %d", len(f.messages)))
+		case "RECENT":
+			result = append(result, "RECENT 0")
+		case "UIDNEXT":
+			result = append(result, fmt.Sprintf("UIDNEXT %d", f.uidNext))
+		case "UIDVALIDITY":
+			result = append(result, fmt.Sprintf("UIDVALIDITY %d", f.uidValidity))
+		case "UNSEEN":
+			unseen := 0
+			for _, msg := range f.messages {
+				if !msg.hasFlag(`\Seen`) {
+					unseen++
+				}
+			}
+			result = append(result, fmt.Sprintf("UNSEEN %d", unseen))
+		default:
+			return "", errBad("Unsupported STATUS item: %s", item)
+		}
+	}
+	session.send("* STATUS %s (%s)", quote(f.name), strings.Join(result, " "))
+	return "", nil
+}
+
+func (session *imapSession) appendMsg(args []any) (string, error) {
+	if len(args) < 2 {
+		return "", errBad("APPEND expects mailbox name and message")
+	}
+	f := session.user.folder(atom(args[0]))
+	if f == nil {
+		return "", errNo("[TRYCREATE] Mailbox doesn't exist")
+	}
+	msg := &message{internalDate: time.Now(), flags: []string{}, body: []byte(atom(args[len(args)-1]))}
+	for _, arg := range args[1 : len(args)-1] {
+		if flags, ok := arg.([]any); ok {
+			for _, flag := range flags {
+				msg.setFlag(atom(flag), true)
+			}
+		} else if date, err := time.Parse(imapDateLayout, strings.TrimSpace(atom(arg))); err == nil {
+			msg.internalDate = date
+		}
+	}
+	uid := f.append(msg)
+	session.sendUpdates(true)
+	return fmt.Sprintf("[APPENDUID %d %d] APPEND completed", f.uidValidity, uid), nil
+}
+
+func (session *imapSession) expunge(set seqSet) (string, error) {
+	if session.readOnly {
+		return "", errNo("Mailbox is read-only")
+	}
+	session.selected.remove(func(msg *message) bool {
+		return msg.hasFlag(`\Deleted`) && (set == nil || set.contains(msg.uid, session.maxUid()))
+	})
+	session.sendUpdates(true)
+	return "", nil
+}
+
+func (session *imapSession) fetch(args []any, uid bool) (string, error) {
+	if len(args) != 2 {
+		return "", errBad("FETCH expects sequence set and items")
+	}
+	session.sendUpdates(uid)
+	msgs, err := session.resolve(atom(args[0]), uid)
+	if err != nil {
+		return "", err
+	}
+	var items []string
+	switch arg := args[1].(type) {
+	case []any:
+		for _, item := range arg {
+			items = append(items, atom(item))
+		}
+	case string:
+		switch strings.ToUpper(arg) {
+		case "FAST", "ALL":
+			items = []string{"FLAGS", "INTERNALDATE", "RFC822.SIZE"}
+		default:
+			items = []string{arg}
+		}
+	}
+
+	for _, sm := range msgs {
+		var parts []string
+		if uid {
+			parts = append(parts, fmt.Sprintf("UID %d", sm.msg.uid))
+		}
+		seen, hasFlags := false, false
+		for _, item := range items {
+			upper := strings.ToUpper(item)
+			switch {
+			case upper == "UID":
+				if !uid {
+					parts = append(parts, fmt.Sprintf("UID %d", sm.msg.uid))
+				}
+			case upper == "FLAGS":
+				hasFlags = true
+				parts = append(parts, "FLAGS ("+strings.Join(sm.msg.flags, " ")+")")
+			case upper == "INTERNALDATE":
+				parts = append(parts, `INTERNALDATE "`+sm.msg.internalDate.Format(imapDateLayout)+`"`)
+			case upper == "RFC822.SIZE":
+				parts = append(parts, fmt.Sprintf("RFC822.SIZE %d", len(sm.msg.body)))
+			case upper == "RFC822":
+				seen = true
+				parts = append(parts, "RFC822 "+literal(sm.msg.body))
+			case upper == "RFC822.HEADER":
+				header, _ := splitMessage(sm.msg.body)
+				parts = append(parts, "RFC822.HEADER "+literal(header))
+			case upper == "RFC822.TEXT":
+				seen = true
+				_, text := splitMessage(sm.msg.body)
+				parts = append(parts, "RFC822.TEXT "+literal(text))
+			case strings.HasPrefix(upper, "BODY[") || strings.HasPrefix(upper, "BODY.PEEK["):
+				name, data, err := fetchSection(item, sm.msg)
+				if err != nil {
+					return "", err
+				}
+				seen = seen || !strings.HasPrefix(upper, "BODY.PEEK[")
+				parts = append(parts, name+" "+literal(data))
+			default:
+				return "", errBad("Unsupported FETCH item: %s", item)
+			}
+		}
+		if seen && !session.readOnly && !sm.msg.hasFlag(`\Seen`) {
+			sm.msg.setFlag(`\Seen`, true)
+			if !hasFlags {
+				parts = append(parts, "FLAGS ("+strings.Join(sm.msg.flags, " ")+")")
+			}
+		}
+		session.send("* %d FETCH (%s)", sm.seq, strings.Join(parts, " "))
+	}
+	return "", nil
+}
+
+func (session *imapSession) store(args []any, uid bool) (string, error) {
+	if len(args) != 3 {
+		return "", errBad("STORE expects sequence set, action and flags")
+	}
+	if session.readOnly {
+		return "", errNo("Mailbox is read-only")
+	}
+	session.sendUpdates(uid)
+	msgs, err := session.resolve(atom(args[0]), uid)
+	if err != nil {
+		return "", err
+	}
+	action := strings.ToUpper(atom(args[1]))
+	silent := strings.HasSuffix(action, ".SILENT")
+	action = strings.TrimSuffix(action, ".SILENT")
+	var flags []string
+	switch arg := args[2].(type) {
+	case []any:
+		for _, flag := range arg {
+			flags = append(flags, atom(flag))
+		}
+	case string:
+		flags = strings.Fields(arg)
+	}
+
+	for _, sm := range msgs {
+		switch action {
+		case "FLAGS":
+			sm.msg.flags = []string{}
+			fallthrough
+		case "+FLAGS":
+			for _, flag := range flags {
+				sm.msg.setFlag(flag, true)
+			}
+		case "-FLAGS":
+			for _, flag := range flags {
+				sm.msg.setFlag(flag, false)
+			}
+		default:
+			return "", errBad("Invalid STORE action")
+		}
+		if !silent {
+			if uid {
+				session.send("* %d FETCH (UID %d FLAGS (%s))", sm.seq, sm.msg.uid, strings.Join(sm.msg.flags, " "))
+			} else {
+				session.send("* %d FETCH (FLAGS (%s))", sm.seq, strings.Join(sm.msg.flags, " "))
+			}
+		}
+	}
+	return "", nil
+}
+
+func (session *imapSession) copyMsgs(args []any, uid, move bool) (string, error) {
+	if len(args) != 2 {
+		return "", errBad("Expected sequence set and mailbox name")
+	}
+	if move && session.readOnly {
+		return "", errNo("Mailbox is read-only")
+	}
+	session.sendUpdates(uid)
+	msgs, err := session.resolve(atom(args[0]), uid)
+	if err != nil {
+		return "", err
+	}
+	dest := session.user.folder(atom(args[1]))
+	if dest == nil {
+		return "", errNo("[TRYCREATE] Mailbox doesn't exist")
+	}
+
+	var srcUids, destUids []string
+	moved := make(map[*message]bool, len(msgs))
+	for _, sm := range msgs {
+		copied := &message{internalDate: sm.msg.internalDate, body: sm.msg.body, flags: slices.Clone(sm.msg.flags)}
+		srcUids = append(srcUids, strconv.FormatUint(uint64(sm.msg.uid), 10))
+		destUids = append(destUids, strconv.FormatUint(uint64(dest.append(copied)), 10))
+		moved[sm.msg] = true
+	}
+	var copyUid string
+	if len(msgs) > 0 {
+		copyUid = fmt.Sprintf("[COPYUID %d %s %s] ", dest.uidValidity, strings.Join(srcUids, ","), strings.Join(destUids, ","))
+	}
+	if !move {
+		return copyUid + "COPY completed", nil
+	}
+	if copyUid != "" {
+		session.send("* OK %sMoved", copyUid)
+	}
+	session.selected.remove(func(msg *message) bool { return moved[msg] })
+	session.sendUpdates(true)
+	return "MOVE completed", nil
+}
+
+func (session *imapSession) search(args []any, uid bool) (string, error) {
+	session.sendUpdates(uid)
+	if len(args) >= 2 && strings.EqualFold(atom(args[0]), "CHARSET") {
+		args = args[2:]
+	}
+	match, rest, err := session.parseSearchKeys(args)
+	if err != nil {
+		return "", err
+	}
+	if len(rest) > 0 {
+		return "", errBad("Unexpected search key")
+	}
+	results := []string{""}
+	for i, u := range session.view {
+		msg := session.selected.byUid(u)
+		if msg == nil || !match(uint32(i+1), msg) {
+			continue
+		}
+		if uid {
+			results = append(results, strconv.FormatUint(uint64(u), 10))
+		} else {
+			results = append(results, strconv.Itoa(i+1))
+		}
+	}
+	session.send("* SEARCH%s", strings.Join(results, " "))
+	return "", nil
+}
+
+type searchFunc func(seq uint32, msg *message) bool
+
+// parseSearchKeys parses all the given search keys, they are ANDed together.
+func (session *imapSession) parseSearchKeys(args []any) (searchFunc, []any, error) {
+	var funcs []searchFunc
+	for len(args) > 0 {
+		var match searchFunc
+		var err error
+		match, args, err = session.parseSearchKey(args)
+		if err != nil {
+			return nil, nil, err
+		}
+		funcs = append(funcs, match)
+	}
+	return func(seq uint32, msg *message) bool {
+		for _, match := range funcs {
+			if !match(seq, msg) {
+				return false
+			}
+		}
+		return true
+	}, nil, nil
+}
+
+func (session *imapSession) parseSearchKey(args []any) (searchFunc, []any, error) {
+	if list, ok := args[0].([]any); ok {
+		match, _, err := session.parseSearchKeys(list)
+		return match, args[1:], err
+	}
+	key := strings.ToUpper(atom(args[0]))
+	args = args[1:]
+	flagKeys := map[string]string{
+		"SEEN": `\Seen`, "DELETED": `\Deleted`, "FLAGGED": `\Flagged`, "ANSWERED": `\Answered`, "DRAFT": `\Draft`,
+	}
+	if flag, ok := flagKeys[key]; ok {
+		return func(_ uint32, msg *message) bool { return msg.hasFlag(flag) }, args, nil
+	}
+	if flag, ok := flagKeys[strings.TrimPrefix(key, "UN")]; ok && strings.HasPrefix(key, "UN") {
+		return func(_ uint32, msg *message) bool { return !msg.hasFlag(flag) }, args, nil
+	}
+
+	switch key {
+	case "ALL":
+		return func(uint32, *message) bool { return true }, args, nil
+	case "NOT":
+		if len(args) == 0 {
+			return nil, nil, errBad("Missing search key")
+		}
+		match, rest, err := session.parseSearchKey(args)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(seq uint32, msg *message) bool { return !match(seq, msg) }, rest, nil
+	case "OR":
+		if len(args) < 2 {
+			return nil, nil, errBad("Missing search key")
+		}
+		match1, rest, err := session.parseSearchKey(args)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			return nil, nil, errBad("Missing search key")
+		}
+		match2, rest, err := session.parseSearchKey(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(seq uint32, msg *message) bool { return match1(seq, msg) || match2(seq, msg) }, rest, nil
+	case "KEYWORD", "UNKEYWORD":
+		if len(args) == 0 {
+			return nil, nil, errBad("Missing keyword")
+		}
+		flag := atom(args[0])
+		return func(_ uint32, msg *message) bool { return msg.hasFlag(flag) == (key == "KEYWORD") }, args[1:], nil
+	case "UID":
+		if len(args) == 0 {
+			return nil, nil, errBad("Missing UID set")
+		}
+		set, err := parseSeqSet(atom(args[0]))
+		if err != nil {
+			return nil, nil, err
+		}
+		max := session.maxUid()
+		return func(_ uint32, msg *message) bool { return set.contains(msg.uid, max) }, args[1:], nil
+	case "HEADER":
+		if len(args) < 2 {
+			return nil, nil, errBad("HEADER expects field name and value")
+		}
+		field, value := atom(args[0]), strings.ToLower(atom(args[1]))
+		return func(_ uint32, msg *message) bool {
+			header, _ := splitMessage(msg.body)
+			filtered := string(filterHeader(header, []string{field}, true))
+			return strings.TrimSpace(filtered) != "" && strings.Contains(strings.ToLower(filtered), value)
+		}, args[2:], nil
+	case "SINCE", "BEFORE", "ON":
+		if len(args) == 0 {
+			return nil, nil, errBad("Missing date")
+		}
+		date, err := time.Parse("2-Jan-2006", atom(args[0]))
+		if err != nil {
+			return nil, nil, errBad("Invalid date")
+		}
+		return func(_ uint32, msg *message) bool {
+			y, m, d := msg.internalDate.Date()
+			day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+			switch key {
+			case "SINCE":
+				return !day.Before(date)
+			case "BEFORE":
+				return day.Before(date)
+			default:
+				return day.Equal(date)
+			}
+		}, args[1:], nil
+	}
+
+	set, err := parseSeqSet(key)
+	if err != nil {
+		return nil, nil, errBad("Unsupported search key: %s", key)
+	}
+	max := uint32(len(session.view))
+	return func(seq uint32, _ *message) bool { return set.contains(seq, max) }, args, nil
+}
+
+type seqMsg struct {
+	seq uint32
+	msg *message
+}
+
+// resolve returns the messages of the selected folder matching the given sequence set.
+func (session *imapSession) resolve(setStr string, uid bool) ([]seqMsg, error) {
+	set, err := parseSeqSet(setStr)
+	if err != nil {
+		return nil, err
+	}
+	max := uint32(len(session.view))
+	if uid {
+		max = session.maxUid()
+	}
+	var msgs []seqMsg
+	for i, u := range session.view {
+		n := uint32(i + 1)
+		if uid {
+			n = u
+		}
+		if !set.contains(n, max) {
+			continue
+		}
+		if msg := session.selected.byUid(u); msg != nil {
+			msgs = append(msgs, seqMsg{seq: uint32(i + 1), msg: msg})
+		}
+	}
+	return msgs, nil
+}
+
+func (session *imapSession) maxUid() uint32 {
+	if len(session.view) == 0 {
+		return 0
+	}
+	return session.view[len(session.view)-1]
+}
+
+// sendUpdates reports new messages to the client and, if expunge is true, also the removed messages.
+// It must be called with srv.mu locked.
+func (session *imapSession) sendUpdates(expunge bool) {
+	if session.selected == nil {
+		return
+	}
+	current := session.selected.uids()
+	view := session.view[:0:0]
+	// expunged messages are reported in descending order so the sequence numbers stay valid
+	for i := len(session.view) - 1; i >= 0; i-- {
+		if _, found := slices.BinarySearch(current, session.view[i]); !found && expunge {
+			session.send("* %d EXPUNGE", i+1)
+		}
+	}
+	for _, u := range session.view {
+		if _, found := slices.BinarySearch(current, u); found || !expunge {
+			view = append(view, u)
+		}
+	}
+	added := false
+	for _, u := range current {
+		if len(view) == 0 || u > view[len(view)-1] {
+			view = append(view, u)
+			added = true
+		}
+	}
+	session.view = view
+	if added {
+		session.send("* %d EXISTS", len(view))
+	}
+}
+
+// readCommand reads a full command, including any literals.
+func (session *imapSession) readCommand() ([]byte, error) {
+	var cmd []byte
+	for {
+		line, err := session.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd = append(cmd, line...)
+		size, nonSync, ok := literalSize(line)
+		if !ok {
+			return cmd, nil
+		}
+		if size > maxLiteralSize {
+			return nil, errors.New("literal too big")
+		}
+		if !nonSync {
+			session.send("+ Ready for literal data")
+			if err := session.flush(); err != nil {
+				return nil, err
+			}
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(session.r, data); err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, "\r\n"...)
+		cmd = append(cmd, data...)
+	}
+}
+
+func (session *imapSession) send(format string, args ...any) {
+	fmt.Fprintf(&session.out, format, args...)
+	session.out.WriteString("\r\n")
+}
+
+func (session *imapSession) flush() error {
+	_, err := session.out.WriteTo(session.conn)
+	return err
+}
+
+// lookup returns the mailbox of the given user if the password is correct, nil otherwise.
+// It must be called with srv.mu locked.
+func (srv *Server) lookup(user, password string) *mailbox {
+	mbox, ok := srv.mailboxes[strings.ToLower(user)]
+	if !ok || mbox.password != password {
+		return nil
+	}
+	return mbox
+}
+
+// fetchSection returns the response item name and data for a BODY[section]<partial> fetch item.
+func fetchSection(item string, msg *message) (string, []byte, error) {
+	start := strings.IndexByte(item, '[')
+	end := strings.LastIndexByte(item, ']')
+	if start < 0 || end < start {
+		return "", nil, errBad("Invalid section: %s", item)
+	}
+	section, partial := item[start+1:end], item[end+1:]
+	header, text := splitMessage(msg.body)
+	var data []byte
+	switch upper := strings.ToUpper(section); {
+	case section == "":
+		data = msg.body
+	case upper == "HEADER":
+		data = header
+	case upper == "TEXT":
+		data = text
+	case strings.HasPrefix(upper, "HEADER.FIELDS"):
+		open, closing := strings.IndexByte(section, '('), strings.LastIndexByte(section, ')')
+		if open < 0 || closing < open {
+			return "", nil, errBad("Invalid section: %s", section)
+		}
+		fields := strings.Fields(section[open+1 : closing])
+		data = filterHeader(header, fields, !strings.HasPrefix(upper, "HEADER.FIELDS.NOT"))
+	default:
+		return "", nil, errBad("Unsupported section: %s", section)
+	}
+
+	name := "BODY[" + section + "]"
+	if partial != "" {
+		var offset, count int
+		if _, err := fmt.Sscanf(partial, "<%d.%d>", &offset, &count); err != nil || offset < 0 || count < 0 {
+			return "", nil, errBad("Invalid partial: %s", partial)
+		}
+		offset = min(offset, len(data))
+		data = data[offset:min(offset+count, len(data))]
+		name += fmt.Sprintf("<%d>", offset)
+	}
+	return name, data, nil
+}
+
+// splitMessage splits a message in its header, including the empty separator line, and its body.
+func splitMessage(data []byte) ([]byte, []byte) {
+	index := bytes.Index(data, []byte("\r\n\r\n"))
+	if index < 0 {
+		return data, nil
+	}
+	return data[:index+4], data[index+4:]
+}
+
+// filterHeader returns the header fields with (include=true) or without (include=false) the given names.
+func filterHeader(header []byte, names []string, include bool) []byte {
+	var result bytes.Buffer
+	keep := false
+	for _, line := range strings.SplitAfter(string(header), "\r\n") {
+		if line == "\r\n" || line == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			name, _, _ := strings.Cut(line, ":")
+			found := slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, strings.TrimSpace(name)) })
+			keep = found == include
+		}
+		if keep {
+			result.WriteString(line)
+		}
+	}
+	result.WriteString("\r\n")
+	return result.Bytes()
+}
+
+func literalSize(line string) (int, bool, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false, false
+	}
+	start := strings.LastIndexByte(line, '{')
+	if start < 0 {
+		return 0, false, false
+	}
+	spec := line[start+1 : len(line)-1]
+	nonSync := strings.HasSuffix(spec, "+")
+	size, err := strconv.Atoi(strings.TrimSuffix(spec, "+"))
+	if err != nil || size < 0 {
+		return 0, false, false
+	}
+	return size, nonSync, true
+}
+
+func literal(data []byte) string {
+	return fmt.Sprintf("{%d}\r\n%s", len(data), data)
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
+
+// atom returns the string value of a parsed IMAP argument, or "" if it is a list.
+func atom(arg any) string {
+	s, _ := arg.(string)
+	return s
+}
+
+// matchMailbox matches a mailbox name against a LIST pattern, "*" matches any
+// sequence of characters and "%" any sequence without the hierarchy delimiter.
+func matchMailbox(pattern, name string) bool {
+	if pattern == "" {
+		return name == ""
+	}
+	switch pattern[0] {
+	case '*', '%':
+		for i := 0; i <= len(name); i++ {
+			if matchMailbox(pattern[1:], name[i:]) {
+				return true
+			}
+			if i < len(name) && pattern[0] == '%' && name[i] == '.' {
+				return false
+			}
+		}
+		return false
+	}
+	if name == "" || !strings.EqualFold(pattern[:1], name[:1]) {
+		return false
+	}
+	return matchMailbox(pattern[1:], name[1:])
+}
+
+type seqRange struct {
+	start, end uint32 // 0 means "*"
+}
+
+type seqSet []seqRange
+
+func parseSeqSet(s string) (seqSet, error) {
+	var set seqSet
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(part, ":")
+		start, err := parseSeqNumber(first)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			if end, err = parseSeqNumber(last); err != nil {
+				return nil, err
+			}
+		}
+		set = append(set, seqRange{start: start, end: end})
+	}
+	return set, nil
+}
+
+func parseSeqNumber(s string) (uint32, error) {
+	if s == "*" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || n == 0 {
+		return 0, errBad("Invalid sequence set")
+	}
+	return uint32(n), nil
+}
+
+// contains returns true if n is in the set, max is the value of "*".
+func (set seqSet) contains(n, max uint32) bool {
+	for _, r := range set {
+		start, end := r.start, r.end
+		if start == 0 {
+			start = max
+		}
+		if end == 0 {
+			end = max
+		}
+		if start > end {
+			start, end = end, start
+		}
+		if n >= start && n <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// imapParser tokenizes IMAP commands, atoms and strings are returned as string values
+// and parenthesized lists as []any.
+type imapParser struct {
+	data []byte
+	pos  int
+}
+
+func parseImapCommand(data []byte) ([]any, error) {
+	parser := &imapParser{data: data}
+	return parser.parseList(0)
+}
+
+func (p *imapParser) parseList(end byte) ([]any, error) {
+	items := []any{}
+	for {
+		for p.pos < len(p.data) && p.data[p.pos] == ' ' {
+			p.pos++
+		}
+		if p.pos >= len(p.data) {
+			if end != 0 {
+				return nil, errors.New("unterminated list")
+			}
+			return items, nil
+		}
+		switch c := p.data[p.pos]; {
+		case end != 0 && c == end:
+			p.pos++
+			return items, nil
+		case c == ')':
+			return nil, errors.New("unexpected )")
+		case c == '(':
+			p.pos++
+			list, err := p.parseList(')')
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, list)
+		case c == '"':
+			s, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, s)
+		case c == '{':
+			s, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, s)
+		default:
+			items = append(items, p.parseAtom())
+		}
+	}
+}
+
+func (p *imapParser) parseQuoted() (string, error) {
+	var sb strings.Builder
+	for p.pos++; p.pos < len(p.data); p.pos++ {
+		switch c := p.data[p.pos]; c {
+		case '\\':
+			p.pos++
+			if p.pos < len(p.data) {
+				sb.WriteByte(p.data[p.pos])
+			}
+		case '"':
+			p.pos++
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated quoted string")
+}
+
+func (p *imapParser) parseLiteral() (string, error) {
+	end := bytes.IndexByte(p.data[p.pos:], '}')
+	if end < 0 {
+		return "", errors.New("invalid literal")
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(string(p.data[p.pos+1:p.pos+end]), "+"))
+	if err != nil || size < 0 {
+		return "", errors.New("invalid literal size")
+	}
+	p.pos += end + 1
+	if !bytes.HasPrefix(p.data[p.pos:], []byte("\r\n")) || p.pos+2+size > len(p.data) {
+		return "", errors.New("truncated literal")
+	}
+	p.pos += 2
+	s := string(p.data[p.pos : p.pos+size])
+	p.pos += size
+	return s, nil
+}
+
+// parseAtom reads an atom, brackets are allowed to contain spaces and parentheses
+// as in BODY.PEEK[HEADER.FIELDS (FROM TO)].
+func (p *imapParser) parseAtom() string {
+	start := p.pos
+	depth := 0
+	for ; p.pos < len(p.data); p.pos++ {
+		c := p.data[p.pos]
+		if depth == 0 && (c == ' ' || c == '(' || c == ')') {
+			break
+		}
+		if c == '[' {
+			depth++
+		} else if c == ']' && depth > 0 {
+			depth--
+		}
+	}
+	return string(p.data[start:p.pos])
+}
@@ -0,0 +1,228 @@
+package mailserver
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type imapTestClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+func dialImap(t *testing.T, srv *Server) *imapTestClient {
+	conn, err := net.Dial("tcp", net.JoinHostPort(srv.Host, strconv.Itoa(int(srv.ImapPort()))))
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() }) //nolint:errcheck
+	client := &imapTestClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	require.Contains(t, client.readLine(), "* OK")
+	return client
+}
+
+func (c *imapTestClient) readLine() string {
+	require.Nil(c.t, c.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	line, err := c.r.ReadString('\n')
+	require.Nil(c.t, err)
+	return line
+}
+
+// cmd sends a command and returns all the response lines, the last one is the tagged response.
+func (c *imapTestClient) cmd(format string, args ...any) []string {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	_, err := fmt.Fprintf(c.conn, tag+" "+format+"\r\n", args...)
+	require.Nil(c.t, err)
+	var lines []string
+	for {
+		line := c.readLine()
+		lines = append(lines, line)
+		if strings.HasPrefix(line, tag+" ") {
+			return lines
+		}
+	}
+}
+
+func (c *imapTestClient) ok(format string, args ...any) []string {
+	lines := c.cmd(format, args...)
+	require.Contains(c.t, lines[len(lines)-1], " OK ", strings.Join(lines, ""))
+	return lines
+}
+
+func (c *imapTestClient) login(addr, password string) {
+	c.ok("LOGIN %q %q", addr, password)
+}
+
+func TestImap_Login(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	addr, password := srv.NewUser()
+	client := dialImap(t, srv)
+
+	lines := client.cmd("SELECT INBOX")
+	require.Contains(t, lines[0], "NO Not authenticated")
+	lines = client.cmd("LOGIN %q wrong", addr)
+	require.Contains(t, lines[0], "NO [AUTHENTICATIONFAILED]")
+	client.login(addr, password)
+	require.Contains(t, client.ok("CAPABILITY")[0], "IDLE")
+	lines = client.cmd("LOGOUT")
+	require.Contains(t, lines[0], "* BYE")
+}
+
+func TestImap_Authenticate(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	srv.AddUser("alice@example.org", "secret")
+	client := dialImap(t, srv)
+	// base64("\x00alice@example.org\x00secret")
+	client.ok("AUTHENTICATE PLAIN AGFsaWNlQGV4YW1wbGUub3JnAHNlY3JldA==")
+}
+
+func TestImap_FetchStoreExpunge(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	addr, password := srv.NewUser()
+	body := "From: bob@example.org\r\nSubject: test\r\n subject continuation\r\nMessage-ID: <1@example.org>\r\n\r\nhello\r\n"
+	require.Nil(t, srv.Deliver([]string{addr}, []byte(body)))
+	require.Nil(t, srv.Deliver([]string{addr}, []byte(body)))
+
+	client := dialImap(t, srv)
+	client.login(addr, password)
+	lines := client.ok("SELECT INBOX")
+	require.Contains(t, strings.Join(lines, ""), "* 2 EXISTS")
+	require.Contains(t, strings.Join(lines, ""), "[UIDNEXT 3]")
+
+	lines = client.ok("UID FETCH 1:* (UID FLAGS RFC822.SIZE BODY.PEEK[HEADER.FIELDS (SUBJECT MESSAGE-ID)])")
+	response := strings.Join(lines, "")
+	require.Contains(t, response, "* 1 FETCH (UID 1 FLAGS () RFC822.SIZE")
+	require.Contains(t, response, "BODY[HEADER.FIELDS (SUBJECT MESSAGE-ID)] {")
+	require.Contains(t, response, "Subject: test\r\n subject continuation\r\nMessage-ID: <1@example.org>\r\n\r\n")
+	require.NotContains(t, response, "From:")
+
+	lines = client.ok("UID FETCH 2 (BODY[])")
+	response = strings.Join(lines, "")
+	require.Contains(t, response, fmt.Sprintf("BODY[] {%d}\r\n%s", len(body), body))
+	require.Contains(t, response, `FLAGS (\Seen)`)
+
+	lines = client.ok("UID SEARCH UNSEEN")
+	require.Equal(t, "* SEARCH 1\r\n", lines[0])
+	lines = client.ok("FETCH 1 (BODY.PEEK[TEXT]<0.2>)")
+	require.Contains(t, strings.Join(lines, ""), "BODY[TEXT]<0> {2}\r\nhe)")
+
+	lines = client.ok(`UID STORE 1 +FLAGS (\Deleted)`)
+	require.Contains(t, lines[0], `FLAGS (\Deleted)`)
+	lines = client.ok("EXPUNGE")
+	require.Equal(t, "* 1 EXPUNGE\r\n", lines[0])
+	require.Equal(t, 1, srv.MessageCount(addr, "INBOX"))
+}
+
+func TestImap_CreateMoveCopy(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	addr, password := srv.NewUser()
+	require.Nil(t, srv.Deliver([]string{addr}, []byte("Subject: a\r\n\r\na\r\n")))
+	require.Nil(t, srv.Deliver([]string{addr}, []byte("Subject: b\r\n\r\nb\r\n")))
+
+	client := dialImap(t, srv)
+	client.login(addr, password)
+	client.ok("CREATE DeltaChat")
+	require.Contains(t, client.cmd("CREATE DeltaChat")[0], "NO [ALREADYEXISTS]")
+	lines := client.ok(`LIST "" "*"`)
+	require.Equal(t, `* LIST (\HasNoChildren) "." "DeltaChat"`+"\r\n", lines[0])
+	require.Equal(t, `* LIST (\HasNoChildren) "." "INBOX"`+"\r\n", lines[1])
+
+	client.ok("SELECT INBOX")
+	require.Contains(t, client.cmd("UID COPY 1 Unknown")[0], "NO [TRYCREATE]")
+	lines = client.ok("UID COPY 1 DeltaChat")
+	require.Contains(t, lines[0], "[COPYUID ")
+	lines = client.ok("UID MOVE 2 DeltaChat")
+	require.Contains(t, lines[0], "* OK [COPYUID ")
+	require.Equal(t, "* 2 EXPUNGE\r\n", lines[1])
+	require.Equal(t, 1, srv.MessageCount(addr, "INBOX"))
+	require.Equal(t, 2, srv.MessageCount(addr, "DeltaChat"))
+
+	lines = client.ok("STATUS DeltaChat (MESSAGES UIDNEXT UNSEEN)")
+	require.Equal(t, `* STATUS "DeltaChat" (MESSAGES 2 UIDNEXT 3 UNSEEN 2)`+"\r\n", lines[0])
+
+	_, err := fmt.Fprintf(client.conn, "a100 APPEND DeltaChat (\\Seen) {5}\r\n")
+	require.Nil(t, err)
+	require.Contains(t, client.readLine(), "+ ")
+	_, err = fmt.Fprintf(client.conn, "\r\n\r\nx\r\n")
+	require.Nil(t, err)
+	require.Contains(t, client.readLine(), "a100 OK [APPENDUID ")
+	require.Equal(t, 3, srv.MessageCount(addr, "DeltaChat"))
+}
+
+func TestImap_Idle(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	addr, password := srv.NewUser()
+
+	client := dialImap(t, srv)
+	client.login(addr, password)
+	client.ok("SELECT INBOX")
+	_, err := fmt.Fprintf(client.conn, "a100 IDLE\r\n")
+	require.Nil(t, err)
+	require.Contains(t, client.readLine(), "+ idling")
+
+	require.Nil(t, srv.Deliver([]string{addr}, []byte("Subject: a\r\n\r\na\r\n")))
+	require.Equal(t, "* 1 EXISTS\r\n", client.readLine())
+
+	_, err = fmt.Fprintf(client.conn, "DONE\r\n")
+	require.Nil(t, err)
+	require.Contains(t, client.readLine(), "a100 OK")
+}
+
+func TestImap_parseImapCommand(t *testing.T) {
+	t.Parallel()
+	args, err := parseImapCommand([]byte(`a1 UID FETCH 1:* (UID BODY.PEEK[HEADER.FIELDS (FROM TO)]) "quoted \"str\"" {3}` + "\r\nabc"))
+	require.Nil(t, err)
+	require.Equal(t, []any{"a1", "UID", "FETCH", "1:*", []any{"UID", "BODY.PEEK[HEADER.FIELDS (FROM TO)]"}, `quoted "str"`, "abc"}, args)
+
+	_, err = parseImapCommand([]byte(`a1 (unterminated`))
+	require.NotNil(t, err)
+	_, err = parseImapCommand([]byte(`a1 "unterminated`))
+	require.NotNil(t, err)
+	_, err = parseImapCommand([]byte("a1 {10}\r\nabc"))
+	require.NotNil(t, err)
+	_, err = parseImapCommand([]byte(`a1 )`))
+	require.NotNil(t, err)
+}
+
+func TestImap_seqSet(t *testing.T) {
+	t.Parallel()
+	set, err := parseSeqSet("1,3:5,7:*")
+	require.Nil(t, err)
+	for n, expected := range map[uint32]bool{1: true, 2: false, 4: true, 6: false, 7: true, 9: true} {
+		require.Equal(t, expected, set.contains(n, 9), "n=%d", n)
+	}
+	// "n:*" with n greater than the maximum matches the maximum
+	set, err = parseSeqSet("10:*")
+	require.Nil(t, err)
+	require.True(t, set.contains(9, 9))
+	require.False(t, set.contains(8, 9))
+
+	_, err = parseSeqSet("0")
+	require.NotNil(t, err)
+	_, err = parseSeqSet("1:x")
+	require.NotNil(t, err)
+}
+
+func TestImap_matchMailbox(t *testing.T) {
+	t.Parallel()
+	require.True(t, matchMailbox("*", "INBOX"))
+	require.True(t, matchMailbox("inbox", "INBOX"))
+	require.True(t, matchMailbox("%", "DeltaChat"))
+	require.False(t, matchMailbox("%", "a.b"))
+	require.True(t, matchMailbox("*", "a.b"))
+	require.True(t, matchMailbox("a.%", "a.b"))
+	require.False(t, matchMailbox("b*", "a.b"))
+}
@@ -0,0 +1,364 @@
+// Package mailserver provides a minimal in-memory SMTP and IMAP server.
+//
+// It implements just enough of both protocols (SMTP submission with AUTH, IMAP with IDLE, MOVE
+// and UIDPLUS) for deltachat-rpc-server to configure accounts and exchange messages on
+// localhost, so that tests can run without internet access. It is not meant to be used as a
+// real mail server: messages are kept in memory and connections are not encrypted.
+package mailserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an in-memory SMTP+IMAP server listening on localhost.
+//
+// Typical usage is as follows:
+//
+//	srv := mailserver.NewServer()
+//	if err := srv.Start(); err != nil {
+//		panic(err)
+//	}
+//	defer srv.Close()
+//	addr, password := srv.NewUser()
+type Server struct {
+	// Domain used for the addresses created with NewUser().
+	Domain string
+	// Host is the IP address the server listens on.
+	Host string
+	// If Log is not nil, the commands received by the server are written to it.
+	Log io.Writer
+
+	mu            sync.Mutex
+	mailboxes     map[string]*mailbox
+	smtpListener  net.Listener
+	imapListener  net.Listener
+	conns         map[net.Conn]struct{}
+	wg            sync.WaitGroup
+	started       bool
+	uidValidities uint32
+}
+
+// NewServer creates a new Server for the "example.org" domain listening on 127.0.0.1.
+func NewServer() *Server {
+	return &Server{Domain: "example.org", Host: "127.0.0.1", mailboxes: make(map[string]*mailbox)}
+}
+
+// Start listens for SMTP and IMAP connections on random free ports of Server.Host.
+// If the server is already started, ServerStartedErr is returned.
+func (srv *Server) Start() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.started {
+		return &ServerStartedErr{}
+	}
+
+	smtpListener, err := net.Listen("tcp", net.JoinHostPort(srv.Host, "0"))
+	if err != nil {
+		return err
+	}
+	imapListener, err := net.Listen("tcp", net.JoinHostPort(srv.Host, "0"))
+	if err != nil {
+		smtpListener.Close() //nolint:errcheck
+		return err
+	}
+	srv.smtpListener = smtpListener
+	srv.imapListener = imapListener
+	srv.conns = make(map[net.Conn]struct{})
+	srv.started = true
+
+	srv.wg.Add(2)
+	go srv.acceptLoop(smtpListener, srv.serveSmtp)
+	go srv.acceptLoop(imapListener, srv.serveImap)
+	return nil
+}
+
+// Close stops listening and closes all active connections.
+func (srv *Server) Close() {
+	srv.mu.Lock()
+	if !srv.started {
+		srv.mu.Unlock()
+		return
+	}
+	srv.started = false
+	srv.smtpListener.Close() //nolint:errcheck
+	srv.imapListener.Close() //nolint:errcheck
+	for conn := range srv.conns {
+		conn.Close() //nolint:errcheck
+	}
+	srv.mu.Unlock()
+
+	srv.wg.Wait()
+}
+
+// SmtpPort returns the port the SMTP server is listening on.
+func (srv *Server) SmtpPort() uint16 {
+	return listenerPort(srv.smtpListener)
+}
+
+// ImapPort returns the port the IMAP server is listening on.
+func (srv *Server) ImapPort() uint16 {
+	return listenerPort(srv.imapListener)
+}
+
+// AddUser creates a mailbox for the given address, if the mailbox already exists
+// only its password is updated.
+func (srv *Server) AddUser(addr, password string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	key := strings.ToLower(addr)
+	if mbox, ok := srv.mailboxes[key]; ok {
+		mbox.password = password
+		return
+	}
+	mbox := &mailbox{addr: addr, password: password, folders: make(map[string]*folder)}
+	srv.addFolder(mbox, "INBOX")
+	srv.mailboxes[key] = mbox
+}
+
+// NewUser creates a mailbox with a random address in Server.Domain and returns its credentials.
+func (srv *Server) NewUser() (addr, password string) {
+	addr = "ci-" + randomHex(4) + "@" + srv.Domain
+	password = randomHex(8)
+	srv.AddUser(addr, password)
+	return addr, password
+}
+
+// Deliver stores the given RFC 5322 message in the INBOX of every recipient.
+// If any of the recipients does not exist, UnknownUserErr is returned and nothing is delivered.
+func (srv *Server) Deliver(to []string, data []byte) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	targets := make([]*mailbox, 0, len(to))
+	for _, addr := range to {
+		mbox, ok := srv.mailboxes[strings.ToLower(addr)]
+		if !ok {
+			return &UnknownUserErr{Addr: addr}
+		}
+		targets = append(targets, mbox)
+	}
+	for _, mbox := range targets {
+		mbox.folders["INBOX"].append(&message{internalDate: time.Now(), body: data, flags: []string{}})
+	}
+	return nil
+}
+
+// MessageCount returns the number of messages in the given folder of the given mailbox.
+func (srv *Server) MessageCount(addr, folderName string) int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	mbox, ok := srv.mailboxes[strings.ToLower(addr)]
+	if !ok {
+		return 0
+	}
+	f := mbox.folder(folderName)
+	if f == nil {
+		return 0
+	}
+	return len(f.messages)
+}
+
+func (srv *Server) acceptLoop(listener net.Listener, serve func(net.Conn)) {
+	defer srv.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		srv.mu.Lock()
+		if !srv.started {
+			srv.mu.Unlock()
+			conn.Close() //nolint:errcheck
+			return
+		}
+		srv.conns[conn] = struct{}{}
+		srv.wg.Add(1)
+		srv.mu.Unlock()
+
+		go func() {
+			defer srv.wg.Done()
+			defer func() {
+				srv.mu.Lock()
+				delete(srv.conns, conn)
+				srv.mu.Unlock()
+				conn.Close() //nolint:errcheck
+			}()
+			serve(conn)
+		}()
+	}
+}
+
+// login returns the mailbox of the given user if the password is correct, nil otherwise.
+func (srv *Server) login(user, password string) *mailbox {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return srv.lookup(user, password)
+}
+
+// addFolder must be called with srv.mu locked.
+func (srv *Server) addFolder(mbox *mailbox, name string) *folder {
+	srv.uidValidities++
+	f := &folder{
+		name:        name,
+		uidValidity: uint32(time.Now().Unix()) + srv.uidValidities,
+		uidNext:     1,
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+	mbox.folders[folderKey(name)] = f
+	return f
+}
+
+func (srv *Server) logf(format string, args ...any) {
+	if srv.Log != nil {
+		fmt.Fprintf(srv.Log, format+"\n", args...)
+	}
+}
+
+type mailbox struct {
+	addr     string
+	password string
+	folders  map[string]*folder
+}
+
+func (mbox *mailbox) folder(name string) *folder {
+	return mbox.folders[folderKey(name)]
+}
+
+type folder struct {
+	name        string
+	uidValidity uint32
+	uidNext     uint32
+	messages    []*message
+	subscribers map[chan struct{}]struct{}
+}
+
+type message struct {
+	uid          uint32
+	flags        []string
+	internalDate time.Time
+	body         []byte
+}
+
+func (f *folder) append(msg *message) uint32 {
+	msg.uid = f.uidNext
+	f.uidNext++
+	f.messages = append(f.messages, msg)
+	f.notify()
+	return msg.uid
+}
+
+func (f *folder) byUid(uid uint32) *message {
+	for _, msg := range f.messages {
+		if msg.uid == uid {
+			return msg
+		}
+	}
+	return nil
+}
+
+func (f *folder) uids() []uint32 {
+	uids := make([]uint32, len(f.messages))
+	for i, msg := range f.messages {
+		uids[i] = msg.uid
+	}
+	return uids
+}
+
+// remove deletes the messages for which the given function returns true.
+func (f *folder) remove(match func(*message) bool) {
+	kept := f.messages[:0]
+	for _, msg := range f.messages {
+		if !match(msg) {
+			kept = append(kept, msg)
+		}
+	}
+	if len(kept) != len(f.messages) {
+		f.messages = kept
+		f.notify()
+	}
+}
+
+// notify wakes up the IMAP sessions idling on this folder.
+func (f *folder) notify() {
+	for ch := range f.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (msg *message) hasFlag(flag string) bool {
+	for _, f := range msg.flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (msg *message) setFlag(flag string, value bool) {
+	if msg.hasFlag(flag) == value {
+		return
+	}
+	if value {
+		msg.flags = append(msg.flags, flag)
+		return
+	}
+	flags := msg.flags[:0]
+	for _, f := range msg.flags {
+		if !strings.EqualFold(f, flag) {
+			flags = append(flags, f)
+		}
+	}
+	msg.flags = flags
+}
+
+func folderKey(name string) string {
+	if strings.EqualFold(name, "INBOX") {
+		return "INBOX"
+	}
+	return name
+}
+
+func listenerPort(listener net.Listener) uint16 {
+	if listener == nil {
+		return 0
+	}
+	return uint16(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func randomHex(n int) string {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(data)
+}
+
+// ServerStartedErr is returned by Server.Start() if the server is already started.
+type ServerStartedErr struct{}
+
+func (e *ServerStartedErr) Error() string {
+	return "server is already started"
+}
+
+// UnknownUserErr is returned by Server.Deliver() if a recipient does not exist.
+type UnknownUserErr struct {
+	Addr string
+}
+
+func (e *UnknownUserErr) Error() string {
+	return "unknown user: " + e.Addr
+}
@@ -0,0 +1,52 @@
+package mailserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *Server {
+	srv := NewServer()
+	require.Nil(t, srv.Start())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestServer_StartTwice(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	err := srv.Start()
+	require.NotNil(t, err)
+	_, ok := err.(*ServerStartedErr)
+	require.True(t, ok)
+	require.NotEmpty(t, err.Error())
+	require.NotZero(t, srv.SmtpPort())
+	require.NotZero(t, srv.ImapPort())
+}
+
+func TestServer_CloseNotStarted(t *testing.T) {
+	t.Parallel()
+	srv := NewServer()
+	srv.Close()
+	require.Zero(t, srv.SmtpPort())
+}
+
+func TestServer_Deliver(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	addr, _ := srv.NewUser()
+	require.Contains(t, addr, "@example.org")
+
+	require.Nil(t, srv.Deliver([]string{addr}, []byte("Subject: hi\r\n\r\nhello\r\n")))
+	require.Equal(t, 1, srv.MessageCount(addr, "INBOX"))
+	require.Equal(t, 0, srv.MessageCount(addr, "Unknown"))
+	require.Equal(t, 0, srv.MessageCount("unknown@example.org", "INBOX"))
+
+	err := srv.Deliver([]string{addr, "unknown@example.org"}, []byte("\r\n"))
+	require.NotNil(t, err)
+	_, ok := err.(*UnknownUserErr)
+	require.True(t, ok)
+	require.Contains(t, err.Error(), "unknown@example.org")
+	require.Equal(t, 1, srv.MessageCount(addr, "INBOX"))
+}
@@ -0,0 +1,212 @@
+package mailserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+type smtpSession struct {
+	srv   *Server
+	tp    *textproto.Conn
+	user  *mailbox
+	from  string
+	rcpts []string
+}
+
+func (srv *Server) serveSmtp(conn net.Conn) {
+	session := &smtpSession{srv: srv, tp: textproto.NewConn(conn)}
+	session.reply("220 %s ESMTP ready", srv.Domain)
+	for {
+		line, err := session.tp.ReadLine()
+		if err != nil {
+			return
+		}
+		srv.logf("smtp C: %s", line)
+		verb, arg, _ := strings.Cut(line, " ")
+		if !session.handle(strings.ToUpper(verb), arg) {
+			return
+		}
+	}
+}
+
+// handle processes a single SMTP command, it returns false if the connection must be closed.
+func (session *smtpSession) handle(verb, arg string) bool {
+	switch verb {
+	case "EHLO":
+		session.reset()
+		session.reply("250-%s", session.srv.Domain)
+		session.reply("250-PIPELINING")
+		session.reply("250-8BITMIME")
+		session.reply("250-AUTH PLAIN LOGIN")
+		session.reply("250 ENHANCEDSTATUSCODES")
+	case "HELO":
+		session.reset()
+		session.reply("250 %s", session.srv.Domain)
+	case "AUTH":
+		session.auth(arg)
+	case "MAIL":
+		if session.user == nil {
+			session.reply("530 5.7.0 Authentication required")
+			return true
+		}
+		addr, ok := parsePath(arg, "FROM:")
+		if !ok {
+			session.reply("501 5.5.4 Syntax: MAIL FROM:<address>")
+			return true
+		}
+		session.reset()
+		session.from = addr
+		session.reply("250 2.1.0 Ok")
+	case "RCPT":
+		if session.user == nil {
+			session.reply("530 5.7.0 Authentication required")
+			return true
+		}
+		addr, ok := parsePath(arg, "TO:")
+		if !ok {
+			session.reply("501 5.5.4 Syntax: RCPT TO:<address>")
+			return true
+		}
+		if !session.srv.exists(addr) {
+			session.reply("550 5.1.1 <%s>: Recipient address rejected: User unknown", addr)
+			return true
+		}
+		session.rcpts = append(session.rcpts, addr)
+		session.reply("250 2.1.5 Ok")
+	case "DATA":
+		if len(session.rcpts) == 0 {
+			session.reply("503 5.5.1 Error: need RCPT command")
+			return true
+		}
+		session.reply("354 End data with <CR><LF>.<CR><LF>")
+		data, err := session.tp.ReadDotBytes()
+		if err != nil {
+			return false
+		}
+		// textproto converts line endings to LF, IMAP clients expect CRLF
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+		if err := session.srv.Deliver(session.rcpts, data); err != nil {
+			session.reply("554 5.0.0 %s", err)
+		} else {
+			session.reply("250 2.0.0 Ok: queued")
+		}
+		session.reset()
+	case "RSET":
+		session.reset()
+		session.reply("250 2.0.0 Ok")
+	case "NOOP":
+		session.reply("250 2.0.0 Ok")
+	case "QUIT":
+		session.reply("221 2.0.0 Bye")
+		return false
+	default:
+		session.reply("502 5.5.2 Error: command not recognized")
+	}
+	return true
+}
+
+func (session *smtpSession) auth(arg string) {
+	if session.user != nil {
+		session.reply("503 5.5.1 Error: already authenticated")
+		return
+	}
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	var user, password string
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		if initial == "" {
+			var ok bool
+			if initial, ok = session.challenge(""); !ok {
+				return
+			}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(initial)
+		if err != nil {
+			session.reply("501 5.5.2 Cannot decode response")
+			return
+		}
+		parts := strings.SplitN(string(decoded), "\x00", 3)
+		if len(parts) != 3 {
+			session.reply("501 5.5.2 Invalid response")
+			return
+		}
+		user, password = parts[1], parts[2]
+	case "LOGIN":
+		encUser, ok := session.challenge(base64.StdEncoding.EncodeToString([]byte("Username:")))
+		if !ok {
+			return
+		}
+		encPassword, ok := session.challenge(base64.StdEncoding.EncodeToString([]byte("Password:")))
+		if !ok {
+			return
+		}
+		rawUser, err1 := base64.StdEncoding.DecodeString(encUser)
+		rawPassword, err2 := base64.StdEncoding.DecodeString(encPassword)
+		if err1 != nil || err2 != nil {
+			session.reply("501 5.5.2 Cannot decode response")
+			return
+		}
+		user, password = string(rawUser), string(rawPassword)
+	default:
+		session.reply("504 5.5.4 Unrecognized authentication type")
+		return
+	}
+
+	session.user = session.srv.login(user, password)
+	if session.user == nil {
+		session.reply("535 5.7.8 Authentication failed")
+		return
+	}
+	session.reply("235 2.7.0 Authentication successful")
+}
+
+// challenge sends a 334 reply and returns the client's response.
+func (session *smtpSession) challenge(text string) (string, bool) {
+	session.reply("334 %s", text)
+	line, err := session.tp.ReadLine()
+	if err != nil {
+		return "", false
+	}
+	if line == "*" {
+		session.reply("501 5.7.0 Authentication aborted")
+		return "", false
+	}
+	return line, true
+}
+
+func (session *smtpSession) reset() {
+	session.from = ""
+	session.rcpts = nil
+}
+
+func (session *smtpSession) reply(format string, args ...any) {
+	session.tp.PrintfLine(format, args...) //nolint:errcheck
+}
+
+// exists returns true if there is a mailbox for the given address.
+func (srv *Server) exists(addr string) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	_, ok := srv.mailboxes[strings.ToLower(addr)]
+	return ok
+}
+
+// parsePath extracts the address from MAIL FROM:<addr> and RCPT TO:<addr> arguments,
+// ignoring any ESMTP parameters.
+func parsePath(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	path := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(path, "<") {
+		return "", false
+	}
+	end := strings.Index(path, ">")
+	if end < 0 {
+		return "", false
+	}
+	return path[1:end], true
+}
@@ -0,0 +1,57 @@
+package mailserver
+
+import (
+	"net"
+	"net/smtp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmtp_SendMail(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	sender, password := srv.NewUser()
+	recipient, _ := srv.NewUser()
+	host := net.JoinHostPort(srv.Host, strconv.Itoa(int(srv.SmtpPort())))
+
+	body := "Subject: test\r\n\r\nhello\r\n.dot-stuffed line\r\n"
+	auth := smtp.PlainAuth("", sender, password, srv.Host)
+	require.Nil(t, smtp.SendMail(host, auth, sender, []string{recipient}, []byte(body)))
+	require.Equal(t, 1, srv.MessageCount(recipient, "INBOX"))
+	require.Equal(t, body, string(srv.mailboxes[recipient].folder("INBOX").messages[0].body))
+
+	// unknown recipient
+	require.NotNil(t, smtp.SendMail(host, auth, sender, []string{"unknown@example.org"}, []byte(body)))
+
+	// wrong password
+	auth = smtp.PlainAuth("", sender, "wrong", srv.Host)
+	require.NotNil(t, smtp.SendMail(host, auth, sender, []string{recipient}, []byte(body)))
+	require.Equal(t, 1, srv.MessageCount(recipient, "INBOX"))
+}
+
+func TestSmtp_AuthRequired(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	recipient, _ := srv.NewUser()
+	host := net.JoinHostPort(srv.Host, strconv.Itoa(int(srv.SmtpPort())))
+	require.NotNil(t, smtp.SendMail(host, nil, recipient, []string{recipient}, []byte("\r\n")))
+	require.Equal(t, 0, srv.MessageCount(recipient, "INBOX"))
+}
+
+func TestSmtp_parsePath(t *testing.T) {
+	t.Parallel()
+	addr, ok := parsePath("FROM:<alice@example.org> BODY=8BITMIME", "FROM:")
+	require.True(t, ok)
+	require.Equal(t, "alice@example.org", addr)
+	addr, ok = parsePath("to: <bob@example.org>", "TO:")
+	require.True(t, ok)
+	require.Equal(t, "bob@example.org", addr)
+	_, ok = parsePath("TO:bob@example.org", "TO:")
+	require.False(t, ok)
+	_, ok = parsePath("FROM:<alice@example.org", "FROM:")
+	require.False(t, ok)
+	_, ok = parsePath("X", "FROM:")
+	require.False(t, ok)
+}
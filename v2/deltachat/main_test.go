@@ -1,14 +1,22 @@
 package deltachat
 
 import (
+	"flag"
 	"os"
 	"testing"
 )
 
 var acfactory *AcFactory
 
+var update = flag.Bool("update", false, "update the golden files of AcFactory.AssertGolden()")
+
 func TestMain(m *testing.M) {
-	acfactory = &AcFactory{Debug: os.Getenv("TEST_DEBUG") == "1"}
+	flag.Parse()
+	acfactory = &AcFactory{
+		Debug:        os.Getenv("TEST_DEBUG") == "1",
+		LocalServer:  os.Getenv("TEST_LOCAL_SERVER") == "1",
+		UpdateGolden: *update,
+	}
 	acfactory.TearUp()
 	defer acfactory.TearDown()
 	m.Run()
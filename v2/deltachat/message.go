@@ -0,0 +1,27 @@
+package deltachat
+
+// QuotedMessageId returns the ID of the quoted message, or 0 if the message has no quote
+// or the quote is only text, e.g. when the quoted message was deleted.
+func (msg *Message) QuotedMessageId() uint32 {
+	if msg.Quote == nil {
+		return 0
+	}
+	if quote, ok := (*msg.Quote).(*MessageQuoteWithMessage); ok {
+		return quote.MessageId
+	}
+	return 0
+}
+
+// QuoteText returns the text of the quote, or an empty string if the message has no quote.
+func (msg *Message) QuoteText() string {
+	if msg.Quote == nil {
+		return ""
+	}
+	switch quote := (*msg.Quote).(type) {
+	case *MessageQuoteJustText:
+		return quote.Text
+	case *MessageQuoteWithMessage:
+		return quote.Text
+	}
+	return ""
+}
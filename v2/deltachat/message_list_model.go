@@ -0,0 +1,299 @@
+package deltachat
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// DefaultMessageWindow is the number of entries loaded by a MessageListModel without Window.
+const DefaultMessageWindow = 100
+
+// MessageListEntry is a message or a day marker of a MessageListModel.
+type MessageListEntry struct {
+	// Id of the message, 0 for day markers.
+	MsgId uint32
+	// Timestamp of the day marker in unix milliseconds, 0 for messages.
+	DayMarker int64
+	// The message, nil for day markers and messages that could not be loaded.
+	Message *Message
+	// Error of the message that could not be loaded.
+	LoadingError string
+}
+
+// IsDayMarker returns true if the entry is a day marker.
+func (entry MessageListEntry) IsDayMarker() bool {
+	return entry.MsgId == 0
+}
+
+// MessageListChange is a change of a MessageListModel. Applying the changes in the order
+// they are received to a copy of the list keeps the copy equal to MessageListModel.Entries().
+type MessageListChange struct {
+	Kind ListChangeKind
+	// Index of the entry after an insert, update or move, or before a remove.
+	Index int
+	// Index of the entry before a move.
+	OldIndex int
+	// The entry, only MsgId and DayMarker are set for removes and moves.
+	Entry MessageListEntry
+}
+
+// MessageListModel keeps the last messages of a chat up to date using the message events,
+// and sends the changes to the list on Changes().
+//
+// Only the last Window entries of the chat are loaded, LoadMore() loads older entries.
+// The events of the account must be passed to HandleEvent(), see MessageListModel.EventHandler().
+// The changes are queued until they are received, so updating the list never blocks.
+type MessageListModel struct {
+	Rpc        *Rpc
+	AccountId  uint32
+	ChatId     uint32
+	DayMarkers bool
+	// Number of entries loaded, DefaultMessageWindow if zero.
+	Window int
+
+	mu      sync.Mutex
+	keys    []messageListKey
+	entries map[messageListKey]MessageListEntry
+	changes *changeQueue[MessageListChange]
+	closed  bool
+}
+
+type messageListKey struct {
+	msgId     uint32
+	dayMarker int64
+}
+
+// Create a new MessageListModel of the given chat, with day markers if addDaymarker is true.
+// Reload() must be called to load the list.
+//
+// The model starts a goroutine delivering the Changes(), Close() must be called when the
+// model is not used anymore to stop it and discard the changes that were not received.
+func NewMessageListModel(rpc *Rpc, accId uint32, chatId uint32, addDaymarker bool) *MessageListModel {
+	return &MessageListModel{
+		Rpc:        rpc,
+		AccountId:  accId,
+		ChatId:     chatId,
+		DayMarkers: addDaymarker,
+		entries:    make(map[messageListKey]MessageListEntry),
+		changes:    newChangeQueue[MessageListChange](),
+	}
+}
+
+// Changes returns the channel receiving the changes of the list, it is closed by Close().
+func (model *MessageListModel) Changes() <-chan MessageListChange {
+	return model.changes.out
+}
+
+// Entries returns a copy of the loaded entries, oldest first.
+func (model *MessageListModel) Entries() []MessageListEntry {
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	entries := make([]MessageListEntry, len(model.keys))
+	for i, key := range model.keys {
+		entries[i] = model.entries[key]
+	}
+	return entries
+}
+
+// Reload fetches the list again, sending the differences with the current list as changes.
+func (model *MessageListModel) Reload() error {
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	return model.reload(nil)
+}
+
+// LoadMore grows the window by count entries, loading older entries.
+func (model *MessageListModel) LoadMore(count int) error {
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	model.Window = model.window() + count
+	return model.reload(nil)
+}
+
+// HandleEvent updates the list for the message events of the chat.
+func (model *MessageListModel) HandleEvent(accId uint32, event EventType) error {
+	if accId != model.AccountId {
+		return nil
+	}
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	switch event := event.(type) {
+	case *EventTypeMsgsChanged:
+		switch {
+		case event.ChatId != 0 && event.ChatId != model.ChatId:
+		case event.MsgId == 0:
+			return model.reload(model.msgIds())
+		default:
+			return model.reload([]uint32{event.MsgId})
+		}
+	case *EventTypeIncomingMsg:
+		if event.ChatId == model.ChatId {
+			return model.reload(nil)
+		}
+	case *EventTypeMsgDeleted:
+		if event.ChatId == model.ChatId {
+			return model.reload(nil)
+		}
+	case *EventTypeMsgDelivered:
+		if event.ChatId == model.ChatId {
+			return model.refresh([]uint32{event.MsgId})
+		}
+	case *EventTypeMsgRead:
+		if event.ChatId == model.ChatId {
+			return model.refresh([]uint32{event.MsgId})
+		}
+	case *EventTypeMsgFailed:
+		if event.ChatId == model.ChatId {
+			return model.refresh([]uint32{event.MsgId})
+		}
+	case *EventTypeReactionsChanged:
+		if event.ChatId == model.ChatId {
+			return model.refresh([]uint32{event.MsgId})
+		}
+	}
+	return nil
+}
+
+// EventHandler returns an EventHandler for Bot.Observe() updating the loaded entries.
+// Errors are ignored, the next message event reloads the affected entries.
+func (model *MessageListModel) EventHandler() EventHandler {
+	return func(_ *Bot, accId uint32, event EventType) {
+		model.HandleEvent(accId, event) //nolint:errcheck
+	}
+}
+
+// Close closes the Changes() channel, the list is not updated anymore and the changes
+// that were not received yet are discarded.
+func (model *MessageListModel) Close() {
+	model.mu.Lock()
+	defer model.mu.Unlock()
+	if !model.closed {
+		model.closed = true
+		model.changes.close()
+	}
+}
+
+func (model *MessageListModel) window() int {
+	if model.Window > 0 {
+		return model.Window
+	}
+	return DefaultMessageWindow
+}
+
+func (model *MessageListModel) msgIds() []uint32 {
+	var ids []uint32
+	for _, key := range model.keys {
+		if key.msgId != 0 {
+			ids = append(ids, key.msgId)
+		}
+	}
+	return ids
+}
+
+// reload fetches the list again, and the given messages if they are still loaded.
+func (model *MessageListModel) reload(changedIds []uint32) error {
+	if model.closed {
+		return nil
+	}
+	items, err := model.Rpc.GetMessageListItems(model.AccountId, model.ChatId, false, model.DayMarkers)
+	if err != nil {
+		return err
+	}
+	items = items[max(0, len(items)-model.window()):]
+	keys := make([]messageListKey, 0, len(items))
+	var missing []uint32
+	for _, item := range items {
+		switch item := item.(type) {
+		case *MessageListItemMessage:
+			key := messageListKey{msgId: item.MsgId}
+			keys = append(keys, key)
+			if _, ok := model.entries[key]; !ok || slices.Contains(changedIds, item.MsgId) {
+				missing = append(missing, item.MsgId)
+			}
+		case *MessageListItemDayMarker:
+			keys = append(keys, messageListKey{dayMarker: item.Timestamp})
+		}
+	}
+	fetched, err := model.fetch(missing)
+	if err != nil {
+		return err
+	}
+	for _, diff := range diffList(model.keys, keys) {
+		entry := MessageListEntry{MsgId: diff.key.msgId, DayMarker: diff.key.dayMarker}
+		if diff.kind == ListInsert {
+			if entry.MsgId != 0 {
+				entry = fetched[entry.MsgId]
+				delete(fetched, entry.MsgId)
+			}
+			model.entries[diff.key] = entry
+		}
+		model.changes.push(MessageListChange{Kind: diff.kind, Index: diff.index, OldIndex: diff.oldIndex, Entry: entry})
+	}
+	for key := range model.entries {
+		if !slices.Contains(keys, key) {
+			delete(model.entries, key)
+		}
+	}
+	model.keys = keys
+	for _, entry := range fetched {
+		model.update(entry)
+	}
+	return nil
+}
+
+// refresh fetches the given messages again and sends updates for the changed ones.
+func (model *MessageListModel) refresh(msgIds []uint32) error {
+	if model.closed {
+		return nil
+	}
+	msgIds = slices.DeleteFunc(msgIds, func(msgId uint32) bool {
+		_, ok := model.entries[messageListKey{msgId: msgId}]
+		return !ok
+	})
+	fetched, err := model.fetch(msgIds)
+	if err != nil {
+		return err
+	}
+	for _, entry := range fetched {
+		model.update(entry)
+	}
+	return nil
+}
+
+// update stores a loaded message, sending an update if it changed.
+func (model *MessageListModel) update(entry MessageListEntry) {
+	key := messageListKey{msgId: entry.MsgId}
+	index := slices.Index(model.keys, key)
+	if index < 0 || reflect.DeepEqual(model.entries[key], entry) {
+		return
+	}
+	model.entries[key] = entry
+	model.changes.push(MessageListChange{Kind: ListUpdate, Index: index, Entry: entry})
+}
+
+func (model *MessageListModel) fetch(msgIds []uint32) (map[uint32]MessageListEntry, error) {
+	entries := make(map[uint32]MessageListEntry, len(msgIds))
+	if len(msgIds) == 0 {
+		return entries, nil
+	}
+	results, err := model.Rpc.GetMessages(model.AccountId, msgIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, msgId := range msgIds {
+		entry := MessageListEntry{MsgId: msgId}
+		switch result := results[strconv.FormatUint(uint64(msgId), 10)].(type) {
+		case *MessageLoadResultMessage:
+			msg := Message(*result)
+			entry.Message = &msg
+		case *MessageLoadResultLoadingError:
+			entry.LoadingError = result.Error
+		default:
+			entry.LoadingError = "message not found"
+		}
+		entries[msgId] = entry
+	}
+	return entries, nil
+}
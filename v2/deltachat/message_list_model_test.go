@@ -0,0 +1,105 @@
+package deltachat
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageListModel(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *Rpc, accId uint32) {
+		chatId, err := rpc.CreateGroupChat(accId, "test group", false)
+		require.Nil(t, err)
+		for i := range 5 {
+			_, err := rpc.MiscSendTextMessage(accId, chatId, strconv.Itoa(i))
+			require.Nil(t, err)
+		}
+
+		model := NewMessageListModel(rpc, accId, chatId, true)
+		defer model.Close()
+		model.Window = 3
+		require.Nil(t, model.Reload())
+		entries := model.Entries()
+		require.Len(t, entries, 3)
+		require.Equal(t, "4", entries[2].Message.Text)
+		for range entries {
+			require.Equal(t, ListInsert, (<-model.Changes()).Kind)
+		}
+
+		require.Nil(t, model.LoadMore(1))
+		change := <-model.Changes()
+		require.Equal(t, ListInsert, change.Kind)
+		require.Equal(t, 0, change.Index)
+		require.Equal(t, "1", change.Entry.Message.Text)
+
+		// the oldest entry is removed from the window
+		msgId, err := rpc.MiscSendTextMessage(accId, chatId, "5")
+		require.Nil(t, err)
+		require.Nil(t, model.HandleEvent(accId, &EventTypeMsgsChanged{ChatId: chatId, MsgId: msgId}))
+		change = <-model.Changes()
+		require.Equal(t, ListRemove, change.Kind)
+		require.Equal(t, 0, change.Index)
+		change = <-model.Changes()
+		require.Equal(t, ListInsert, change.Kind)
+		require.Equal(t, 3, change.Index)
+		require.Equal(t, msgId, change.Entry.MsgId)
+
+		// events of other chats are ignored
+		require.Nil(t, model.HandleEvent(accId, &EventTypeIncomingMsg{ChatId: chatId + 1, MsgId: msgId}))
+		select {
+		case change = <-model.Changes():
+			t.Fatalf("unexpected change %v", change.Kind)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		_, err = rpc.SendReaction(accId, msgId, []string{"👍"})
+		require.Nil(t, err)
+		require.Nil(t, model.HandleEvent(accId, &EventTypeReactionsChanged{ChatId: chatId, MsgId: msgId}))
+		change = <-model.Changes()
+		require.Equal(t, ListUpdate, change.Kind)
+		require.Equal(t, msgId, change.Entry.MsgId)
+		require.NotNil(t, change.Entry.Message.Reactions)
+	})
+}
+
+func TestMessageListModel_ReloadWithoutReceiver(t *testing.T) {
+	t.Parallel()
+	const count = 150
+	trans := &listTransport{results: func(method string, params []any) any {
+		if method == "get_message_list_items" {
+			var items []any
+			for msgId := range count {
+				// a day marker every 10 messages
+				if msgId%10 == 0 {
+					items = append(items, map[string]any{"kind": "dayMarker", "timestamp": msgId})
+				}
+				items = append(items, map[string]any{"kind": "message", "msg_id": msgId + 10})
+			}
+			return items
+		}
+		msgs := make(map[string]any)
+		for _, msgId := range params[1].([]uint32) {
+			msgs[strconv.Itoa(int(msgId))] = map[string]any{"kind": "message", "id": msgId, "text": strconv.Itoa(int(msgId))}
+		}
+		return msgs
+	}}
+	model := NewMessageListModel(&Rpc{Context: context.Background(), Transport: trans}, 1, 12, true)
+	defer model.Close()
+	model.Window = 200
+
+	// the changes are not received while reloading
+	require.Nil(t, model.Reload())
+	entries := model.Entries()
+	require.Len(t, entries, count+count/10)
+	require.True(t, entries[0].IsDayMarker())
+	require.Equal(t, "10", entries[1].Message.Text)
+	for i := range entries {
+		change := <-model.Changes()
+		require.Equal(t, ListInsert, change.Kind)
+		require.Equal(t, i, change.Index)
+	}
+}
@@ -0,0 +1,39 @@
+package deltachat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_Quote(t *testing.T) {
+	t.Parallel()
+
+	var msg Message
+	require.Nil(t, json.Unmarshal([]byte(`{"id":12,"text":"reply"}`), &msg))
+	require.Nil(t, msg.Quote)
+	require.Zero(t, msg.QuotedMessageId())
+	require.Empty(t, msg.QuoteText())
+
+	require.Nil(t, json.Unmarshal([]byte(`{"id":12,"quote":{"kind":"WithMessage","messageId":10,"text":"hello"}}`), &msg))
+	require.Equal(t, uint32(10), msg.QuotedMessageId())
+	require.Equal(t, "hello", msg.QuoteText())
+
+	msg = Message{}
+	require.Nil(t, json.Unmarshal([]byte(`{"id":12,"quote":{"kind":"JustText","text":"deleted"}}`), &msg))
+	require.Zero(t, msg.QuotedMessageId())
+	require.Equal(t, "deleted", msg.QuoteText())
+
+	// decoding a message without quote into a used value clears the quote
+	require.Nil(t, json.Unmarshal([]byte(`{"id":13,"text":"no reply"}`), &msg))
+	require.Nil(t, msg.Quote)
+	require.Nil(t, json.Unmarshal([]byte(`{"id":12,"quote":{"kind":"JustText","text":"deleted"}}`), &msg))
+	require.Nil(t, json.Unmarshal([]byte(`{"id":13,"quote":null}`), &msg))
+	require.Nil(t, msg.Quote)
+
+	var quote MessageQuote = &MessageQuoteJustText{Text: "deleted"}
+	result := MessageLoadResultMessage{Quote: &quote}
+	require.Nil(t, json.Unmarshal([]byte(`{"id":13,"text":"no reply"}`), &result))
+	require.Nil(t, result.Quote)
+}
@@ -0,0 +1,135 @@
+package deltachat
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+)
+
+// DefaultBatchSize is the number of items fetched per RPC call by a Pager without BatchSize.
+const DefaultBatchSize = 50
+
+// ItemLoadingErr is yielded by the Pager iterators for a single item that could not be loaded,
+// the iteration continues with the next item.
+type ItemLoadingErr struct {
+	Id     uint32
+	Reason string
+}
+
+func (err *ItemLoadingErr) Error() string {
+	return fmt.Sprintf("failed to load item %v: %v", err.Id, err.Reason)
+}
+
+// Pager iterates over chats, messages and contacts, fetching their details lazily
+// in batches when the iteration reaches them.
+//
+// Errors of the RPC calls are yielded once and end the iteration, per-item errors
+// are yielded as ItemLoadingErr with the zero value, e.g. Message{Id: id}.
+type Pager struct {
+	Rpc *Rpc
+	// Number of items fetched per RPC call, DefaultBatchSize if zero.
+	BatchSize int
+}
+
+// Create a new Pager with the default batch size.
+func NewPager(rpc *Rpc) *Pager {
+	return &Pager{Rpc: rpc}
+}
+
+// Messages returns an iterator over the messages of a chat, without day markers.
+func (pager *Pager) Messages(accId uint32, chatId uint32) iter.Seq2[Message, error] {
+	listIds := func() ([]uint32, error) { return pager.Rpc.GetMessageIds(accId, chatId, false, false) }
+	fetch := func(ids []uint32) (map[string]MessageLoadResult, error) { return pager.Rpc.GetMessages(accId, ids) }
+	return paginate(pager.batchSize(), listIds, fetch, func(id uint32, result MessageLoadResult) (Message, error) {
+		switch result := result.(type) {
+		case *MessageLoadResultMessage:
+			return Message(*result), nil
+		case *MessageLoadResultLoadingError:
+			return Message{Id: id}, &ItemLoadingErr{Id: id, Reason: result.Error}
+		}
+		return Message{Id: id}, &ItemLoadingErr{Id: id, Reason: "message not found"}
+	})
+}
+
+// ChatListItems returns an iterator over the chatlist entries matching the given filters,
+// see Rpc.GetChatlistEntries(). Items are ChatListItemFetchResultChatListItem or
+// ChatListItemFetchResultArchiveLink, ChatListItemFetchResultError is yielded as ItemLoadingErr.
+func (pager *Pager) ChatListItems(accId uint32, listFlags *ChatListFlags, queryString *string, queryContactId *uint32) iter.Seq2[ChatListItemFetchResult, error] {
+	listIds := func() ([]uint32, error) {
+		return pager.Rpc.GetChatlistEntries(accId, listFlags, queryString, queryContactId)
+	}
+	fetch := func(ids []uint32) (map[string]ChatListItemFetchResult, error) {
+		return pager.Rpc.GetChatlistItemsByEntries(accId, ids)
+	}
+	return paginate(pager.batchSize(), listIds, fetch, func(id uint32, result ChatListItemFetchResult) (ChatListItemFetchResult, error) {
+		switch result := result.(type) {
+		case *ChatListItemFetchResultError:
+			return nil, &ItemLoadingErr{Id: id, Reason: result.Error}
+		case nil:
+			return nil, &ItemLoadingErr{Id: id, Reason: "chat not found"}
+		}
+		return result, nil
+	})
+}
+
+// Contacts returns an iterator over the contacts matching the given flags and query,
+// see Rpc.GetContactIds().
+func (pager *Pager) Contacts(accId uint32, listFlags ContactFlags, query *string) iter.Seq2[Contact, error] {
+	listIds := func() ([]uint32, error) { return pager.Rpc.GetContactIds(accId, listFlags, query) }
+	fetch := func(ids []uint32) (map[string]Contact, error) { return pager.Rpc.GetContactsByIds(accId, ids) }
+	return paginate(pager.batchSize(), listIds, fetch, func(id uint32, contact Contact) (Contact, error) {
+		if contact.Id != id {
+			return Contact{Id: id}, &ItemLoadingErr{Id: id, Reason: "contact not found"}
+		}
+		return contact, nil
+	})
+}
+
+func (pager *Pager) batchSize() int {
+	if pager.BatchSize > 0 {
+		return pager.BatchSize
+	}
+	return DefaultBatchSize
+}
+
+// paginate returns an iterator over the items with the ids returned by listIds,
+// fetching them in batches of the given size. Items missing from the fetched map
+// are passed to convert as zero values.
+func paginate[R, T any](size int, listIds func() ([]uint32, error), fetch func([]uint32) (map[string]R, error), convert func(uint32, R) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		ids, err := listIds()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for batch := range slices.Chunk(ids, size) {
+			results, err := fetch(batch)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, id := range batch {
+				if !yield(convert(id, results[strconv.FormatUint(uint64(id), 10)])) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Messages returns an iterator over the messages of a chat, see Pager.Messages().
+func (rpc *Rpc) Messages(accId uint32, chatId uint32) iter.Seq2[Message, error] {
+	return NewPager(rpc).Messages(accId, chatId)
+}
+
+// ChatListItems returns an iterator over the chatlist, see Pager.ChatListItems().
+func (rpc *Rpc) ChatListItems(accId uint32, listFlags *ChatListFlags, queryString *string, queryContactId *uint32) iter.Seq2[ChatListItemFetchResult, error] {
+	return NewPager(rpc).ChatListItems(accId, listFlags, queryString, queryContactId)
+}
+
+// Contacts returns an iterator over the contacts, see Pager.Contacts().
+func (rpc *Rpc) Contacts(accId uint32, listFlags ContactFlags, query *string) iter.Seq2[Contact, error] {
+	return NewPager(rpc).Contacts(accId, listFlags, query)
+}
@@ -0,0 +1,137 @@
+package deltachat
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+	var batches [][]uint32
+	listIds := func() ([]uint32, error) { return []uint32{1, 2, 3, 4, 5}, nil }
+	fetch := func(ids []uint32) (map[string]string, error) {
+		batches = append(batches, ids)
+		results := make(map[string]string)
+		for _, id := range ids {
+			if id != 3 {
+				results[strconv.Itoa(int(id))] = "item" + strconv.Itoa(int(id))
+			}
+		}
+		return results, nil
+	}
+	convert := func(id uint32, item string) (string, error) {
+		if item == "" {
+			return "", &ItemLoadingErr{Id: id, Reason: "not found"}
+		}
+		return item, nil
+	}
+
+	var items []string
+	var loadingErr *ItemLoadingErr
+	for item, err := range paginate(2, listIds, fetch, convert) {
+		if err != nil {
+			require.ErrorAs(t, err, &loadingErr)
+			continue
+		}
+		items = append(items, item)
+	}
+	require.Equal(t, []string{"item1", "item2", "item4", "item5"}, items)
+	require.Equal(t, uint32(3), loadingErr.Id)
+	require.Equal(t, [][]uint32{{1, 2}, {3, 4}, {5}}, batches)
+
+	// batches after break are not fetched
+	batches = nil
+	for item := range paginate(2, listIds, fetch, convert) {
+		if item == "item1" {
+			break
+		}
+	}
+	require.Equal(t, [][]uint32{{1, 2}}, batches)
+}
+
+func TestPaginate_Error(t *testing.T) {
+	t.Parallel()
+	failure := errors.New("failure")
+	convert := func(id uint32, item string) (string, error) { return item, nil }
+
+	count := 0
+	listIds := func() ([]uint32, error) { return nil, failure }
+	fetch := func(ids []uint32) (map[string]string, error) { return nil, nil }
+	for _, err := range paginate(2, listIds, fetch, convert) {
+		require.Equal(t, failure, err)
+		count++
+	}
+	require.Equal(t, 1, count)
+
+	count = 0
+	listIds = func() ([]uint32, error) { return []uint32{1, 2, 3}, nil }
+	fetch = func(ids []uint32) (map[string]string, error) { return nil, failure }
+	for _, err := range paginate(2, listIds, fetch, convert) {
+		require.Equal(t, failure, err)
+		count++
+	}
+	require.Equal(t, 1, count)
+}
+
+func TestRpc_Messages(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *Rpc, accId uint32) {
+		chatId, err := rpc.CreateGroupChat(accId, "test group", false)
+		require.Nil(t, err)
+		for i := range 5 {
+			_, err := rpc.MiscSendTextMessage(accId, chatId, strconv.Itoa(i))
+			require.Nil(t, err)
+		}
+
+		var texts []string
+		pager := &Pager{Rpc: rpc, BatchSize: 2}
+		for msg, err := range pager.Messages(accId, chatId) {
+			require.Nil(t, err)
+			if !msg.IsInfo {
+				texts = append(texts, msg.Text)
+			}
+		}
+		require.Equal(t, []string{"0", "1", "2", "3", "4"}, texts)
+
+		for msg, err := range rpc.Messages(accId, chatId) {
+			require.Nil(t, err)
+			require.NotZero(t, msg.Id)
+			break
+		}
+	})
+}
+
+func TestRpc_ChatListItems(t *testing.T) {
+	t.Parallel()
+	acfactory.WithOnlineAccount(func(rpc *Rpc, accId uint32) {
+		_, err := rpc.CreateGroupChat(accId, "test group", false)
+		require.Nil(t, err)
+
+		var names []string
+		for item, err := range rpc.ChatListItems(accId, nil, strptr("test group"), nil) {
+			require.Nil(t, err)
+			names = append(names, item.(*ChatListItemFetchResultChatListItem).Name)
+		}
+		require.Equal(t, []string{"test group"}, names)
+	})
+}
+
+func TestRpc_Contacts(t *testing.T) {
+	t.Parallel()
+	acfactory.WithUnconfiguredAccount(func(rpc *Rpc, accId uint32) {
+		for i := range 3 {
+			_, err := rpc.CreateContact(accId, "null"+strconv.Itoa(i)+"@localhost", nil)
+			require.Nil(t, err)
+		}
+
+		var addrs []string
+		for contact, err := range (&Pager{Rpc: rpc, BatchSize: 1}).Contacts(accId, 0, strptr("null")) {
+			require.Nil(t, err)
+			addrs = append(addrs, contact.Address)
+		}
+		require.ElementsMatch(t, []string{"null0@localhost", "null1@localhost", "null2@localhost"}, addrs)
+	})
+}
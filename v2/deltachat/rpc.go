@@ -479,7 +479,7 @@ func (rpc *Rpc) EstimateAutoDeletionCount(accountId uint32, fromServer bool, sec
 	return result, err
 }
 
-func (rpc *Rpc) GetChatlistEntries(accountId uint32, listFlags *uint32, queryString *string, queryContactId *uint32) ([]uint32, error) {
+func (rpc *Rpc) GetChatlistEntries(accountId uint32, listFlags *ChatListFlags, queryString *string, queryContactId *uint32) ([]uint32, error) {
 	var result []uint32
 	err := rpc.Transport.CallResult(rpc.Context, &result, "get_chatlist_entries", accountId, listFlags, queryString, queryContactId)
 	return result, err
@@ -1152,7 +1152,7 @@ func (rpc *Rpc) GetBlockedContacts(accountId uint32) ([]Contact, error) {
 // - `DC_GCL_ADD_SELF` - Add SELF unless filtered by other parameters.
 // - `DC_GCL_ADDRESS` - List address-contacts instead of key-contacts.
 // * `query` - A string to filter the list.
-func (rpc *Rpc) GetContactIds(accountId uint32, listFlags uint32, query *string) ([]uint32, error) {
+func (rpc *Rpc) GetContactIds(accountId uint32, listFlags ContactFlags, query *string) ([]uint32, error) {
 	var result []uint32
 	err := rpc.Transport.CallResult(rpc.Context, &result, "get_contact_ids", accountId, listFlags, query)
 	return result, err
@@ -1162,7 +1162,7 @@ func (rpc *Rpc) GetContactIds(accountId uint32, listFlags uint32, query *string)
 //
 // Formerly called `getContacts2` in Desktop.
 // See [`Self::get_contact_ids`] for parameters and more info.
-func (rpc *Rpc) GetContacts(accountId uint32, listFlags uint32, query *string) ([]Contact, error) {
+func (rpc *Rpc) GetContacts(accountId uint32, listFlags ContactFlags, query *string) ([]Contact, error) {
 	var result []Contact
 	err := rpc.Transport.CallResult(rpc.Context, &result, "get_contacts", accountId, listFlags, query)
 	return result, err
@@ -1367,8 +1367,8 @@ func (rpc *Rpc) MaybeNetwork() error {
 // e.g. in the title of the main screen.
 //
 // If the connectivity changes, a #DC_EVENT_CONNECTIVITY_CHANGED will be emitted.
-func (rpc *Rpc) GetConnectivity(accountId uint32) (uint32, error) {
-	var result uint32
+func (rpc *Rpc) GetConnectivity(accountId uint32) (Connectivity, error) {
+	var result Connectivity
 	err := rpc.Transport.CallResult(rpc.Context, &result, "get_connectivity", accountId)
 	return result, err
 }
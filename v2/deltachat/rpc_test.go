@@ -82,7 +82,7 @@ func TestAccount_Connectivity(t *testing.T) {
 	acfactory.WithUnconfiguredAccount(func(rpc *Rpc, accId uint32) {
 		conn, err := rpc.GetConnectivity(accId)
 		require.Nil(t, err)
-		require.Greater(t, conn, uint32(0))
+		require.Greater(t, conn, Connectivity(0))
 
 		html, err := rpc.GetConnectivityHtml(accId)
 		require.Nil(t, err)
@@ -0,0 +1,138 @@
+package deltachat
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/chatmail/rpc-client-go/v2/internal/openrpc"
+)
+
+// Fingerprint of the schema the bindings were generated from, written by dcrpcgen-go.
+//
+//go:embed schema_fingerprint.json
+var schemaFingerprintJSON []byte
+
+func bindingsFingerprint() *openrpc.Fingerprint {
+	var fp openrpc.Fingerprint
+	if err := json.Unmarshal(schemaFingerprintJSON, &fp); err != nil {
+		panic(err)
+	}
+	return &fp
+}
+
+// BindingsMethods returns the JSON-RPC methods of the bindings by name with their Go
+// signature, e.g. "get_config": "(uint32, string) *string".
+func BindingsMethods() map[string]string {
+	return bindingsFingerprint().Methods
+}
+
+// MethodChange is a method whose signature differs between the bindings and the server.
+type MethodChange struct {
+	Method string
+	// Go signature of the method in the bindings, e.g. "(uint32, string) *string".
+	Bindings string
+	// Go signature the method would have if the bindings were generated from the server's schema.
+	Server string
+}
+
+// SchemaReport describes the differences between the schema the bindings were generated from
+// and the schema of a running deltachat-rpc-server.
+type SchemaReport struct {
+	ServerVersion   string
+	BindingsVersion string
+	// Methods used by the bindings that the server does not provide.
+	MissingMethods []string
+	// Methods with different parameters or result.
+	ChangedMethods []MethodChange
+	// Event kinds emitted by the server that the bindings can not decode.
+	NewEventKinds []string
+}
+
+// Compatible returns true if the bindings can be used with the server.
+func (report *SchemaReport) Compatible() bool {
+	return len(report.MissingMethods) == 0 && len(report.ChangedMethods) == 0 && len(report.NewEventKinds) == 0
+}
+
+func (report *SchemaReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "bindings generated for deltachat-rpc-server %v, server is %v", report.BindingsVersion, report.ServerVersion)
+	for _, method := range report.MissingMethods {
+		fmt.Fprintf(&b, "\nmissing method: %v", method)
+	}
+	for _, change := range report.ChangedMethods {
+		fmt.Fprintf(&b, "\nchanged method: %v%v, server: %v%v", change.Method, change.Bindings, change.Method, change.Server)
+	}
+	for _, kind := range report.NewEventKinds {
+		fmt.Fprintf(&b, "\nnew event kind: %v", kind)
+	}
+	return b.String()
+}
+
+// compareSchemas returns the report of the differences between the bindings and server fingerprints.
+func compareSchemas(bindings, server *openrpc.Fingerprint) *SchemaReport {
+	report := &SchemaReport{ServerVersion: server.Version, BindingsVersion: bindings.Version}
+	for method, signature := range bindings.Methods {
+		serverSignature, ok := server.Methods[method]
+		if !ok {
+			report.MissingMethods = append(report.MissingMethods, method)
+		} else if serverSignature != signature {
+			report.ChangedMethods = append(report.ChangedMethods, MethodChange{Method: method, Bindings: signature, Server: serverSignature})
+		}
+	}
+	for _, kind := range server.EventKinds {
+		if !slices.Contains(bindings.EventKinds, kind) {
+			report.NewEventKinds = append(report.NewEventKinds, kind)
+		}
+	}
+	slices.Sort(report.MissingMethods)
+	slices.SortFunc(report.ChangedMethods, func(a, b MethodChange) int { return strings.Compare(a.Method, b.Method) })
+	slices.Sort(report.NewEventKinds)
+	return report
+}
+
+// CheckSchema compares the schema the bindings were generated from with the schema of the
+// running server. The server version is requested with GetSystemInfo(), if it matches the
+// bindings version the schemas are assumed to be the same, otherwise the OpenRPC schema
+// is requested from the server binary.
+func (trans *IOTransport) CheckSchema(ctx context.Context) (*SchemaReport, error) {
+	rpc := &Rpc{Context: ctx, Transport: trans}
+	info, err := rpc.GetSystemInfo()
+	if err != nil {
+		return nil, err
+	}
+	bindings := bindingsFingerprint()
+	version := strings.TrimPrefix(info["deltachat_core_version"], "v")
+	if version == bindings.Version {
+		return &SchemaReport{ServerVersion: version, BindingsVersion: bindings.Version}, nil
+	}
+
+	data, err := exec.CommandContext(ctx, trans.Cmd, "--openrpc").Output()
+	if err != nil {
+		return nil, fmt.Errorf("running %v --openrpc: %w", trans.Cmd, err)
+	}
+	doc, err := openrpc.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	server, err := openrpc.NewFingerprint(doc)
+	if err != nil {
+		return nil, err
+	}
+	server.Version = version
+	return compareSchemas(bindings, server), nil
+}
+
+// SchemaMismatchErr is returned by IOTransport.Open() if IOTransport.VerifySchema is set
+// and the server is not compatible with the bindings.
+type SchemaMismatchErr struct {
+	Report *SchemaReport
+}
+
+func (e *SchemaMismatchErr) Error() string {
+	return "incompatible deltachat-rpc-server schema: " + e.Report.String()
+}
@@ -0,0 +1,82 @@
+package deltachat
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/chatmail/rpc-client-go/v2/internal/openrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindingsFingerprint(t *testing.T) {
+	t.Parallel()
+	fp := bindingsFingerprint()
+	require.NotEmpty(t, fp.Version)
+	// hand-written Rpc methods like Rpc.Messages() are not in the fingerprint
+	file, err := parser.ParseFile(token.NewFileSet(), "rpc.go", nil, 0)
+	require.Nil(t, err)
+	generated := 0
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil {
+			generated++
+		}
+	}
+	require.Len(t, fp.Methods, generated)
+	require.Equal(t, "() map[string]string", fp.Methods["get_system_info"])
+	require.Contains(t, fp.EventKinds, "IncomingMsg")
+}
+
+func TestCompareSchemas(t *testing.T) {
+	t.Parallel()
+	bindings := &openrpc.Fingerprint{
+		Version: "2.0.0",
+		Methods: map[string]string{
+			"get_config":   "(uint32, string) *string",
+			"set_config":   "(uint32, string, *string)",
+			"remove_chat":  "(uint32, uint32)",
+			"get_chat_ids": "(uint32) []uint32",
+		},
+		EventKinds: []string{"Info", "IncomingMsg"},
+	}
+	server := &openrpc.Fingerprint{
+		Version: "2.1.0",
+		Methods: map[string]string{
+			"get_config":   "(uint32, string) *string",
+			"set_config":   "(uint32, string, string)",
+			"get_chat_ids": "(uint32) []uint64",
+			"new_method":   "()",
+		},
+		EventKinds: []string{"Info", "IncomingMsg", "Warning"},
+	}
+
+	report := compareSchemas(bindings, server)
+	require.False(t, report.Compatible())
+	require.Equal(t, "2.0.0", report.BindingsVersion)
+	require.Equal(t, "2.1.0", report.ServerVersion)
+	require.Equal(t, []string{"remove_chat"}, report.MissingMethods)
+	require.Equal(t, []MethodChange{
+		{Method: "get_chat_ids", Bindings: "(uint32) []uint32", Server: "(uint32) []uint64"},
+		{Method: "set_config", Bindings: "(uint32, string, *string)", Server: "(uint32, string, string)"},
+	}, report.ChangedMethods)
+	require.Equal(t, []string{"Warning"}, report.NewEventKinds)
+	require.Contains(t, report.String(), "missing method: remove_chat")
+
+	err := &SchemaMismatchErr{Report: report}
+	require.Contains(t, err.Error(), "new event kind: Warning")
+
+	report = compareSchemas(bindings, bindings)
+	require.True(t, report.Compatible())
+}
+
+func TestIOTransport_CheckSchema(t *testing.T) {
+	t.Parallel()
+	acfactory.WithRpc(func(rpc *Rpc) {
+		trans := rpc.Transport.(*IOTransport)
+		report, err := trans.CheckSchema(rpc.Context)
+		require.Nil(t, err)
+		require.NotEmpty(t, report.ServerVersion)
+		require.True(t, report.Compatible(), report.String())
+	})
+}
@@ -128,6 +128,14 @@ type CallInfo struct {
 	State CallState `json:"-"`
 }
 
+func (s CallInfo) MarshalJSON() ([]byte, error) {
+	type alias CallInfo
+	return json.Marshal(struct {
+		alias
+		State CallState `json:"state"`
+	}{alias: alias(s), State: s.State})
+}
+
 func (s *CallInfo) UnmarshalJSON(data []byte) error {
 	var raw struct {
 		HasVideo bool            `json:"hasVideo"`
@@ -592,6 +600,14 @@ type Event struct {
 	Event EventType `json:"-"`
 }
 
+func (s Event) MarshalJSON() ([]byte, error) {
+	type alias Event
+	return json.Marshal(struct {
+		alias
+		Event EventType `json:"event"`
+	}{alias: alias(s), Event: s.Event})
+}
+
 func (s *Event) UnmarshalJSON(data []byte) error {
 	var raw struct {
 		ContextId uint32          `json:"contextId"`
@@ -1914,10 +1930,10 @@ type Message struct {
 	// True if the message was correctly encrypted&signed, false otherwise. Historically, UIs showed a small padlock on the message then.
 	//
 	// Today, the UIs should instead show a small email-icon on the message if `show_padlock` is `false`, and nothing if it is `true`.
-	ShowPadlock   bool   `json:"showPadlock"`
-	SortTimestamp int64  `json:"sortTimestamp"`
-	State         uint32 `json:"state"`
-	Subject       string `json:"subject"`
+	ShowPadlock   bool     `json:"showPadlock"`
+	SortTimestamp int64    `json:"sortTimestamp"`
+	State         MsgState `json:"state"`
+	Subject       string   `json:"subject"`
 	// when is_info is true this describes what type of system message it is
 	SystemMessageType SystemMessageType `json:"systemMessageType"`
 	Text              string            `json:"text"`
@@ -1927,6 +1943,14 @@ type Message struct {
 	WebxdcHref        *string           `json:"webxdcHref,omitempty"`
 }
 
+func (s Message) MarshalJSON() ([]byte, error) {
+	type alias Message
+	return json.Marshal(struct {
+		alias
+		Quote *MessageQuote `json:"quote,omitempty"`
+	}{alias: alias(s), Quote: s.Quote})
+}
+
 func (s *Message) UnmarshalJSON(data []byte) error {
 	var raw struct {
 		ChatId                uint32            `json:"chatId"`
@@ -1959,7 +1983,7 @@ func (s *Message) UnmarshalJSON(data []byte) error {
 		Sender                Contact           `json:"sender"`
 		ShowPadlock           bool              `json:"showPadlock"`
 		SortTimestamp         int64             `json:"sortTimestamp"`
-		State                 uint32            `json:"state"`
+		State                 MsgState          `json:"state"`
 		Subject               string            `json:"subject"`
 		SystemMessageType     SystemMessageType `json:"systemMessageType"`
 		Text                  string            `json:"text"`
@@ -2008,10 +2032,13 @@ func (s *Message) UnmarshalJSON(data []byte) error {
 	s.VcardContact = raw.VcardContact
 	s.ViewType = raw.ViewType
 	s.WebxdcHref = raw.WebxdcHref
+	s.Quote = nil
 	if len(raw.Quote) > 0 && string(raw.Quote) != "null" {
-		if err := unmarshalMessageQuote(raw.Quote, s.Quote); err != nil {
+		var val MessageQuote
+		if err := unmarshalMessageQuote(raw.Quote, &val); err != nil {
 			return err
 		}
+		s.Quote = &val
 	}
 	return nil
 }
@@ -2039,6 +2066,14 @@ type MessageInfo struct {
 	ServerUrls         []string `json:"serverUrls"`
 }
 
+func (s MessageInfo) MarshalJSON() ([]byte, error) {
+	type alias MessageInfo
+	return json.Marshal(struct {
+		alias
+		EphemeralTimer EphemeralTimer `json:"ephemeralTimer"`
+	}{alias: alias(s), EphemeralTimer: s.EphemeralTimer})
+}
+
 func (s *MessageInfo) UnmarshalJSON(data []byte) error {
 	var raw struct {
 		EphemeralTimer     json.RawMessage `json:"ephemeralTimer"`
@@ -2158,7 +2193,7 @@ type MessageLoadResultMessage struct {
 	OriginalMsgId      *uint32       `json:"originalMsgId,omitempty"`
 	OverrideSenderName *string       `json:"overrideSenderName,omitempty"`
 	ParentId           *uint32       `json:"parentId,omitempty"`
-	Quote              *MessageQuote `json:"quote,omitempty"`
+	Quote              *MessageQuote `json:"-"`
 	Reactions          *Reactions    `json:"reactions,omitempty"`
 	ReceivedTimestamp  int64         `json:"receivedTimestamp"`
 	SavedMessageId     *uint32       `json:"savedMessageId,omitempty"`
@@ -2166,10 +2201,10 @@ type MessageLoadResultMessage struct {
 	// True if the message was correctly encrypted&signed, false otherwise. Historically, UIs showed a small padlock on the message then.
 	//
 	// Today, the UIs should instead show a small email-icon on the message if `show_padlock` is `false`, and nothing if it is `true`.
-	ShowPadlock   bool   `json:"showPadlock"`
-	SortTimestamp int64  `json:"sortTimestamp"`
-	State         uint32 `json:"state"`
-	Subject       string `json:"subject"`
+	ShowPadlock   bool     `json:"showPadlock"`
+	SortTimestamp int64    `json:"sortTimestamp"`
+	State         MsgState `json:"state"`
+	Subject       string   `json:"subject"`
 	// when is_info is true this describes what type of system message it is
 	SystemMessageType SystemMessageType `json:"systemMessageType"`
 	Text              string            `json:"text"`
@@ -2186,7 +2221,100 @@ func (v *MessageLoadResultMessage) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		Kind string `json:"kind"`
 		alias
-	}{Kind: "message", alias: alias(*v)})
+		Quote *MessageQuote `json:"quote,omitempty"`
+	}{Kind: "message", alias: alias(*v), Quote: v.Quote})
+}
+
+func (s *MessageLoadResultMessage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ChatId                uint32            `json:"chatId"`
+		DimensionsHeight      int32             `json:"dimensionsHeight"`
+		DimensionsWidth       int32             `json:"dimensionsWidth"`
+		DownloadState         DownloadState     `json:"downloadState"`
+		Duration              int32             `json:"duration"`
+		Error                 *string           `json:"error,omitempty"`
+		File                  *string           `json:"file,omitempty"`
+		FileBytes             uint64            `json:"fileBytes"`
+		FileMime              *string           `json:"fileMime,omitempty"`
+		FileName              *string           `json:"fileName,omitempty"`
+		FromId                uint32            `json:"fromId"`
+		HasDeviatingTimestamp bool              `json:"hasDeviatingTimestamp"`
+		HasHtml               bool              `json:"hasHtml"`
+		HasLocation           bool              `json:"hasLocation"`
+		Id                    uint32            `json:"id"`
+		InfoContactId         *uint32           `json:"infoContactId,omitempty"`
+		IsBot                 bool              `json:"isBot"`
+		IsEdited              bool              `json:"isEdited"`
+		IsForwarded           bool              `json:"isForwarded"`
+		IsInfo                bool              `json:"isInfo"`
+		OriginalMsgId         *uint32           `json:"originalMsgId,omitempty"`
+		OverrideSenderName    *string           `json:"overrideSenderName,omitempty"`
+		ParentId              *uint32           `json:"parentId,omitempty"`
+		Quote                 json.RawMessage   `json:"quote"`
+		Reactions             *Reactions        `json:"reactions,omitempty"`
+		ReceivedTimestamp     int64             `json:"receivedTimestamp"`
+		SavedMessageId        *uint32           `json:"savedMessageId,omitempty"`
+		Sender                Contact           `json:"sender"`
+		ShowPadlock           bool              `json:"showPadlock"`
+		SortTimestamp         int64             `json:"sortTimestamp"`
+		State                 MsgState          `json:"state"`
+		Subject               string            `json:"subject"`
+		SystemMessageType     SystemMessageType `json:"systemMessageType"`
+		Text                  string            `json:"text"`
+		Timestamp             int64             `json:"timestamp"`
+		VcardContact          *VcardContact     `json:"vcardContact,omitempty"`
+		ViewType              Viewtype          `json:"viewType"`
+		WebxdcHref            *string           `json:"webxdcHref,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	s.ChatId = raw.ChatId
+	s.DimensionsHeight = raw.DimensionsHeight
+	s.DimensionsWidth = raw.DimensionsWidth
+	s.DownloadState = raw.DownloadState
+	s.Duration = raw.Duration
+	s.Error = raw.Error
+	s.File = raw.File
+	s.FileBytes = raw.FileBytes
+	s.FileMime = raw.FileMime
+	s.FileName = raw.FileName
+	s.FromId = raw.FromId
+	s.HasDeviatingTimestamp = raw.HasDeviatingTimestamp
+	s.HasHtml = raw.HasHtml
+	s.HasLocation = raw.HasLocation
+	s.Id = raw.Id
+	s.InfoContactId = raw.InfoContactId
+	s.IsBot = raw.IsBot
+	s.IsEdited = raw.IsEdited
+	s.IsForwarded = raw.IsForwarded
+	s.IsInfo = raw.IsInfo
+	s.OriginalMsgId = raw.OriginalMsgId
+	s.OverrideSenderName = raw.OverrideSenderName
+	s.ParentId = raw.ParentId
+	s.Reactions = raw.Reactions
+	s.ReceivedTimestamp = raw.ReceivedTimestamp
+	s.SavedMessageId = raw.SavedMessageId
+	s.Sender = raw.Sender
+	s.ShowPadlock = raw.ShowPadlock
+	s.SortTimestamp = raw.SortTimestamp
+	s.State = raw.State
+	s.Subject = raw.Subject
+	s.SystemMessageType = raw.SystemMessageType
+	s.Text = raw.Text
+	s.Timestamp = raw.Timestamp
+	s.VcardContact = raw.VcardContact
+	s.ViewType = raw.ViewType
+	s.WebxdcHref = raw.WebxdcHref
+	s.Quote = nil
+	if len(raw.Quote) > 0 && string(raw.Quote) != "null" {
+		var val MessageQuote
+		if err := unmarshalMessageQuote(raw.Quote, &val); err != nil {
+			return err
+		}
+		s.Quote = &val
+	}
+	return nil
 }
 
 type MessageLoadResultLoadingError struct {
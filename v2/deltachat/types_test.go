@@ -500,3 +500,103 @@ func TestUnmarshalQr(t *testing.T) {
 	require.NotNil(t, unmarshalQr(json.RawMessage(`notjson`), &out))
 	require.NotNil(t, unmarshalQr(json.RawMessage(`{"kind":"Unknown"}`), &out))
 }
+
+func TestMessage_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	var quote MessageQuote = &MessageQuoteWithMessage{MessageId: 10, Text: "hello"}
+	data, err := json.Marshal(Message{Id: 12, Text: "reply", Quote: &quote})
+	require.Nil(t, err)
+	require.Contains(t, string(data), `"quote":{"kind":"WithMessage"`)
+
+	var msg Message
+	require.Nil(t, json.Unmarshal(data, &msg))
+	require.Equal(t, uint32(12), msg.Id)
+	require.Equal(t, quote, *msg.Quote)
+
+	data, err = json.Marshal(Message{Id: 12})
+	require.Nil(t, err)
+	require.NotContains(t, string(data), `"quote"`)
+}
+
+func TestMessageLoadResultMessage_UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	var result MessageLoadResult
+	data := `{"kind":"message","id":12,"quote":{"kind":"JustText","text":"hello"}}`
+	require.Nil(t, unmarshalMessageLoadResult(json.RawMessage(data), &result))
+	msg := result.(*MessageLoadResultMessage)
+	require.Equal(t, "JustText", (*msg.Quote).GetKind())
+
+	encoded, err := json.Marshal(result)
+	require.Nil(t, err)
+	require.Contains(t, string(encoded), `"kind":"message"`)
+	require.Contains(t, string(encoded), `"quote":{"kind":"JustText","text":"hello"}`)
+}
+
+func TestCallInfo_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(CallInfo{HasVideo: true, State: &CallStateCompleted{Duration: 42}})
+	require.Nil(t, err)
+	var info CallInfo
+	require.Nil(t, json.Unmarshal(data, &info))
+	require.True(t, info.HasVideo)
+	require.Equal(t, &CallStateCompleted{Duration: 42}, info.State)
+}
+
+// fuzzUnion checks that values accepted by the decoder of a tagged union survive
+// an encoding round trip.
+func fuzzUnion[T any](f *testing.F, unmarshal func(json.RawMessage, *T) error, seeds ...string) {
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var value T
+		if err := unmarshal(data, &value); err != nil {
+			return
+		}
+		encoded, err := json.Marshal(value)
+		require.Nil(t, err)
+		var decoded T
+		require.Nil(t, unmarshal(encoded, &decoded), "%s", encoded)
+		reencoded, err := json.Marshal(decoded)
+		require.Nil(t, err)
+		require.JSONEq(t, string(encoded), string(reencoded))
+	})
+}
+
+func FuzzUnmarshalMessageQuote(f *testing.F) {
+	fuzzUnion(f, unmarshalMessageQuote,
+		`{"kind":"JustText","text":"hello"}`,
+		`{"kind":"WithMessage","messageId":10,"text":"hello","image":null}`,
+	)
+}
+
+func FuzzUnmarshalCallState(f *testing.F) {
+	fuzzUnion(f, unmarshalCallState,
+		`{"kind":"Alerting"}`,
+		`{"kind":"Completed","duration":5}`,
+	)
+}
+
+func FuzzUnmarshalMessageLoadResult(f *testing.F) {
+	fuzzUnion(f, unmarshalMessageLoadResult,
+		`{"kind":"message","id":12,"quote":{"kind":"JustText","text":"hello"}}`,
+		`{"kind":"loadingError","error":"failed"}`,
+	)
+}
+
+func FuzzUnmarshalEventType(f *testing.F) {
+	fuzzUnion(f, unmarshalEventType,
+		`{"kind":"Info","msg":"hello"}`,
+		`{"kind":"IncomingMsg","chatId":10,"msgId":12}`,
+	)
+}
+
+func FuzzMessage_UnmarshalJSON(f *testing.F) {
+	fuzzUnion(f, func(data json.RawMessage, msg *Message) error { return json.Unmarshal(data, msg) },
+		`{"id":12,"quote":{"kind":"WithMessage","messageId":10,"text":"hello"}}`,
+		`{"id":12,"quote":null}`,
+	)
+}
@@ -4,7 +4,9 @@ go 1.25.0
 
 require (
 	github.com/creachadair/jrpc2 v1.3.5
+	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/stretchr/testify v1.8.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -12,5 +14,4 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
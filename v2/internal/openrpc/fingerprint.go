@@ -0,0 +1,47 @@
+package openrpc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fingerprint summarizes the parts of a schema that the Go bindings depend on.
+type Fingerprint struct {
+	// Version of deltachat-rpc-server the schema was taken from.
+	Version string `json:"version"`
+	// Methods maps method names to their Go signature, e.g. "(uint32, *string) []uint32".
+	Methods map[string]string `json:"methods"`
+	// EventKinds are the kinds of the EventType union.
+	EventKinds []string `json:"eventKinds"`
+}
+
+// NewFingerprint returns the fingerprint of the given document.
+func NewFingerprint(doc *Document) (*Fingerprint, error) {
+	types, err := NewTypes(doc)
+	if err != nil {
+		return nil, err
+	}
+	fp := &Fingerprint{Version: strings.TrimPrefix(doc.Info.Version, "v"), Methods: make(map[string]string), EventKinds: []string{}}
+	for _, method := range doc.Methods {
+		var params []string
+		for _, param := range method.Params {
+			typ, err := types.ParamType(param)
+			if err != nil {
+				return nil, fmt.Errorf("%v: param %v: %w", method.Name, param.Name, err)
+			}
+			params = append(params, typ)
+		}
+		result, _, err := types.ResultType(method)
+		if err != nil {
+			return nil, fmt.Errorf("%v: result: %w", method.Name, err)
+		}
+		fp.Methods[method.Name] = strings.TrimSpace("(" + strings.Join(params, ", ") + ") " + result)
+	}
+	for _, variant := range types.Unions["EventType"] {
+		kind, _ := variant.VariantKind()
+		fp.EventKinds = append(fp.EventKinds, kind)
+	}
+	slices.Sort(fp.EventKinds)
+	return fp, nil
+}
@@ -0,0 +1,188 @@
+// Package openrpc parses the OpenRPC schema of deltachat-rpc-server and maps its types to Go.
+//
+// It is shared by the dcrpcgen-go code generator and the schema drift check of the deltachat package.
+package openrpc
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// Document is the subset of an OpenRPC document used by the generator.
+type Document struct {
+	Info struct {
+		Version string `json:"version"`
+	} `json:"info"`
+	Methods    []Method `json:"methods"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Method is an OpenRPC method description.
+type Method struct {
+	Name        string               `json:"name"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description"`
+	Params      []*ContentDescriptor `json:"params"`
+	Result      *ContentDescriptor   `json:"result"`
+}
+
+// ContentDescriptor describes a method parameter or result.
+type ContentDescriptor struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema emitted by deltachat-rpc-server.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 schemaType         `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []string           `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                schemaItems        `json:"items"`
+	AdditionalProperties *SchemaOrBool      `json:"additionalProperties"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	AllOf                []*Schema          `json:"allOf"`
+}
+
+// Parse parses an OpenRPC document.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// schemaType is the "type" keyword, a single type name or a list of type names.
+type schemaType []string
+
+func (t *schemaType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = schemaType{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*t = names
+	return nil
+}
+
+// schemaItems is the "items" keyword, a single schema or a list of schemas for tuples.
+type schemaItems []*Schema
+
+func (items *schemaItems) UnmarshalJSON(data []byte) error {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		var list []*Schema
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		*items = list
+		return nil
+	}
+	var item Schema
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+	*items = schemaItems{&item}
+	return nil
+}
+
+// SchemaOrBool is the "additionalProperties" keyword, booleans are ignored.
+type SchemaOrBool struct {
+	*Schema
+}
+
+func (s *SchemaOrBool) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case "true", "false":
+		return nil
+	}
+	s.Schema = &Schema{}
+	return json.Unmarshal(data, s.Schema)
+}
+
+// NonNull returns the schema without the "null" type and whether the schema was nullable.
+func (s *Schema) NonNull() (*Schema, bool) {
+	if len(s.AnyOf) == 2 {
+		for i, option := range s.AnyOf {
+			if option.IsNull() {
+				return s.AnyOf[1-i], true
+			}
+		}
+	}
+	if slices.Contains(s.Type, "null") {
+		clone := *s
+		clone.Type = slices.DeleteFunc(slices.Clone(s.Type), func(t string) bool { return t == "null" })
+		return &clone, true
+	}
+	return s, s.Nullable
+}
+
+// IsNull returns true if the schema only allows null.
+func (s *Schema) IsNull() bool {
+	return len(s.Type) == 1 && s.Type[0] == "null"
+}
+
+// TypeName returns the JSON type of the schema, or an empty string if there is none or several.
+func (s *Schema) TypeName() string {
+	if len(s.Type) == 1 {
+		return s.Type[0]
+	}
+	return ""
+}
+
+// IsRequired returns true if the given property is required.
+func (s *Schema) IsRequired(property string) bool {
+	return slices.Contains(s.Required, property)
+}
+
+// RefName returns the name of the referenced component schema, or an empty string.
+func (s *Schema) RefName() string {
+	if s.Ref == "" && len(s.AllOf) == 1 {
+		return s.AllOf[0].RefName()
+	}
+	return s.Ref[strings.LastIndex(s.Ref, "/")+1:]
+}
+
+// VariantKind returns the value of the "kind" tag if the schema is a tagged union variant.
+func (s *Schema) VariantKind() (string, bool) {
+	kind, ok := s.Properties["kind"]
+	if !ok || len(kind.Enum) != 1 {
+		return "", false
+	}
+	return kind.Enum[0], true
+}
+
+// StringEnum returns the values of the schema and their descriptions if it is an enumeration of strings.
+func (s *Schema) StringEnum() ([]string, []string, bool) {
+	if s.TypeName() == "string" && len(s.Enum) > 0 {
+		return s.Enum, make([]string, len(s.Enum)), true
+	}
+	if len(s.OneOf) == 0 {
+		return nil, nil, false
+	}
+	var values, docs []string
+	for _, option := range s.OneOf {
+		if option.TypeName() != "string" || len(option.Enum) == 0 {
+			return nil, nil, false
+		}
+		for _, value := range option.Enum {
+			values = append(values, value)
+			docs = append(docs, option.Description)
+		}
+	}
+	return values, docs, true
+}
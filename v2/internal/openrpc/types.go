@@ -0,0 +1,134 @@
+package openrpc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Types maps the schemas of a Document to Go types.
+type Types struct {
+	// Unions maps the names of the tagged unions to their variants.
+	Unions map[string][]*Schema
+}
+
+// NewTypes collects the tagged unions of the given document.
+func NewTypes(doc *Document) (*Types, error) {
+	types := &Types{Unions: make(map[string][]*Schema)}
+	for name, schema := range doc.Components.Schemas {
+		if _, _, ok := schema.StringEnum(); ok || len(schema.OneOf) == 0 {
+			continue
+		}
+		for _, variant := range schema.OneOf {
+			if _, ok := variant.VariantKind(); !ok {
+				return nil, fmt.Errorf("%v: unsupported oneOf, only enums and unions tagged with \"kind\" are supported", name)
+			}
+		}
+		types.Unions[name] = schema.OneOf
+	}
+	return types, nil
+}
+
+// IsUnion returns true if typ is the name of a tagged union.
+func (types *Types) IsUnion(typ string) bool {
+	_, ok := types.Unions[typ]
+	return ok
+}
+
+// GoType returns the Go type of the given schema and whether the value can be null.
+func (types *Types) GoType(schema *Schema) (string, bool, error) {
+	schema, nullable := schema.NonNull()
+	if name := schema.RefName(); name != "" {
+		return name, nullable, nil
+	}
+	var typ string
+	switch schema.TypeName() {
+	case "string":
+		typ = "string"
+	case "boolean":
+		typ = "bool"
+	case "number":
+		typ = "float64"
+	case "integer":
+		switch schema.Format {
+		case "uint8", "int":
+			// []uint8 would be encoded as base64 instead of a list of numbers
+			typ = "int"
+		case "int8", "int16", "int32", "int64", "uint16", "uint32", "uint64", "uint":
+			typ = schema.Format
+		default:
+			typ = "int64"
+		}
+	case "array":
+		switch len(schema.Items) {
+		case 1:
+			item, itemNullable, err := types.GoType(schema.Items[0])
+			if err != nil {
+				return "", false, err
+			}
+			typ = "[]" + Optional(item, itemNullable)
+		case 2:
+			first, firstNullable, err := types.GoType(schema.Items[0])
+			if err != nil {
+				return "", false, err
+			}
+			second, secondNullable, err := types.GoType(schema.Items[1])
+			if err != nil {
+				return "", false, err
+			}
+			typ = fmt.Sprintf("Pair[%v, %v]", Optional(first, firstNullable), Optional(second, secondNullable))
+		default:
+			return "", false, fmt.Errorf("unsupported array with %d item schemas", len(schema.Items))
+		}
+	case "object":
+		if schema.AdditionalProperties == nil || schema.AdditionalProperties.Schema == nil {
+			typ = "map[string]any"
+			break
+		}
+		value, valueNullable, err := types.GoType(schema.AdditionalProperties.Schema)
+		if err != nil {
+			return "", false, err
+		}
+		typ = "map[string]" + Optional(value, valueNullable)
+	case "":
+		typ = "any"
+	default:
+		return "", false, fmt.Errorf("unsupported type %q", schema.TypeName())
+	}
+	return typ, nullable, nil
+}
+
+// ParamType returns the Go type of a method parameter.
+func (types *Types) ParamType(param *ContentDescriptor) (string, error) {
+	typ, nullable, err := types.GoType(param.Schema)
+	if err != nil {
+		return "", err
+	}
+	if types.IsUnion(typ) {
+		return typ, nil
+	}
+	return Optional(typ, nullable || !param.Required), nil
+}
+
+// ResultType returns the Go type of a method result, or an empty string if the method
+// has no result. Tagged unions are returned as nil interfaces when the result is null.
+func (types *Types) ResultType(method Method) (string, bool, error) {
+	if method.Result == nil || method.Result.Schema == nil || method.Result.Schema.IsNull() {
+		return "", false, nil
+	}
+	typ, nullable, err := types.GoType(method.Result.Schema)
+	if err != nil {
+		return "", false, err
+	}
+	if types.IsUnion(typ) {
+		return typ, nullable, nil
+	}
+	return Optional(typ, nullable), nullable, nil
+}
+
+// Optional returns the type to use for values that can be null.
+func Optional(typ string, nullable bool) string {
+	if !nullable || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "any" {
+		return typ
+	}
+	return "*" + typ
+}